@@ -0,0 +1,10 @@
+-- 为 conversations 增加上下文摘要缓存
+-- chat 接口在历史消息超出上下文窗口时，把早期消息摘要后缓存，避免每次请求重新生成
+
+ALTER TABLE conversations ADD COLUMN IF NOT EXISTS summary TEXT;
+ALTER TABLE conversations ADD COLUMN IF NOT EXISTS summary_message_count INT NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN conversations.summary IS '早期消息摘要，覆盖前 summary_message_count 条消息';
+
+-- 验证
+SELECT 'conversation summary columns added successfully' AS status;
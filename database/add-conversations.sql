@@ -0,0 +1,31 @@
+-- 服务端保存对话记录
+-- 对话历史从前端 localStorage 迁移到数据库，chat 接口按 conversation_id 追加消息
+
+CREATE TABLE IF NOT EXISTS conversations (
+  conversation_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_did VARCHAR(255) NOT NULL,
+  project_id UUID NOT NULL,
+  title TEXT NOT NULL DEFAULT '',
+  stage VARCHAR(32) NOT NULL DEFAULT 'initial',
+  report_id UUID REFERENCES business_reports(report_id) ON DELETE SET NULL,
+  created_at TIMESTAMP DEFAULT NOW(),
+  updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS messages (
+  message_id BIGSERIAL PRIMARY KEY,
+  conversation_id UUID NOT NULL REFERENCES conversations(conversation_id) ON DELETE CASCADE,
+  role VARCHAR(16) NOT NULL,
+  content TEXT NOT NULL,
+  payload JSONB,
+  created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_conversations_user_project ON conversations(user_did, project_id, updated_at DESC);
+CREATE INDEX IF NOT EXISTS idx_messages_conversation ON messages(conversation_id, message_id);
+
+-- 报告关联生成它的对话
+ALTER TABLE business_reports ADD COLUMN IF NOT EXISTS conversation_id UUID;
+
+-- 验证
+SELECT 'conversations and messages tables created successfully' AS status;
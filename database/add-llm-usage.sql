@@ -0,0 +1,27 @@
+-- 新增 LLM 调用用量表
+-- 每次模型调用（chat、标签识别等）记录 token 数、模型、延迟和估算成本，供 /usage 接口统计
+
+CREATE TABLE IF NOT EXISTS llm_usage (
+  usage_id BIGSERIAL PRIMARY KEY,
+  user_did VARCHAR(255) NOT NULL,
+  project_id UUID,
+  endpoint VARCHAR(64) NOT NULL,
+  provider VARCHAR(32) NOT NULL,
+  model VARCHAR(128) NOT NULL,
+  call_type VARCHAR(16) NOT NULL,
+  prompt_tokens INT NOT NULL DEFAULT 0,
+  completion_tokens INT NOT NULL DEFAULT 0,
+  total_tokens INT GENERATED ALWAYS AS (prompt_tokens + completion_tokens) STORED,
+  latency_ms INT NOT NULL DEFAULT 0,
+  cost_usd NUMERIC(12, 6) NOT NULL DEFAULT 0,
+  error_kind VARCHAR(32),
+  created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_llm_usage_user_created ON llm_usage(user_did, created_at);
+CREATE INDEX IF NOT EXISTS idx_llm_usage_project_created ON llm_usage(project_id, created_at);
+
+COMMENT ON TABLE llm_usage IS 'LLM 调用用量：token 数、延迟和估算成本，按用户 DID 和项目归属';
+
+-- 验证
+SELECT 'llm_usage table created successfully' AS status;
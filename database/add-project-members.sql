@@ -0,0 +1,46 @@
+-- 新增项目成员和报告评论
+-- 报告不再只能由创建者访问：同一项目的成员按角色（owner / editor / viewer）
+-- 查看、评论、更新条目状态和编辑项目内的报告，owner 可管理成员和分享链接，并可删除、恢复和清除项目内的报告
+-- 报告创建者始终拥有自己报告的全部权限
+-- X-Zero 项目的成员（由 DID Login API 的 /api/projects 确认）即为该项目的 owner，
+-- 后端在其首次访问项目时写入；editor / viewer 只能由 owner 邀请
+
+CREATE TABLE IF NOT EXISTS project_members (
+  project_id UUID NOT NULL,
+  user_did VARCHAR(255) NOT NULL,
+  role VARCHAR(16) NOT NULL CHECK (role IN ('owner', 'editor', 'viewer')),
+  added_by VARCHAR(255) NOT NULL,
+  created_at TIMESTAMP DEFAULT NOW(),
+  updated_at TIMESTAMP DEFAULT NOW(),
+  PRIMARY KEY (project_id, user_did)
+);
+
+CREATE INDEX IF NOT EXISTS idx_project_members_user ON project_members(user_did);
+
+COMMENT ON TABLE project_members IS '项目成员及其角色，X-Zero 项目成员为 owner，其他成员由 owner 邀请';
+
+-- 回填已有项目：只有最早保存报告的用户成为 owner；其他作者不自动授予 editor，
+-- 他们访问项目时由后端向 X-Zero 确认成员身份后成为 owner，否则需由 owner 邀请
+INSERT INTO project_members (project_id, user_did, role, added_by, created_at)
+SELECT DISTINCT ON (project_id) project_id, user_did, 'owner', user_did, created_at
+FROM business_reports
+ORDER BY project_id, created_at, user_did
+ON CONFLICT (project_id, user_did) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS report_comments (
+  comment_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  report_id UUID NOT NULL REFERENCES business_reports(report_id) ON DELETE CASCADE,
+  item_id VARCHAR(32),
+  user_did VARCHAR(255) NOT NULL,
+  body TEXT NOT NULL,
+  created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_comments_report ON report_comments(report_id, created_at);
+
+COMMENT ON TABLE report_comments IS '项目成员对报告及其条目的评论';
+
+-- 验证
+SELECT role, COUNT(*) AS members, COUNT(DISTINCT project_id) AS projects
+FROM project_members
+GROUP BY role;
@@ -0,0 +1,16 @@
+-- 新增 AI 接口限流表
+-- chat 和 identify-profession-tags 按用户 DID 使用令牌桶限流，超限返回 429 和 Retry-After
+-- 每日 token 限额直接统计 llm_usage（见 add-llm-usage.sql），无需额外的表
+
+CREATE TABLE IF NOT EXISTS rate_limits (
+  user_did VARCHAR(255) NOT NULL,
+  endpoint VARCHAR(64) NOT NULL,
+  tokens DOUBLE PRECISION NOT NULL,
+  updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (user_did, endpoint)
+);
+
+COMMENT ON TABLE rate_limits IS 'AI 接口令牌桶限流状态；每日 token 限额基于 llm_usage 统计';
+
+-- 验证
+SELECT 'rate_limits table created successfully' AS status;
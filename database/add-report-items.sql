@@ -0,0 +1,119 @@
+-- 新增报告条目表，并从 business_reports.recommendations 回填已有报告
+-- 之后 save-report 事务内写入条目表，get-report 从条目表读取，update-report-item 按条目更新状态
+-- 未通过校验（validation_errors 不为空）的报告不回填，仍按原 JSON 返回
+
+CREATE TABLE IF NOT EXISTS report_workflows (
+  report_id UUID NOT NULL REFERENCES business_reports(report_id) ON DELETE CASCADE,
+  item_id VARCHAR(32) NOT NULL,
+  position INT NOT NULL,
+  name TEXT NOT NULL,
+  description TEXT NOT NULL DEFAULT '',
+  input_requirements TEXT NOT NULL DEFAULT '',
+  output_requirements TEXT NOT NULL DEFAULT '',
+  estimated_cost NUMERIC(12, 2) NOT NULL DEFAULT 0,
+  priority VARCHAR(16) NOT NULL DEFAULT '',
+  status VARCHAR(32),
+  task_id TEXT,
+  created_at TIMESTAMP DEFAULT NOW(),
+  updated_at TIMESTAMP DEFAULT NOW(),
+  PRIMARY KEY (report_id, item_id)
+);
+
+CREATE TABLE IF NOT EXISTS report_roles (
+  report_id UUID NOT NULL REFERENCES business_reports(report_id) ON DELETE CASCADE,
+  item_id VARCHAR(32) NOT NULL,
+  position INT NOT NULL,
+  title TEXT NOT NULL,
+  responsibilities JSONB NOT NULL DEFAULT '[]',
+  requirements JSONB NOT NULL DEFAULT '[]',
+  work_hours TEXT NOT NULL DEFAULT '',
+  monthly_budget NUMERIC(12, 2) NOT NULL DEFAULT 0,
+  priority VARCHAR(16) NOT NULL DEFAULT '',
+  status VARCHAR(32),
+  task_id TEXT,
+  created_at TIMESTAMP DEFAULT NOW(),
+  updated_at TIMESTAMP DEFAULT NOW(),
+  PRIMARY KEY (report_id, item_id)
+);
+
+CREATE TABLE IF NOT EXISTS report_phases (
+  report_id UUID NOT NULL REFERENCES business_reports(report_id) ON DELETE CASCADE,
+  item_id VARCHAR(32) NOT NULL,
+  position INT NOT NULL,
+  phase_name TEXT NOT NULL,
+  duration TEXT NOT NULL DEFAULT '',
+  monthly_budget NUMERIC(12, 2) NOT NULL DEFAULT 0,
+  budget_breakdown JSONB NOT NULL DEFAULT '{}',
+  created_at TIMESTAMP DEFAULT NOW(),
+  updated_at TIMESTAMP DEFAULT NOW(),
+  PRIMARY KEY (report_id, item_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_workflows_order ON report_workflows(report_id, position);
+CREATE INDEX IF NOT EXISTS idx_report_roles_order ON report_roles(report_id, position);
+CREATE INDEX IF NOT EXISTS idx_report_phases_order ON report_phases(report_id, position);
+CREATE INDEX IF NOT EXISTS idx_report_workflows_task ON report_workflows(task_id) WHERE task_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_report_roles_task ON report_roles(task_id) WHERE task_id IS NOT NULL;
+
+COMMENT ON TABLE report_workflows IS '报告中的 AI 工作流，状态和任务关联按条目保存';
+COMMENT ON TABLE report_roles IS '报告中的人工岗位';
+COMMENT ON TABLE report_phases IS '报告中的实施阶段及预算';
+
+-- 回填：条目 ID 与前端一致，从 0 开始编号；状态取自 recommendations.item_statuses
+INSERT INTO report_workflows (report_id, item_id, position, name, description, input_requirements,
+                              output_requirements, estimated_cost, priority, status, task_id)
+SELECT r.report_id,
+       'wf-' || (w.ord - 1),
+       w.ord - 1,
+       COALESCE(w.item->>'name', ''),
+       COALESCE(w.item->>'description', ''),
+       COALESCE(w.item->>'input_requirements', ''),
+       COALESCE(w.item->>'output_requirements', ''),
+       CASE WHEN jsonb_typeof(w.item->'estimated_cost') = 'number' THEN (w.item->>'estimated_cost')::numeric ELSE 0 END,
+       COALESCE(w.item->>'priority', ''),
+       r.recommendations->'item_statuses'->('wf-' || (w.ord - 1))->>'status',
+       r.recommendations->'item_statuses'->('wf-' || (w.ord - 1))->>'task_id'
+FROM business_reports r,
+     jsonb_array_elements(CASE WHEN jsonb_typeof(r.recommendations->'ai_workflows') = 'array'
+                               THEN r.recommendations->'ai_workflows' ELSE '[]' END) WITH ORDINALITY AS w(item, ord)
+WHERE r.validation_errors IS NULL
+ON CONFLICT DO NOTHING;
+
+INSERT INTO report_roles (report_id, item_id, position, title, responsibilities, requirements,
+                          work_hours, monthly_budget, priority, status, task_id)
+SELECT r.report_id,
+       'role-' || (h.ord - 1),
+       h.ord - 1,
+       COALESCE(h.item->>'title', ''),
+       CASE WHEN jsonb_typeof(h.item->'responsibilities') = 'array' THEN h.item->'responsibilities' ELSE '[]' END,
+       CASE WHEN jsonb_typeof(h.item->'requirements') = 'array' THEN h.item->'requirements' ELSE '[]' END,
+       COALESCE(h.item->>'work_hours', ''),
+       CASE WHEN jsonb_typeof(h.item->'monthly_budget') = 'number' THEN (h.item->>'monthly_budget')::numeric ELSE 0 END,
+       COALESCE(h.item->>'priority', ''),
+       r.recommendations->'item_statuses'->('role-' || (h.ord - 1))->>'status',
+       r.recommendations->'item_statuses'->('role-' || (h.ord - 1))->>'task_id'
+FROM business_reports r,
+     jsonb_array_elements(CASE WHEN jsonb_typeof(r.recommendations->'human_roles') = 'array'
+                               THEN r.recommendations->'human_roles' ELSE '[]' END) WITH ORDINALITY AS h(item, ord)
+WHERE r.validation_errors IS NULL
+ON CONFLICT DO NOTHING;
+
+INSERT INTO report_phases (report_id, item_id, position, phase_name, duration, monthly_budget, budget_breakdown)
+SELECT r.report_id,
+       'phase-' || (p.ord - 1),
+       p.ord - 1,
+       COALESCE(p.item->>'phase_name', ''),
+       COALESCE(p.item->>'duration', ''),
+       CASE WHEN jsonb_typeof(p.item->'monthly_budget') = 'number' THEN (p.item->>'monthly_budget')::numeric ELSE 0 END,
+       CASE WHEN jsonb_typeof(p.item->'budget_breakdown') = 'object' THEN p.item->'budget_breakdown' ELSE '{}' END
+FROM business_reports r,
+     jsonb_array_elements(CASE WHEN jsonb_typeof(r.recommendations->'phases') = 'array'
+                               THEN r.recommendations->'phases' ELSE '[]' END) WITH ORDINALITY AS p(item, ord)
+WHERE r.validation_errors IS NULL
+ON CONFLICT DO NOTHING;
+
+-- 验证
+SELECT
+  (SELECT COUNT(*) FROM report_workflows) AS workflows,
+  (SELECT COUNT(*) FROM report_roles) AS roles,
+  (SELECT COUNT(*) FROM report_phases) AS phases;
@@ -0,0 +1,10 @@
+-- 报告列表分页索引
+-- GET /reports 改为游标分页，按 (排序字段, report_id) 定位下一页，
+-- 以下索引覆盖按创建时间和更新时间排序（升序和降序都可使用）
+
+CREATE INDEX IF NOT EXISTS idx_business_reports_list_created ON business_reports(user_did, project_id, created_at, report_id);
+CREATE INDEX IF NOT EXISTS idx_business_reports_list_updated ON business_reports(user_did, project_id, updated_at, report_id);
+
+-- 验证
+SELECT indexname FROM pg_indexes
+WHERE tablename = 'business_reports' AND indexname LIKE 'idx_business_reports_list_%';
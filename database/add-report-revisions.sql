@@ -0,0 +1,26 @@
+-- 新增报告修订历史表
+-- 每次修改报告后写入一条完整快照，用于查看历史版本和比较 AI 原始方案与后续修改
+-- 已有报告以当前内容回填一条 import 修订
+
+CREATE TABLE IF NOT EXISTS report_revisions (
+  revision_id BIGSERIAL PRIMARY KEY,
+  report_id UUID NOT NULL REFERENCES business_reports(report_id) ON DELETE CASCADE,
+  version INT NOT NULL,
+  action VARCHAR(32) NOT NULL,
+  user_did VARCHAR(255) NOT NULL,
+  business_goal TEXT NOT NULL,
+  recommendations JSONB NOT NULL,
+  created_at TIMESTAMP DEFAULT NOW(),
+  UNIQUE (report_id, version)
+);
+
+COMMENT ON TABLE report_revisions IS '报告修订历史，版本 1 为 AI 最初生成的内容，可比较任意两个版本';
+
+-- 回填：已有报告的当前内容作为当前版本的修订
+INSERT INTO report_revisions (report_id, version, action, user_did, business_goal, recommendations, created_at)
+SELECT report_id, version, 'import', user_did, business_goal, recommendations, updated_at
+FROM business_reports
+ON CONFLICT (report_id, version) DO NOTHING;
+
+-- 验证
+SELECT COUNT(*) AS revisions FROM report_revisions;
@@ -0,0 +1,24 @@
+-- 新增报告假设方案表
+-- 用户可以在已保存的报告上调整月预算上限、删除岗位、修改 AI 工作流成本或延长阶段时长，
+-- 重新计算的结果可以按名称保存，原报告不受影响
+
+CREATE TABLE IF NOT EXISTS report_scenarios (
+  scenario_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  report_id UUID NOT NULL REFERENCES business_reports(report_id) ON DELETE CASCADE,
+  user_did VARCHAR(255) NOT NULL,
+  name VARCHAR(255) NOT NULL,
+  report_version INT NOT NULL,
+  constraints JSONB NOT NULL,
+  result JSONB NOT NULL,
+  created_at TIMESTAMP DEFAULT NOW(),
+  updated_at TIMESTAMP DEFAULT NOW(),
+  UNIQUE (report_id, name)
+);
+
+COMMENT ON TABLE report_scenarios IS '报告的命名假设方案，同名方案再次保存时覆盖';
+
+-- 验证
+SELECT column_name, data_type
+FROM information_schema.columns
+WHERE table_name = 'report_scenarios'
+ORDER BY ordinal_position;
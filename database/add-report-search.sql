@@ -0,0 +1,26 @@
+-- 报告搜索与筛选
+-- search_text 汇总业务目标、摘要、AI 工作流名称和描述、人工岗位名称，
+-- 中文没有空格分词，因此使用 pg_trgm 三元组索引支持任意子串的 ILIKE 匹配
+-- total_budget / monthly_budget 由后端计算（需要解析阶段时长），用于按预算筛选
+-- 已有报告的 search_text 与预算需要后端计算，执行本脚本后调用一次性回填函数：
+--   sam remote invoke BackfillSearchIndexFunction
+-- 返回 done 为 false 时表示超时前未处理完，再次调用即可
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE business_reports ADD COLUMN IF NOT EXISTS search_text TEXT;
+ALTER TABLE business_reports ADD COLUMN IF NOT EXISTS total_budget NUMERIC(12, 2);
+ALTER TABLE business_reports ADD COLUMN IF NOT EXISTS monthly_budget NUMERIC(12, 2);
+
+COMMENT ON COLUMN business_reports.search_text IS '搜索用文本，NULL 表示尚未建立索引';
+COMMENT ON COLUMN business_reports.total_budget IS '全部阶段的总预算（月预算 × 时长），XZT';
+COMMENT ON COLUMN business_reports.monthly_budget IS '最高的阶段月预算，XZT';
+
+CREATE INDEX IF NOT EXISTS idx_business_reports_search ON business_reports USING GIN (search_text gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_business_reports_budget ON business_reports(user_did, total_budget);
+
+-- 验证
+SELECT
+  COUNT(*) AS reports,
+  COUNT(search_text) AS indexed
+FROM business_reports;
@@ -0,0 +1,43 @@
+-- 新增报告分享链接
+-- 报告所有者可以为报告创建只读分享链接（可选过期时间和密码，可撤销），
+-- 无需登录即可通过链接查看去除了所有者信息的报告；每次访问记录在 report_share_access 中
+-- 令牌只保存 SHA-256 哈希，密码使用 PBKDF2-SHA256 加盐哈希
+
+CREATE TABLE IF NOT EXISTS report_shares (
+  share_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  report_id UUID NOT NULL REFERENCES business_reports(report_id) ON DELETE CASCADE,
+  user_did VARCHAR(255) NOT NULL,
+  token_hash CHAR(64) NOT NULL UNIQUE,
+  password_hash TEXT,
+  label VARCHAR(255) NOT NULL DEFAULT '',
+  expires_at TIMESTAMP,
+  revoked_at TIMESTAMP,
+  access_count INT NOT NULL DEFAULT 0,
+  last_accessed_at TIMESTAMP,
+  created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_shares_report ON report_shares(report_id, created_at DESC);
+
+COMMENT ON TABLE report_shares IS '报告的只读分享链接，通过令牌匿名访问';
+
+CREATE TABLE IF NOT EXISTS report_share_access (
+  access_id BIGSERIAL PRIMARY KEY,
+  share_id UUID NOT NULL REFERENCES report_shares(share_id) ON DELETE CASCADE,
+  outcome VARCHAR(32) NOT NULL,
+  source_ip VARCHAR(64) NOT NULL DEFAULT '',
+  user_agent TEXT NOT NULL DEFAULT '',
+  accessed_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_share_access_share ON report_share_access(share_id, accessed_at DESC);
+-- 密码错误次数按链接和来源 IP 分别计数，一个访问者无法锁定其他人
+CREATE INDEX IF NOT EXISTS idx_report_share_access_failures ON report_share_access(share_id, source_ip, accessed_at DESC);
+
+COMMENT ON TABLE report_share_access IS '分享链接访问日志，也用于限制密码尝试次数';
+
+-- 验证
+SELECT table_name, COUNT(*) AS columns
+FROM information_schema.columns
+WHERE table_name IN ('report_shares', 'report_share_access')
+GROUP BY table_name;
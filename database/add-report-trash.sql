@@ -0,0 +1,16 @@
+-- 报告软删除与回收站
+-- DELETE /report/{id} 不再直接删除，而是写入 deleted_at 移入回收站；
+-- 回收站中的报告可以恢复或手动清空，超过保留期（TRASH_RETENTION_DAYS，默认 30 天）后由定时任务永久删除
+-- 永久删除时条目、修订、假设方案随外键级联删除
+
+ALTER TABLE business_reports ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+COMMENT ON COLUMN business_reports.deleted_at IS '移入回收站的时间；回收站中的报告对所有读取接口不可见，超过保留期（TRASH_RETENTION_DAYS）后永久删除';
+
+CREATE INDEX IF NOT EXISTS idx_business_reports_trash ON business_reports(user_did, deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- 验证
+SELECT
+  COUNT(*) FILTER (WHERE deleted_at IS NULL) AS active,
+  COUNT(*) FILTER (WHERE deleted_at IS NOT NULL) AS trashed
+FROM business_reports;
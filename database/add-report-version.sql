@@ -0,0 +1,9 @@
+-- 为 business_reports 增加版本号，用于乐观并发控制
+-- GET /report/{id} 返回 ETag，PATCH 请求携带 If-Match，版本不一致时返回 409 Conflict
+
+ALTER TABLE business_reports ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
+
+COMMENT ON COLUMN business_reports.version IS '报告版本号，更新时加 1；带 If-Match 的请求版本不一致返回 409';
+
+-- 验证
+SELECT 'business_reports.version column added successfully' AS status;
@@ -0,0 +1,9 @@
+-- 为 business_reports 增加推荐内容校验结果
+-- save-report 默认拒绝未通过校验的推荐；以 allow_invalid 保存时记录错误列表
+
+ALTER TABLE business_reports ADD COLUMN IF NOT EXISTS validation_errors JSONB;
+
+COMMENT ON COLUMN business_reports.validation_errors IS '推荐内容的校验错误：[{path, message}]，以 allow_invalid 保存时写入';
+
+-- 验证
+SELECT 'validation_errors column added successfully' AS status;
@@ -7,6 +7,13 @@ CREATE TABLE IF NOT EXISTS business_reports (
   project_id UUID NOT NULL,
   business_goal TEXT NOT NULL,
   recommendations JSONB NOT NULL,  -- 包含 ai_workflows, human_roles, phases
+  validation_errors JSONB,         -- 未通过校验时保存的错误列表，通过校验为 NULL
+  conversation_id UUID,            -- 生成该报告的对话
+  version INT NOT NULL DEFAULT 1,  -- 每次修改加 1，用作 ETag / If-Match 乐观锁
+  search_text TEXT,                -- 搜索用文本：目标、摘要、工作流名称和描述、岗位名称；NULL 表示尚未建立索引
+  total_budget NUMERIC(12, 2),     -- 全部阶段的总预算（月预算 × 时长）
+  monthly_budget NUMERIC(12, 2),   -- 最高的阶段月预算
+  deleted_at TIMESTAMP,            -- 移入回收站的时间，NULL 表示未删除
   created_at TIMESTAMP DEFAULT NOW(),
   updated_at TIMESTAMP DEFAULT NOW()
 );
@@ -16,5 +23,240 @@ CREATE INDEX IF NOT EXISTS idx_business_reports_user ON business_reports(user_di
 CREATE INDEX IF NOT EXISTS idx_business_reports_project ON business_reports(project_id);
 CREATE INDEX IF NOT EXISTS idx_business_reports_created ON business_reports(created_at DESC);
 
+-- 中文没有分词，搜索用 pg_trgm 三元组索引支持任意子串的 ILIKE 匹配
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX IF NOT EXISTS idx_business_reports_search ON business_reports USING GIN (search_text gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_business_reports_budget ON business_reports(user_did, total_budget);
+CREATE INDEX IF NOT EXISTS idx_business_reports_trash ON business_reports(user_did, deleted_at) WHERE deleted_at IS NOT NULL;
+-- 报告列表按 (排序字段, report_id) 游标分页
+CREATE INDEX IF NOT EXISTS idx_business_reports_list_created ON business_reports(user_did, project_id, created_at, report_id);
+CREATE INDEX IF NOT EXISTS idx_business_reports_list_updated ON business_reports(user_did, project_id, updated_at, report_id);
+
 COMMENT ON TABLE business_reports IS '商业咨询报告，存储AI生成的推荐内容';
 COMMENT ON COLUMN business_reports.recommendations IS 'JSON格式：{ai_workflows: [], human_roles: [], phases: []}';
+COMMENT ON COLUMN business_reports.version IS '报告版本号，更新时加 1；带 If-Match 的请求版本不一致返回 409';
+COMMENT ON COLUMN business_reports.validation_errors IS '推荐内容的校验错误：[{path, message}]，以 allow_invalid 保存时写入';
+COMMENT ON COLUMN business_reports.search_text IS '搜索用文本，NULL 表示尚未建立索引';
+COMMENT ON COLUMN business_reports.total_budget IS '全部阶段的总预算（月预算 × 时长），XZT';
+COMMENT ON COLUMN business_reports.monthly_budget IS '最高的阶段月预算，XZT';
+COMMENT ON COLUMN business_reports.deleted_at IS '移入回收站的时间；回收站中的报告对所有读取接口不可见，超过保留期（TRASH_RETENTION_DAYS）后永久删除';
+
+-- 对话表（服务端保存的咨询会话）
+CREATE TABLE IF NOT EXISTS conversations (
+  conversation_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_did VARCHAR(255) NOT NULL,
+  project_id UUID NOT NULL,
+  title TEXT NOT NULL DEFAULT '',
+  stage VARCHAR(32) NOT NULL DEFAULT 'initial',  -- initial / questioning / recommending
+  report_id UUID REFERENCES business_reports(report_id) ON DELETE SET NULL,
+  summary TEXT,                                  -- 超出上下文窗口的早期消息摘要（缓存）
+  summary_message_count INT NOT NULL DEFAULT 0,  -- 摘要覆盖的前 N 条消息
+  created_at TIMESTAMP DEFAULT NOW(),
+  updated_at TIMESTAMP DEFAULT NOW()
+);
+
+-- 对话消息表
+CREATE TABLE IF NOT EXISTS messages (
+  message_id BIGSERIAL PRIMARY KEY,
+  conversation_id UUID NOT NULL REFERENCES conversations(conversation_id) ON DELETE CASCADE,
+  role VARCHAR(16) NOT NULL,       -- user / assistant
+  content TEXT NOT NULL,
+  payload JSONB,                   -- assistant 消息解析后的响应（stage, questions, recommendations）
+  created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_conversations_user_project ON conversations(user_did, project_id, updated_at DESC);
+CREATE INDEX IF NOT EXISTS idx_messages_conversation ON messages(conversation_id, message_id);
+
+COMMENT ON TABLE conversations IS '咨询对话，按用户 DID 和项目保存，支持跨设备继续';
+COMMENT ON TABLE messages IS '对话消息，由 chat 接口在每轮对话后追加';
+
+-- LLM 调用用量表（每次模型调用一行，用于成本统计、计费和限额）
+CREATE TABLE IF NOT EXISTS llm_usage (
+  usage_id BIGSERIAL PRIMARY KEY,
+  user_did VARCHAR(255) NOT NULL,
+  project_id UUID,                         -- 可为空，如未传 project_id 的标签识别
+  endpoint VARCHAR(64) NOT NULL,           -- chat / identify-profession-tags
+  provider VARCHAR(32) NOT NULL,           -- deepseek / openai / local
+  model VARCHAR(128) NOT NULL,
+  call_type VARCHAR(16) NOT NULL,          -- json / text / stream
+  prompt_tokens INT NOT NULL DEFAULT 0,
+  completion_tokens INT NOT NULL DEFAULT 0,
+  total_tokens INT GENERATED ALWAYS AS (prompt_tokens + completion_tokens) STORED,
+  latency_ms INT NOT NULL DEFAULT 0,
+  cost_usd NUMERIC(12, 6) NOT NULL DEFAULT 0,  -- 按调用时价格估算
+  error_kind VARCHAR(32),                  -- 失败调用的错误类型，成功为 NULL
+  created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_llm_usage_user_created ON llm_usage(user_did, created_at);
+CREATE INDEX IF NOT EXISTS idx_llm_usage_project_created ON llm_usage(project_id, created_at);
+
+COMMENT ON TABLE llm_usage IS 'LLM 调用用量：token 数、延迟和估算成本，按用户 DID 和项目归属';
+
+-- AI 接口限流表（按用户 DID 和接口的令牌桶）
+CREATE TABLE IF NOT EXISTS rate_limits (
+  user_did VARCHAR(255) NOT NULL,
+  endpoint VARCHAR(64) NOT NULL,
+  tokens DOUBLE PRECISION NOT NULL,  -- 桶内剩余请求数，按时间线性补充
+  updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (user_did, endpoint)
+);
+
+COMMENT ON TABLE rate_limits IS 'AI 接口令牌桶限流状态；每日 token 限额基于 llm_usage 统计';
+
+-- 报告条目表：AI 工作流、人工岗位、阶段分别存储，可单独查询和更新
+-- item_id 在保存时按顺序分配（wf-0、role-0、phase-0 ...），在报告内唯一且不变
+CREATE TABLE IF NOT EXISTS report_workflows (
+  report_id UUID NOT NULL REFERENCES business_reports(report_id) ON DELETE CASCADE,
+  item_id VARCHAR(32) NOT NULL,
+  position INT NOT NULL,
+  name TEXT NOT NULL,
+  description TEXT NOT NULL DEFAULT '',
+  input_requirements TEXT NOT NULL DEFAULT '',
+  output_requirements TEXT NOT NULL DEFAULT '',
+  estimated_cost NUMERIC(12, 2) NOT NULL DEFAULT 0,
+  priority VARCHAR(16) NOT NULL DEFAULT '',
+  status VARCHAR(32),              -- NULL / draft_created / published
+  task_id TEXT,                    -- Task UI 中创建的任务
+  created_at TIMESTAMP DEFAULT NOW(),
+  updated_at TIMESTAMP DEFAULT NOW(),
+  PRIMARY KEY (report_id, item_id)
+);
+
+CREATE TABLE IF NOT EXISTS report_roles (
+  report_id UUID NOT NULL REFERENCES business_reports(report_id) ON DELETE CASCADE,
+  item_id VARCHAR(32) NOT NULL,
+  position INT NOT NULL,
+  title TEXT NOT NULL,
+  responsibilities JSONB NOT NULL DEFAULT '[]',
+  requirements JSONB NOT NULL DEFAULT '[]',
+  work_hours TEXT NOT NULL DEFAULT '',
+  monthly_budget NUMERIC(12, 2) NOT NULL DEFAULT 0,
+  priority VARCHAR(16) NOT NULL DEFAULT '',
+  status VARCHAR(32),
+  task_id TEXT,
+  created_at TIMESTAMP DEFAULT NOW(),
+  updated_at TIMESTAMP DEFAULT NOW(),
+  PRIMARY KEY (report_id, item_id)
+);
+
+CREATE TABLE IF NOT EXISTS report_phases (
+  report_id UUID NOT NULL REFERENCES business_reports(report_id) ON DELETE CASCADE,
+  item_id VARCHAR(32) NOT NULL,
+  position INT NOT NULL,
+  phase_name TEXT NOT NULL,
+  duration TEXT NOT NULL DEFAULT '',
+  monthly_budget NUMERIC(12, 2) NOT NULL DEFAULT 0,
+  budget_breakdown JSONB NOT NULL DEFAULT '{}',
+  created_at TIMESTAMP DEFAULT NOW(),
+  updated_at TIMESTAMP DEFAULT NOW(),
+  PRIMARY KEY (report_id, item_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_workflows_order ON report_workflows(report_id, position);
+CREATE INDEX IF NOT EXISTS idx_report_roles_order ON report_roles(report_id, position);
+CREATE INDEX IF NOT EXISTS idx_report_phases_order ON report_phases(report_id, position);
+CREATE INDEX IF NOT EXISTS idx_report_workflows_task ON report_workflows(task_id) WHERE task_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_report_roles_task ON report_roles(task_id) WHERE task_id IS NOT NULL;
+
+COMMENT ON TABLE report_workflows IS '报告中的 AI 工作流，状态和任务关联按条目保存';
+COMMENT ON TABLE report_roles IS '报告中的人工岗位';
+COMMENT ON TABLE report_phases IS '报告中的实施阶段及预算';
+
+-- 报告修订历史（每次修改后的完整快照，不可修改）
+CREATE TABLE IF NOT EXISTS report_revisions (
+  revision_id BIGSERIAL PRIMARY KEY,
+  report_id UUID NOT NULL REFERENCES business_reports(report_id) ON DELETE CASCADE,
+  version INT NOT NULL,                -- 修改后的报告版本号，与 business_reports.version 对应
+  action VARCHAR(32) NOT NULL,         -- create / item_status / trash / restore / ...
+  user_did VARCHAR(255) NOT NULL,      -- 执行修改的用户
+  business_goal TEXT NOT NULL,
+  recommendations JSONB NOT NULL,      -- 修改后的完整推荐内容（含条目 ID 和状态）
+  created_at TIMESTAMP DEFAULT NOW(),
+  UNIQUE (report_id, version)
+);
+
+COMMENT ON TABLE report_revisions IS '报告修订历史，版本 1 为 AI 最初生成的内容，可比较任意两个版本';
+
+-- 报告的预算假设方案（what-if），不修改原报告
+CREATE TABLE IF NOT EXISTS report_scenarios (
+  scenario_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  report_id UUID NOT NULL REFERENCES business_reports(report_id) ON DELETE CASCADE,
+  user_did VARCHAR(255) NOT NULL,
+  name VARCHAR(255) NOT NULL,
+  report_version INT NOT NULL,         -- 计算方案时报告的版本号
+  constraints JSONB NOT NULL,          -- 月预算上限、删除的岗位、工作流成本、阶段时长
+  result JSONB NOT NULL,               -- 重新计算的方案、预算汇总和差额
+  created_at TIMESTAMP DEFAULT NOW(),
+  updated_at TIMESTAMP DEFAULT NOW(),
+  UNIQUE (report_id, name)
+);
+
+COMMENT ON TABLE report_scenarios IS '报告的命名假设方案，同名方案再次保存时覆盖';
+
+-- 报告分享链接：只读，可设置过期时间和密码，可随时撤销
+CREATE TABLE IF NOT EXISTS report_shares (
+  share_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  report_id UUID NOT NULL REFERENCES business_reports(report_id) ON DELETE CASCADE,
+  user_did VARCHAR(255) NOT NULL,
+  token_hash CHAR(64) NOT NULL UNIQUE, -- 分享令牌的 SHA-256（十六进制），令牌本身只在创建时返回一次
+  password_hash TEXT,                  -- PBKDF2-SHA256 密码哈希，NULL 表示无需密码
+  label VARCHAR(255) NOT NULL DEFAULT '',
+  expires_at TIMESTAMP,                -- NULL 表示永不过期
+  revoked_at TIMESTAMP,
+  access_count INT NOT NULL DEFAULT 0, -- 成功查看的次数
+  last_accessed_at TIMESTAMP,
+  created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_shares_report ON report_shares(report_id, created_at DESC);
+
+COMMENT ON TABLE report_shares IS '报告的只读分享链接，通过令牌匿名访问';
+
+-- 分享链接的访问记录，包括密码错误、过期、已撤销等被拒绝的访问
+CREATE TABLE IF NOT EXISTS report_share_access (
+  access_id BIGSERIAL PRIMARY KEY,
+  share_id UUID NOT NULL REFERENCES report_shares(share_id) ON DELETE CASCADE,
+  outcome VARCHAR(32) NOT NULL,        -- viewed / password_required / wrong_password / expired / revoked / throttled
+  source_ip VARCHAR(64) NOT NULL DEFAULT '',
+  user_agent TEXT NOT NULL DEFAULT '',
+  accessed_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_share_access_share ON report_share_access(share_id, accessed_at DESC);
+-- 密码错误次数按链接和来源 IP 分别计数，一个访问者无法锁定其他人
+CREATE INDEX IF NOT EXISTS idx_report_share_access_failures ON report_share_access(share_id, source_ip, accessed_at DESC);
+
+COMMENT ON TABLE report_share_access IS '分享链接访问日志，也用于限制密码尝试次数';
+
+-- 项目成员：同一 X-Zero 项目的成员按角色访问项目内的报告
+-- owner 可管理成员和分享链接，并可删除、恢复和清除项目内的报告，editor 可编辑报告条目和状态，viewer 可查看和评论
+-- 报告创建者始终拥有自己报告的全部权限
+CREATE TABLE IF NOT EXISTS project_members (
+  project_id UUID NOT NULL,
+  user_did VARCHAR(255) NOT NULL,
+  role VARCHAR(16) NOT NULL CHECK (role IN ('owner', 'editor', 'viewer')),
+  added_by VARCHAR(255) NOT NULL,
+  created_at TIMESTAMP DEFAULT NOW(),
+  updated_at TIMESTAMP DEFAULT NOW(),
+  PRIMARY KEY (project_id, user_did)
+);
+
+CREATE INDEX IF NOT EXISTS idx_project_members_user ON project_members(user_did);
+
+COMMENT ON TABLE project_members IS '项目成员及其角色，X-Zero 项目成员为 owner，其他成员由 owner 邀请';
+
+-- 报告评论，item_id 非空时评论针对报告中的某个条目
+CREATE TABLE IF NOT EXISTS report_comments (
+  comment_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  report_id UUID NOT NULL REFERENCES business_reports(report_id) ON DELETE CASCADE,
+  item_id VARCHAR(32),
+  user_did VARCHAR(255) NOT NULL,
+  body TEXT NOT NULL,
+  created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_comments_report ON report_comments(report_id, created_at);
+
+COMMENT ON TABLE report_comments IS '项目成员对报告及其条目的评论';
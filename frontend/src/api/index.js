@@ -67,30 +67,265 @@ chatApi.interceptors.response.use(
   }
 )
 
-// Chat API (uses Function URL for longer timeout support)
+// Chat API. The chat function is only served through its Function URL
+// (VITE_CHAT_API_URL, the ChatFunctionUrl output), not API Gateway.
 export const sendMessage = (messages, projectId, stream = false) => {
   return chatApi.post('/', { messages, project_id: projectId, stream })
 }
 
+// Streaming chat: the Function URL answers with server-sent events
+// (token, stage, final, error). Resolves with the final payload in the same
+// { success, data } shape as sendMessage.
+export const streamMessage = async (messages, projectId, { onToken, onStage, conversationId } = {}) => {
+  const token = localStorage.getItem('token')
+  const res = await fetch(CHAT_API_URL, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+      ...(token ? { Authorization: `Bearer ${token}` } : {}),
+    },
+    body: JSON.stringify({
+      messages,
+      project_id: projectId,
+      conversation_id: conversationId,
+      stream: true,
+    }),
+  })
+
+  if (res.status === 401) {
+    localStorage.removeItem('token')
+    localStorage.removeItem('userInfo')
+    window.location.href = '/'
+  }
+  if (!res.ok || !res.body) {
+    const data = await res.json().catch(() => null)
+    if (data && res.status === 429) {
+      data.retry_after = Number(res.headers.get('Retry-After')) || null
+    }
+    throw data || new Error(`Chat request failed (${res.status})`)
+  }
+
+  const reader = res.body.getReader()
+  const decoder = new TextDecoder()
+  let buffer = ''
+  let finalData = null
+
+  const dispatch = (raw) => {
+    let event = 'message'
+    const dataLines = []
+    raw.split('\n').forEach((line) => {
+      if (line.startsWith('event:')) event = line.slice(6).trim()
+      else if (line.startsWith('data:')) dataLines.push(line.slice(5).replace(/^ /, ''))
+    })
+    if (dataLines.length === 0) return
+    const data = JSON.parse(dataLines.join('\n'))
+
+    if (event === 'token') onToken?.(data.content)
+    else if (event === 'stage') onStage?.(data.stage)
+    else if (event === 'final') finalData = data
+    else if (event === 'error') throw { success: false, error: data.message }
+  }
+
+  for (;;) {
+    const { done, value } = await reader.read()
+    if (done) break
+    buffer += decoder.decode(value, { stream: true })
+
+    let idx
+    while ((idx = buffer.indexOf('\n\n')) >= 0) {
+      const raw = buffer.slice(0, idx)
+      buffer = buffer.slice(idx + 2)
+      dispatch(raw)
+    }
+  }
+  if (buffer.trim()) dispatch(buffer)
+
+  if (!finalData) {
+    throw new Error('Chat stream ended unexpectedly')
+  }
+  return { success: true, data: finalData }
+}
+
+// Conversations API (server-held history; pass conversation_id to chat and
+// send only the new messages)
+export const createConversation = (projectId, title = '') => {
+  return api.post('/conversations', { project_id: projectId, title })
+}
+
+export const getConversations = (projectId) => {
+  return api.get('/conversations', { params: { project_id: projectId } })
+}
+
+export const getConversation = (conversationId) => {
+  return api.get(`/conversation/${conversationId}`)
+}
+
+export const deleteConversation = (conversationId) => {
+  return api.delete(`/conversation/${conversationId}`)
+}
+
 // Reports API
 export const saveReport = (data) => {
   return api.post('/save-report', data)
 }
 
-export const getReports = (projectId) => {
-  return api.get('/reports', { params: { project_id: projectId } })
+// Paged list: resolves with { reports, next_cursor }. options: sort
+// (created_at, updated_at, total_budget), order, limit, cursor, fields
+// (summary or full)
+export const getReports = (projectId, options = {}) => {
+  return api.get('/reports', { params: { project_id: projectId, ...options } })
 }
 
 export const getReport = (reportId) => {
   return api.get(`/report/${reportId}`)
 }
 
+// params: q, project_id, from, to, min_budget, max_budget, status, priority,
+// limit, offset
+export const searchReports = (params = {}) => {
+  return api.get('/reports/search', { params })
+}
+
+export const compareReports = (reportIds) => {
+  return api.get('/reports/compare', { params: { ids: reportIds.join(',') } })
+}
+
+// Moves the report to the trash
 export const deleteReport = (reportId) => {
   return api.delete(`/report/${reportId}`)
 }
 
-export const updateReportItem = (reportId, itemId, data) => {
-  return api.patch(`/report/${reportId}/item/${itemId}`, data)
+export const getTrash = (projectId) => {
+  return api.get('/reports/trash', { params: { project_id: projectId } })
+}
+
+export const restoreReport = (reportId) => {
+  return api.post(`/report/${reportId}/restore`)
+}
+
+// Without reportId the whole trash is emptied
+export const purgeTrash = (reportId) => {
+  return api.delete('/reports/trash', { params: reportId ? { report_id: reportId } : {} })
+}
+
+// Pass the report version to make the update fail with 409 if the report
+// changed since it was loaded
+export const updateReportItem = (reportId, itemId, data, version) => {
+  const headers = version ? { 'If-Match': `"${version}"` } : {}
+  return api.patch(`/report/${reportId}/item/${itemId}`, data, { headers })
+}
+
+export const addReportItem = (reportId, list, item, version, position) => {
+  const headers = version ? { 'If-Match': `"${version}"` } : {}
+  const params = position !== undefined ? { position } : {}
+  return api.post(`/report/${reportId}/items/${list}`, item, { headers, params })
+}
+
+export const editReportItem = (reportId, itemId, item, version) => {
+  const headers = version ? { 'If-Match': `"${version}"` } : {}
+  return api.put(`/report/${reportId}/item/${itemId}`, item, { headers })
+}
+
+export const deleteReportItem = (reportId, itemId, version) => {
+  const headers = version ? { 'If-Match': `"${version}"` } : {}
+  return api.delete(`/report/${reportId}/item/${itemId}`, { headers })
+}
+
+export const reorderReportItems = (reportId, list, itemIds, version) => {
+  const headers = version ? { 'If-Match': `"${version}"` } : {}
+  return api.put(`/report/${reportId}/items/${list}/order`, { item_ids: itemIds }, { headers })
+}
+
+export const getReportRevisions = (reportId) => {
+  return api.get(`/report/${reportId}/revisions`)
+}
+
+export const getReportRevision = (reportId, version) => {
+  return api.get(`/report/${reportId}/revisions/${version}`)
+}
+
+export const diffReportRevisions = (reportId, from, to) => {
+  return api.get(`/report/${reportId}/diff`, { params: { from, to } })
+}
+
+export const runReportScenario = (reportId, constraints, name) => {
+  return api.post(`/report/${reportId}/scenarios`, { ...constraints, name })
+}
+
+export const getReportScenarios = (reportId) => {
+  return api.get(`/report/${reportId}/scenarios`)
+}
+
+// Resolves with a Blob. format: markdown, pdf, docx, csv or xlsx (the last
+// two hold the budget only). The Accept header makes API Gateway return
+// binary formats undecoded.
+export const exportReport = (reportId, format) => {
+  const accept = {
+    pdf: 'application/pdf',
+    docx: 'application/vnd.openxmlformats-officedocument.wordprocessingml.document',
+    xlsx: 'application/vnd.openxmlformats-officedocument.spreadsheetml.sheet',
+  }[format] || '*/*'
+  return api.get(`/report/${reportId}/export`, {
+    params: { format },
+    responseType: 'blob',
+    headers: { Accept: accept },
+  })
+}
+
+// Share links. options: label, password, expires_in_days. The token is only
+// returned on creation.
+export const createReportShare = (reportId, options = {}) => {
+  return api.post(`/report/${reportId}/shares`, options)
+}
+
+export const getReportShares = (reportId) => {
+  return api.get(`/report/${reportId}/shares`)
+}
+
+export const revokeReportShare = (reportId, shareId) => {
+  return api.delete(`/report/${reportId}/shares/${shareId}`)
+}
+
+export const getReportShareAccess = (reportId, shareId, limit) => {
+  return api.get(`/report/${reportId}/shares/${shareId}/access`, { params: limit ? { limit } : {} })
+}
+
+// No login needed. Password protected links answer 403 with
+// details.password_required until the right password is sent.
+export const getSharedReport = (token, password) => {
+  const headers = password ? { 'X-Share-Password': password } : {}
+  return api.get(`/shared/${token}`, { headers })
+}
+
+// Comments, on the report or on one of its items
+export const getReportComments = (reportId, itemId) => {
+  return api.get(`/report/${reportId}/comments`, { params: itemId ? { item_id: itemId } : {} })
+}
+
+export const addReportComment = (reportId, body, itemId) => {
+  return api.post(`/report/${reportId}/comments`, { body, item_id: itemId || null })
+}
+
+export const deleteReportComment = (reportId, commentId) => {
+  return api.delete(`/report/${reportId}/comments/${commentId}`)
+}
+
+// Project members. role: owner, editor or viewer
+export const getProjectMembers = (projectId) => {
+  return api.get(`/project/${projectId}/members`)
+}
+
+export const setProjectMember = (projectId, userDid, role) => {
+  return api.post(`/project/${projectId}/members`, { user_did: userDid, role })
+}
+
+export const removeProjectMember = (projectId, userDid) => {
+  return api.delete(`/project/${projectId}/members`, { params: { user_did: userDid } })
+}
+
+// Usage API
+export const getUsage = (params = {}) => {
+  return api.get('/usage', { params })
 }
 
 // DID Login API
@@ -114,8 +349,8 @@ export const listProjects = () => {
 }
 
 // Profession Tags API
-export const identifyProfessionTags = (description) => {
-  return api.post('/identify-profession-tags', { task_description: description })
+export const identifyProfessionTags = (description, projectId) => {
+  return api.post('/identify-profession-tags', { task_description: description, project_id: projectId })
 }
 
 export default api
@@ -1,60 +1,179 @@
 import { useState, useEffect, useRef } from 'react'
 import { useNavigate } from 'react-router-dom'
-import { sendMessage, saveReport } from '../api'
-import { saveConversation, loadConversation, clearConversation, exportAsTxt } from '../utils/storage'
+import { streamMessage, saveReport, createConversation, getConversation } from '../api'
+import { saveConversationId, loadConversationId, clearConversation, exportAsTxt } from '../utils/storage'
 import './ChatPage.css'
 
-function ChatPage({ selectedProject }) {
-  const navigate = useNavigate()
-  const [conversation, setConversation] = useState({
-    messages: [],
-    stage: 'initial',
+const GREETING = '您好！我是您的一人公司商业顾问。请告诉我您的商业目标是什么？例如：跨境电商、SaaS产品、内容创作等。'
+
+const greetingMessage = () => ({
+  role: 'assistant',
+  content: GREETING,
+  timestamp: new Date().toISOString(),
+})
+
+// formatResponse turns a consultant response (the final chat event, or the
+// payload of a stored assistant message) into the text shown in the chat
+const formatResponse = (aiResponse) => {
+  if (aiResponse.stage === 'recommending' && aiResponse.recommendations) {
+    // AI provided recommendations - format the content to include recommendations
+    let content = aiResponse.message || '根据您的情况，我为您制定了以下方案：'
+    const recs = aiResponse.recommendations
+
+    // Add summary
+    if (recs.summary) {
+      content += '\n\n📋 方案概述：\n' + recs.summary
+    }
+    
+    // Add AI workflows
+    if (recs.ai_workflows && recs.ai_workflows.length > 0) {
+      content += '\n\n🤖 AI自动化工作流：'
+      recs.ai_workflows.forEach((workflow, i) => {
+        content += `\n\n${i + 1}. ${workflow.name}`
+        if (workflow.description) content += `\n   描述：${workflow.description}`
+        if (workflow.input_requirements) content += `\n   输入要求：${workflow.input_requirements}`
+        if (workflow.output_requirements) content += `\n   输出要求：${workflow.output_requirements}`
+        if (workflow.estimated_cost) content += `\n   预算：${workflow.estimated_cost} XZT/月`
+        if (workflow.priority) content += `\n   优先级：${workflow.priority}`
+      })
+    }
+    
+    // Add human roles
+    if (recs.human_roles && recs.human_roles.length > 0) {
+      content += '\n\n👥 人力资源配置：'
+      recs.human_roles.forEach((role, i) => {
+        content += `\n\n${i + 1}. ${role.title}`
+        if (role.responsibilities && role.responsibilities.length > 0) {
+          content += `\n   职责：${role.responsibilities.join('、')}`
+        }
+        if (role.requirements && role.requirements.length > 0) {
+          content += `\n   要求：${role.requirements.join('、')}`
+        }
+        if (role.work_hours) content += `\n   工作时间：${role.work_hours}`
+        if (role.monthly_budget) content += `\n   预算：${role.monthly_budget} XZT/月`
+        if (role.priority) content += `\n   优先级：${role.priority}`
+      })
+    }
+    
+    // Add phases
+    if (recs.phases && recs.phases.length > 0) {
+      content += '\n\n📅 实施阶段：'
+      recs.phases.forEach((phase, i) => {
+        content += `\n\n${i + 1}. ${phase.phase_name}`
+        if (phase.duration) content += `\n   时长：${phase.duration}`
+        if (phase.monthly_budget) content += `\n   月预算：${phase.monthly_budget} XZT`
+        if (phase.budget_breakdown) {
+          content += `\n   预算明细：`
+          // Check if budget_breakdown is an object
+          if (typeof phase.budget_breakdown === 'object' && !Array.isArray(phase.budget_breakdown)) {
+            Object.entries(phase.budget_breakdown).forEach(([key, value]) => {
+              content += `\n     - ${key}: ${value} XZT`
+            })
+          } else if (typeof phase.budget_breakdown === 'string') {
+            // If it's a string, just display it
+            content += `\n     ${phase.budget_breakdown}`
+          }
+        }
+      })
+    }
+
+    return content
+  }
+  if (aiResponse.stage === 'questioning') {
+    // AI asking questions
+    let content = aiResponse.message || ''
+    if (aiResponse.questions && aiResponse.questions.length > 0) {
+      content += '\n\n' + aiResponse.questions.map((q, i) => `${i + 1}. ${q}`).join('\n')
+    }
+    return content
+  }
+  return aiResponse.message || aiResponse.content || '请继续...'
+}
+
+// fromServer rebuilds the chat state from a stored conversation
+const fromServer = (conv) => {
+  const state = {
+    conversationId: conv.conversation_id,
+    projectId: conv.project_id,
+    messages: [greetingMessage()],
+    stage: conv.stage || 'initial',
     businessGoal: '',
     recommendations: null,
+  }
+  ;(conv.messages || []).forEach((m) => {
+    if (m.role === 'user') {
+      state.messages.push({ role: 'user', content: m.content, timestamp: m.created_at })
+      return
+    }
+    if (m.role !== 'assistant') return
+    let aiResponse = m.payload
+    if (!aiResponse) {
+      try {
+        aiResponse = JSON.parse(m.content)
+      } catch {
+        aiResponse = { message: m.content }
+      }
+    }
+    if (aiResponse.stage === 'recommending' && aiResponse.recommendations) {
+      state.recommendations = aiResponse.recommendations
+    }
+    state.messages.push({ role: 'assistant', content: formatResponse(aiResponse), timestamp: m.created_at })
   })
+  return state
+}
+
+const emptyConversation = () => ({
+  conversationId: null,
+  projectId: null,
+  messages: [greetingMessage()],
+  stage: 'initial',
+  businessGoal: '',
+  recommendations: null,
+})
+
+function ChatPage({ selectedProject }) {
+  const navigate = useNavigate()
+  const [conversation, setConversation] = useState(emptyConversation)
   const [inputValue, setInputValue] = useState('')
   const [loading, setLoading] = useState(false)
+  const [loadingText, setLoadingText] = useState('思考中...')
   const [error, setError] = useState(null)
   const [showContinuePrompt, setShowContinuePrompt] = useState(false)
   const messagesEndRef = useRef(null)
 
   useEffect(() => {
-    // Load last conversation
-    const savedConversation = loadConversation()
-    if (savedConversation && savedConversation.messages.length > 0) {
-      setConversation(savedConversation)
-      setShowContinuePrompt(true)
-    } else {
-      // Start new conversation
+    // Resume the last conversation; its history is kept on the server
+    const conversationId = loadConversationId()
+    if (!conversationId) {
       startNewConversation()
+      return
     }
+    getConversation(conversationId)
+      .then((response) => {
+        const state = fromServer(response.data)
+        setConversation(state)
+        setShowContinuePrompt(state.messages.length > 1)
+      })
+      .catch((err) => {
+        console.error('Load conversation error:', err)
+        startNewConversation()
+      })
   }, [])
 
+  useEffect(() => {
+    // Conversations belong to one project
+    if (conversation.projectId && selectedProject && conversation.projectId !== selectedProject.project_id) {
+      startNewConversation()
+    }
+  }, [selectedProject?.project_id])
+
   useEffect(() => {
     // Auto scroll to bottom
     messagesEndRef.current?.scrollIntoView({ behavior: 'smooth' })
   }, [conversation.messages])
 
-  useEffect(() => {
-    // Save conversation to localStorage
-    if (conversation.messages.length > 0) {
-      saveConversation(conversation)
-    }
-  }, [conversation])
-
   const startNewConversation = () => {
-    const initialMessage = {
-      role: 'assistant',
-      content: '您好！我是您的一人公司商业顾问。请告诉我您的商业目标是什么？例如：跨境电商、SaaS产品、内容创作等。',
-      timestamp: new Date().toISOString(),
-    }
-    
-    setConversation({
-      messages: [initialMessage],
-      stage: 'initial',
-      businessGoal: '',
-      recommendations: null,
-    })
+    setConversation(emptyConversation())
     setShowContinuePrompt(false)
     clearConversation()
   }
@@ -79,123 +198,50 @@ function ChatPage({ selectedProject }) {
     }))
     setInputValue('')
     setError(null)
+    setShowContinuePrompt(false)
+    setLoadingText('思考中...')
     setLoading(true)
 
     try {
-      // Only send the last 6 messages (3 rounds) to reduce API response time
-      // This keeps the context relevant while staying under API Gateway's 29s timeout
-      const messagesToSend = newMessages.slice(-6)
-      const response = await sendMessage(messagesToSend, selectedProject.project_id)
-      
+      // The server keeps the history, so only the new turn is sent
+      let conversationId = conversation.conversationId
+      if (!conversationId) {
+        const created = await createConversation(selectedProject.project_id)
+        conversationId = created.data.conversation_id
+        saveConversationId(conversationId)
+        setConversation(prev => ({ ...prev, conversationId, projectId: selectedProject.project_id }))
+      }
+
+      const response = await streamMessage(
+        [{ role: 'user', content: userMessage.content }],
+        selectedProject.project_id,
+        {
+          conversationId,
+          onStage: (stage) => {
+            setLoadingText(stage === 'recommending' ? '正在生成方案...' : '正在整理问题...')
+          },
+        },
+      )
+
       if (!response || !response.success) {
         throw new Error(response?.error || '发送消息失败')
       }
-      
+
       const aiResponse = response.data
-        
-        // Parse AI response
-        let assistantMessage
-        let newStage = conversation.stage
-        let newRecommendations = conversation.recommendations
-
-        if (aiResponse.stage === 'recommending' && aiResponse.recommendations) {
-          // AI provided recommendations - format the content to include recommendations
-          let content = aiResponse.message || '根据您的情况，我为您制定了以下方案：'
-          
-          const recs = aiResponse.recommendations
-          console.log('AI Recommendations:', recs)
-          
-          // Add summary
-          if (recs.summary) {
-            content += '\n\n📋 方案概述：\n' + recs.summary
-          }
-          
-          // Add AI workflows
-          if (recs.ai_workflows && recs.ai_workflows.length > 0) {
-            content += '\n\n🤖 AI自动化工作流：'
-            recs.ai_workflows.forEach((workflow, i) => {
-              content += `\n\n${i + 1}. ${workflow.name}`
-              if (workflow.description) content += `\n   描述：${workflow.description}`
-              if (workflow.input_requirements) content += `\n   输入要求：${workflow.input_requirements}`
-              if (workflow.output_requirements) content += `\n   输出要求：${workflow.output_requirements}`
-              if (workflow.estimated_cost) content += `\n   预算：${workflow.estimated_cost} XZT/月`
-              if (workflow.priority) content += `\n   优先级：${workflow.priority}`
-            })
-          }
-          
-          // Add human roles
-          if (recs.human_roles && recs.human_roles.length > 0) {
-            content += '\n\n👥 人力资源配置：'
-            recs.human_roles.forEach((role, i) => {
-              content += `\n\n${i + 1}. ${role.title}`
-              if (role.responsibilities && role.responsibilities.length > 0) {
-                content += `\n   职责：${role.responsibilities.join('、')}`
-              }
-              if (role.requirements && role.requirements.length > 0) {
-                content += `\n   要求：${role.requirements.join('、')}`
-              }
-              if (role.work_hours) content += `\n   工作时间：${role.work_hours}`
-              if (role.monthly_budget) content += `\n   预算：${role.monthly_budget} XZT/月`
-              if (role.priority) content += `\n   优先级：${role.priority}`
-            })
-          }
-          
-          // Add phases
-          if (recs.phases && recs.phases.length > 0) {
-            content += '\n\n📅 实施阶段：'
-            recs.phases.forEach((phase, i) => {
-              content += `\n\n${i + 1}. ${phase.phase_name}`
-              if (phase.duration) content += `\n   时长：${phase.duration}`
-              if (phase.monthly_budget) content += `\n   月预算：${phase.monthly_budget} XZT`
-              if (phase.budget_breakdown) {
-                content += `\n   预算明细：`
-                // Check if budget_breakdown is an object
-                if (typeof phase.budget_breakdown === 'object' && !Array.isArray(phase.budget_breakdown)) {
-                  Object.entries(phase.budget_breakdown).forEach(([key, value]) => {
-                    content += `\n     - ${key}: ${value} XZT`
-                  })
-                } else if (typeof phase.budget_breakdown === 'string') {
-                  // If it's a string, just display it
-                  content += `\n     ${phase.budget_breakdown}`
-                }
-              }
-            })
-          }
-          
-          assistantMessage = {
-            role: 'assistant',
-            content,
-            timestamp: new Date().toISOString(),
-          }
-          newStage = 'recommending'
-          newRecommendations = aiResponse.recommendations
-        } else if (aiResponse.stage === 'questioning') {
-          // AI asking questions
-          let content = aiResponse.message || ''
-          if (aiResponse.questions && aiResponse.questions.length > 0) {
-            content += '\n\n' + aiResponse.questions.map((q, i) => `${i + 1}. ${q}`).join('\n')
-          }
-          assistantMessage = {
-            role: 'assistant',
-            content,
-            timestamp: new Date().toISOString(),
-          }
-          newStage = 'questioning'
-        } else {
-          // Default response
-          assistantMessage = {
-            role: 'assistant',
-            content: aiResponse.message || aiResponse.content || '请继续...',
-            timestamp: new Date().toISOString(),
-          }
-        }
+      const assistantMessage = {
+        role: 'assistant',
+        content: formatResponse(aiResponse),
+        timestamp: new Date().toISOString(),
+      }
 
-        setConversation(prev => ({
-          ...prev,
-          messages: [...newMessages, assistantMessage],
-          stage: newStage,
-          recommendations: newRecommendations,
-        }))
+      setConversation(prev => ({
+        ...prev,
+        messages: [...newMessages, assistantMessage],
+        stage: aiResponse.stage === 'recommending' || aiResponse.stage === 'questioning' ? aiResponse.stage : prev.stage,
+        recommendations: aiResponse.stage === 'recommending' && aiResponse.recommendations
+          ? aiResponse.recommendations
+          : prev.recommendations,
+      }))
     } catch (err) {
       setError(err.error || err.message || '发送消息失败，请重试')
       console.error('Send message error:', err)
@@ -204,7 +250,9 @@ function ChatPage({ selectedProject }) {
     }
   }
 
-  const handleSaveReport = async () => {
+  // allowInvalid keeps recommendations that fail the schema check; the
+  // report is then flagged and cannot be edited or budgeted
+  const handleSaveReport = async (allowInvalid = false) => {
     if (!conversation.recommendations) {
       setError('还没有生成推荐方案')
       return
@@ -222,6 +270,8 @@ function ChatPage({ selectedProject }) {
         project_id: selectedProject.project_id,
         business_goal: conversation.recommendations.business_goal || conversation.businessGoal,
         recommendations: conversation.recommendations,
+        ...(conversation.conversationId ? { conversation_id: conversation.conversationId } : {}),
+        ...(allowInvalid ? { allow_invalid: true } : {}),
       })
 
       if (response.success) {
@@ -229,7 +279,18 @@ function ChatPage({ selectedProject }) {
         navigate('/reports')
       }
     } catch (err) {
-      setError(err.error || '保存报告失败')
+      // 422 lists the schema errors in details: show them and offer to save anyway
+      if (!allowInvalid && Array.isArray(err.details) && err.details.length > 0) {
+        const issues = err.details.slice(0, 8).map((d) => `• ${d.path}: ${d.message}`).join('\n')
+        const more = err.details.length > 8 ? `\n…共 ${err.details.length} 处问题` : ''
+        if (confirm(`方案格式有误，无法直接保存：\n${issues}${more}\n\n仍要保存吗？报告会标记为未通过校验，不能编辑条目，也不计算预算。`)) {
+          await handleSaveReport(true)
+          return
+        }
+        setError('方案格式有误，未保存，可以让顾问重新生成方案')
+      } else {
+        setError(err.error || '保存报告失败')
+      }
       console.error('Save report error:', err)
     } finally {
       setLoading(false)
@@ -294,7 +355,7 @@ function ChatPage({ selectedProject }) {
               <div className="message-content">
                 <div className="loading-container">
                   <div className="spinner"></div>
-                  <p className="loading-text">思考中...</p>
+                  <p className="loading-text">{loadingText}</p>
                 </div>
               </div>
             </div>
@@ -313,7 +374,7 @@ function ChatPage({ selectedProject }) {
           <div className="recommendations-actions">
             <button 
               className="btn btn-primary"
-              onClick={handleSaveReport}
+              onClick={() => handleSaveReport()}
               disabled={loading}
             >
               💾 保存报告
@@ -61,7 +61,7 @@ function ReportDetailPage({ selectedProject }) {
       let professionTags = []
       try {
         console.log('Identifying profession tags for workflow...')
-        const tagsResponse = await identifyProfessionTags(fullDescription)
+        const tagsResponse = await identifyProfessionTags(fullDescription, selectedProject?.project_id)
         if (tagsResponse.success && tagsResponse.data?.profession_tags) {
           professionTags = tagsResponse.data.profession_tags
           console.log('Identified tags:', professionTags)
@@ -91,7 +91,7 @@ function ReportDetailPage({ selectedProject }) {
 
       if (response.data.success) {
         // Update item status
-        await updateReportItem(reportId, `wf-${index}`, {
+        await updateReportItem(reportId, workflow.id || `wf-${index}`, {
           status: 'published',
           task_id: response.data.data.task_id,
         })
@@ -130,7 +130,7 @@ function ReportDetailPage({ selectedProject }) {
       let professionTags = []
       try {
         console.log('Identifying profession tags for role...')
-        const tagsResponse = await identifyProfessionTags(fullDescription)
+        const tagsResponse = await identifyProfessionTags(fullDescription, selectedProject?.project_id)
         if (tagsResponse.success && tagsResponse.data?.profession_tags) {
           professionTags = tagsResponse.data.profession_tags
           console.log('Identified tags:', professionTags)
@@ -160,7 +160,7 @@ function ReportDetailPage({ selectedProject }) {
 
       if (response.data.success) {
         // Update item status
-        await updateReportItem(reportId, `role-${index}`, {
+        await updateReportItem(reportId, role.id || `role-${index}`, {
           status: 'published',
           task_id: response.data.data.task_id,
         })
@@ -188,9 +188,14 @@ function ReportDetailPage({ selectedProject }) {
       await updateReportItem(reportId, itemId, {
         status: null,
         task_id: null,
-      })
+      }, report?.version)
       loadReport()
     } catch (err) {
+      if (err.error?.startsWith('Report has been modified')) {
+        alert('报告已在其他页面被修改，已重新加载')
+        loadReport()
+        return
+      }
       alert(err.error || '取消失败')
       console.error('Cancel publish error:', err)
     }
@@ -244,6 +249,12 @@ function ReportDetailPage({ selectedProject }) {
         <span>创建时间: {new Date(report.created_at).toLocaleString('zh-CN')}</span>
       </div>
 
+      {report.validation_errors && (
+        <div className="error-message">
+          该报告保存时未通过格式校验（{report.validation_errors.length} 处问题），按原样显示，不能编辑条目，也不计算预算。
+        </div>
+      )}
+
       {recommendations?.summary && (
         <div className="report-summary-box">
           <h3>方案概述</h3>
@@ -323,7 +334,7 @@ function ReportDetailPage({ selectedProject }) {
                       </span>
                       <button 
                         className="btn btn-secondary btn-sm"
-                        onClick={() => handleCancelPublish(`wf-${index}`)}
+                        onClick={() => handleCancelPublish(workflow.id || `wf-${index}`)}
                       >
                         取消发布
                       </button>
@@ -406,7 +417,7 @@ function ReportDetailPage({ selectedProject }) {
                       </span>
                       <button 
                         className="btn btn-secondary btn-sm"
-                        onClick={() => handleCancelPublish(`role-${index}`)}
+                        onClick={() => handleCancelPublish(role.id || `role-${index}`)}
                       >
                         取消发布
                       </button>
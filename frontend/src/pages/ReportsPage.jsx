@@ -5,23 +5,27 @@ import './ReportsPage.css'
 
 function ReportsPage({ selectedProject }) {
   const [reports, setReports] = useState([])
+  const [nextCursor, setNextCursor] = useState(null)
+  const [sort, setSort] = useState('created_at')
   const [loading, setLoading] = useState(true)
+  const [loadingMore, setLoadingMore] = useState(false)
   const [error, setError] = useState(null)
 
   useEffect(() => {
     if (selectedProject) {
       loadReports()
     }
-  }, [selectedProject])
+  }, [selectedProject, sort])
 
   const loadReports = async () => {
     setLoading(true)
     setError(null)
 
     try {
-      const response = await getReports(selectedProject.project_id)
+      const response = await getReports(selectedProject.project_id, { sort })
       if (response.success) {
-        setReports(response.data || [])
+        setReports(response.data?.reports || [])
+        setNextCursor(response.data?.next_cursor || null)
       }
     } catch (err) {
       setError(err.error || '加载报告失败')
@@ -31,8 +35,24 @@ function ReportsPage({ selectedProject }) {
     }
   }
 
+  const loadMore = async () => {
+    setLoadingMore(true)
+    try {
+      const response = await getReports(selectedProject.project_id, { sort, cursor: nextCursor })
+      if (response.success) {
+        setReports([...reports, ...(response.data?.reports || [])])
+        setNextCursor(response.data?.next_cursor || null)
+      }
+    } catch (err) {
+      setError(err.error || '加载报告失败')
+      console.error('Load reports error:', err)
+    } finally {
+      setLoadingMore(false)
+    }
+  }
+
   const handleDelete = async (reportId) => {
-    if (!confirm('确定要删除这份报告吗？')) return
+    if (!confirm('确定要删除这份报告吗？报告将移入回收站，30 天内可以恢复。')) return
 
     try {
       const response = await deleteReport(reportId)
@@ -45,19 +65,6 @@ function ReportsPage({ selectedProject }) {
     }
   }
 
-  const countPublishedTasks = (recommendations) => {
-    if (!recommendations) return 0
-    
-    let count = 0
-    if (recommendations.ai_workflows) {
-      count += recommendations.ai_workflows.filter(w => w.status === 'published').length
-    }
-    if (recommendations.human_roles) {
-      count += recommendations.human_roles.filter(r => r.status === 'published').length
-    }
-    return count
-  }
-
   if (loading) {
     return (
       <div className="reports-page">
@@ -87,6 +94,18 @@ function ReportsPage({ selectedProject }) {
         </div>
       )}
 
+      <div className="reports-toolbar">
+        <select
+          className="project-select"
+          value={sort}
+          onChange={(e) => setSort(e.target.value)}
+        >
+          <option value="created_at">按创建时间</option>
+          <option value="updated_at">按更新时间</option>
+          <option value="total_budget">按总预算</option>
+        </select>
+      </div>
+
       {reports.length === 0 ? (
         <div className="empty-state">
           <div className="empty-icon">📋</div>
@@ -110,26 +129,26 @@ function ReportsPage({ selectedProject }) {
                 <div className="stat">
                   <span className="stat-label">AI工作流</span>
                   <span className="stat-value">
-                    {report.recommendations?.ai_workflows?.length || 0}
+                    {report.workflow_count}
                   </span>
                 </div>
                 <div className="stat">
                   <span className="stat-label">真人岗位</span>
                   <span className="stat-value">
-                    {report.recommendations?.human_roles?.length || 0}
+                    {report.role_count}
                   </span>
                 </div>
                 <div className="stat">
                   <span className="stat-label">已发布任务</span>
                   <span className="stat-value">
-                    {countPublishedTasks(report.recommendations)}
+                    {report.published_count}
                   </span>
                 </div>
               </div>
 
-              {report.recommendations?.summary && (
+              {report.summary && (
                 <div className="report-summary">
-                  {report.recommendations.summary}
+                  {report.summary}
                 </div>
               )}
 
@@ -151,6 +170,18 @@ function ReportsPage({ selectedProject }) {
           ))}
         </div>
       )}
+
+      {nextCursor && (
+        <div className="reports-more">
+          <button
+            className="btn btn-secondary"
+            onClick={loadMore}
+            disabled={loadingMore}
+          >
+            {loadingMore ? '加载中...' : '加载更多'}
+          </button>
+        </div>
+      )}
     </div>
   )
 }
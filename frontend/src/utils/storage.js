@@ -1,29 +1,21 @@
 // LocalStorage keys
 const CONVERSATION_KEY = 'business_consultant_conversation'
+const CONVERSATION_ID_KEY = 'business_consultant_conversation_id'
 const LAST_PROJECT_KEY = 'business_consultant_last_project'
 
-// Save conversation to localStorage
-export const saveConversation = (conversation) => {
-  try {
-    localStorage.setItem(CONVERSATION_KEY, JSON.stringify(conversation))
-  } catch (e) {
-    console.error('Failed to save conversation:', e)
-  }
+// Save the ID of the current conversation; its history is kept on the server
+export const saveConversationId = (conversationId) => {
+  localStorage.setItem(CONVERSATION_ID_KEY, conversationId)
 }
 
-// Load conversation from localStorage
-export const loadConversation = () => {
-  try {
-    const data = localStorage.getItem(CONVERSATION_KEY)
-    return data ? JSON.parse(data) : null
-  } catch (e) {
-    console.error('Failed to load conversation:', e)
-    return null
-  }
+// Load the ID of the last conversation
+export const loadConversationId = () => {
+  return localStorage.getItem(CONVERSATION_ID_KEY)
 }
 
-// Clear conversation
+// Clear the current conversation, including history kept by older versions
 export const clearConversation = () => {
+  localStorage.removeItem(CONVERSATION_ID_KEY)
   localStorage.removeItem(CONVERSATION_KEY)
 }
 
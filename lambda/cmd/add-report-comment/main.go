@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/x-zero/business-consultant/pkg/access"
+	"github.com/x-zero/business-consultant/pkg/auth"
+	"github.com/x-zero/business-consultant/pkg/db"
+	"github.com/x-zero/business-consultant/pkg/report"
+	"github.com/x-zero/business-consultant/pkg/response"
+)
+
+// CommentRequest is a new comment, on the report or on one of its items
+type CommentRequest struct {
+	Body   string  `json:"body"`
+	ItemID *string `json:"item_id"`
+}
+
+// handler adds a comment to a report; every project member may comment:
+// POST /report/{id}/comments
+func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Handle OPTIONS
+	if request.HTTPMethod == "OPTIONS" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 200,
+			Headers: map[string]string{
+				"Access-Control-Allow-Origin":  "*",
+				"Access-Control-Allow-Headers": "Content-Type,Authorization",
+				"Access-Control-Allow-Methods": "POST,OPTIONS",
+			},
+		}, nil
+	}
+
+	// Validate JWT
+	authHeader := request.Headers["Authorization"]
+	if authHeader == "" {
+		authHeader = request.Headers["authorization"]
+	}
+	claims, err := auth.ValidateToken(authHeader)
+	if err != nil {
+		return response.Error(401, fmt.Sprintf("Invalid token: %v", err))
+	}
+
+	reportID := request.PathParameters["id"]
+	if reportID == "" {
+		return response.Error(400, "Report ID is required")
+	}
+
+	var req CommentRequest
+	if err := json.Unmarshal([]byte(request.Body), &req); err != nil {
+		return response.Error(400, "Invalid request body")
+	}
+	req.Body = strings.TrimSpace(req.Body)
+	if req.Body == "" {
+		return response.Error(400, "body is required")
+	}
+	if utf8.RuneCountInString(req.Body) > report.MaxCommentLength {
+		return response.Error(400, fmt.Sprintf("body must be at most %d characters", report.MaxCommentLength))
+	}
+	if req.ItemID != nil && *req.ItemID == "" {
+		req.ItemID = nil
+	}
+
+	// Initialize database
+	if err := db.InitDB(); err != nil {
+		return response.Error(500, fmt.Sprintf("Database error: %v", err))
+	}
+
+	pool := db.GetPool()
+
+	// Verify access
+	if _, err := access.Report(ctx, pool, reportID, claims.DID, authHeader, access.Comment); err != nil {
+		return access.ErrorResponse(err)
+	}
+
+	comment, err := report.AddComment(ctx, pool, reportID, req.ItemID, claims.DID, req.Body)
+	if errors.Is(err, report.ErrItemNotFound) {
+		return response.Error(404, "Item not found")
+	}
+	if err != nil {
+		return response.Error(500, err.Error())
+	}
+
+	return response.Success(comment)
+}
+
+func main() {
+	lambda.Start(handler)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/x-zero/business-consultant/pkg/db"
+	"github.com/x-zero/business-consultant/pkg/search"
+)
+
+// BackfillResult is the outcome of one invocation
+type BackfillResult struct {
+	Indexed int  `json:"indexed"`
+	Done    bool `json:"done"` // false when time ran out; invoke again
+}
+
+// handler is invoked once after add-report-search.sql and computes the
+// search text and budgets of the reports saved before those columns
+// existed, one transaction per report
+func handler(ctx context.Context) (BackfillResult, error) {
+	var result BackfillResult
+	if err := db.InitDB(); err != nil {
+		return result, fmt.Errorf("database error: %v", err)
+	}
+	pool := db.GetPool()
+
+	// Leave time to commit the last report before the function times out
+	deadline, ok := ctx.Deadline()
+	for !ok || time.Until(deadline) > 10*time.Second {
+		tx, err := pool.Begin(ctx)
+		if err != nil {
+			return result, fmt.Errorf("failed to begin transaction: %v", err)
+		}
+		indexed, err := search.IndexNext(ctx, tx)
+		if err != nil {
+			tx.Rollback(ctx)
+			return result, err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return result, fmt.Errorf("failed to commit: %v", err)
+		}
+		if !indexed {
+			result.Done = true
+			break
+		}
+		result.Indexed++
+	}
+
+	log.Printf("Indexed %d reports, done: %v", result.Indexed, result.Done)
+	return result, nil
+}
+
+func main() {
+	lambda.Start(handler)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/x-zero/business-consultant/pkg/conversation"
+	"github.com/x-zero/business-consultant/pkg/db"
+	"github.com/x-zero/business-consultant/pkg/deepseek"
+)
+
+// turn carries the state of one chat request
+type turn struct {
+	userDID      string
+	history      []deepseek.Message // everything sent to the model
+	newMessages  []deepseek.Message // the messages of this request
+	conversation *conversation.Conversation
+}
+
+// loadHistory prepends the stored messages of conv to the new messages
+func (t *turn) loadHistory(ctx context.Context, conv *conversation.Conversation) error {
+	stored, err := conversation.Messages(ctx, db.GetPool(), conv.ConversationID)
+	if err != nil {
+		return err
+	}
+
+	history := make([]deepseek.Message, 0, len(stored)+len(t.newMessages))
+	for _, m := range stored {
+		history = append(history, deepseek.Message{Role: m.Role, Content: m.Content})
+	}
+
+	t.conversation = conv
+	t.history = append(history, t.newMessages...)
+	return nil
+}
+
+// fitWindow keeps the history within the context window, summarising older
+// turns. Summaries of stored conversations are cached, so they are only
+// regenerated once more turns fall out of the window. If summarising fails
+// the full history is sent, since the window is well below the model limit.
+func (t *turn) fitWindow(ctx context.Context, client deepseek.LLMProvider) {
+	var cached *conversation.Summary
+	if t.conversation != nil {
+		cached = t.conversation.Summary
+	}
+
+	messages, summary, err := conversation.PolicyFromEnv().Fit(t.history, cached, conversation.NewSummarizer(ctx, client))
+	if err != nil {
+		fmt.Printf("Context window: %v\n", err)
+		return
+	}
+	t.history = messages
+
+	if t.conversation != nil && summary != nil && summary != cached {
+		if err := conversation.SaveSummary(ctx, db.GetPool(), t.conversation.ConversationID, summary); err != nil {
+			fmt.Printf("Failed to cache summary of %s: %v\n", t.conversation.ConversationID, err)
+		}
+	}
+}
+
+// save appends the new user messages and the assistant answer to the stored
+// conversation. Answers that could not be parsed are not stored, so the user
+// can simply retry the turn. Failures are logged, not returned: the answer
+// has already been produced and is still delivered.
+func (t *turn) save(ctx context.Context, result *ChatResult) {
+	if t.conversation == nil {
+		return
+	}
+	result.ConversationID = t.conversation.ConversationID
+	if result.parseErr != nil {
+		return
+	}
+
+	messages := make([]conversation.Message, 0, len(t.newMessages)+1)
+	for _, m := range t.newMessages {
+		messages = append(messages, conversation.Message{Role: m.Role, Content: m.Content})
+	}
+
+	// Store the normalised JSON so later turns show the model valid output
+	content, _ := json.Marshal(result.ConsultantResponse)
+	payload, _ := json.Marshal(result)
+	messages = append(messages, conversation.Message{
+		Role:    "assistant",
+		Content: string(content),
+		Payload: payload,
+	})
+
+	tx, err := db.GetPool().Begin(ctx)
+	if err != nil {
+		fmt.Printf("Failed to save conversation %s: %v\n", t.conversation.ConversationID, err)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if err := conversation.Append(ctx, tx, t.conversation.ConversationID, result.Stage, messages...); err != nil {
+		fmt.Printf("Failed to save conversation %s: %v\n", t.conversation.ConversationID, err)
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		fmt.Printf("Failed to save conversation %s: %v\n", t.conversation.ConversationID, err)
+	}
+}
@@ -3,23 +3,52 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"strings"
+	"io"
+	"math"
+	"strconv"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/x-zero/business-consultant/pkg/auth"
+	"github.com/x-zero/business-consultant/pkg/conversation"
+	"github.com/x-zero/business-consultant/pkg/db"
 	"github.com/x-zero/business-consultant/pkg/deepseek"
+	"github.com/x-zero/business-consultant/pkg/ratelimit"
+	"github.com/x-zero/business-consultant/pkg/report"
 	"github.com/x-zero/business-consultant/pkg/response"
+	"github.com/x-zero/business-consultant/pkg/sse"
+	"github.com/x-zero/business-consultant/pkg/usage"
 )
 
+// ChatResult is the payload returned for every chat turn
+type ChatResult struct {
+	report.ConsultantResponse
+	UserDID          string                  `json:"user_did"`
+	ConversationID   string                  `json:"conversation_id,omitempty"`
+	FinishReason     string                  `json:"finish_reason,omitempty"`
+	ValidationErrors report.ValidationErrors `json:"validation_errors,omitempty"`
+	Repairs          []string                `json:"repairs,omitempty"`
+	RepairAttempts   int                     `json:"repair_attempts"`
+	Raw              string                  `json:"raw,omitempty"`
+
+	parseErr error
+}
+
+// ChatRequest carries either the whole history (client-held conversations)
+// or, together with conversation_id, only the new messages of this turn
 type ChatRequest struct {
-	Messages  []deepseek.Message `json:"messages"`
-	ProjectID string             `json:"project_id"`
-	Stream    bool               `json:"stream"`
+	Messages       []deepseek.Message `json:"messages"`
+	ProjectID      string             `json:"project_id"`
+	ConversationID string             `json:"conversation_id"`
+	Stream         bool               `json:"stream"`
 }
 
-func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+// handler serves the chat Function URL, which runs in RESPONSE_STREAM invoke
+// mode. Streaming requests are answered with server-sent events, all others
+// with a single buffered JSON body.
+func handler(ctx context.Context, request events.LambdaFunctionURLRequest) (*events.LambdaFunctionURLStreamingResponse, error) {
 	// Validate JWT
 	authHeader := request.Headers["Authorization"]
 	if authHeader == "" {
@@ -27,80 +56,141 @@ func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 	}
 	claims, err := auth.ValidateToken(authHeader)
 	if err != nil {
-		return response.ErrorNoCORS(401, fmt.Sprintf("Invalid token: %v", err))
+		return response.StreamError(401, fmt.Sprintf("Invalid token: %v", err))
 	}
 
 	// Parse request body
 	var req ChatRequest
 	if err := json.Unmarshal([]byte(request.Body), &req); err != nil {
-		return response.ErrorNoCORS(400, "Invalid request body")
+		return response.StreamError(400, "Invalid request body")
 	}
 
 	if len(req.Messages) == 0 {
-		return response.ErrorNoCORS(400, "Messages cannot be empty")
+		return response.StreamError(400, "Messages cannot be empty")
 	}
 
-	if req.ProjectID == "" {
-		return response.ErrorNoCORS(400, "Project ID is required")
+	// Per-user rate limit and daily token quota
+	limit := ratelimit.Check(ctx, claims.DID, "chat")
+	if !limit.Allowed {
+		return response.StreamErrorWithHeaders(429, limit.Reason, limit.Headers())
 	}
 
-	client := deepseek.NewClient()
-
-	// Handle streaming request
-	if req.Stream {
-		var accumulated strings.Builder
-		var chunks []string
+	t := &turn{userDID: claims.DID, history: req.Messages, newMessages: req.Messages}
 
-		err := client.ChatStream(req.Messages, func(chunk string) error {
-			accumulated.WriteString(chunk)
-			chunks = append(chunks, chunk)
-			return nil
-		})
+	// Server-held conversation: prepend the stored history
+	if req.ConversationID != "" {
+		if err := db.InitDB(); err != nil {
+			return response.StreamError(500, fmt.Sprintf("Database error: %v", err))
+		}
 
+		conv, err := conversation.Get(ctx, db.GetPool(), req.ConversationID, claims.DID)
+		if errors.Is(err, conversation.ErrNotFound) {
+			return response.StreamError(404, "Conversation not found")
+		}
 		if err != nil {
-			return response.ErrorNoCORS(500, fmt.Sprintf("AI error: %v", err))
+			return response.StreamError(500, err.Error())
+		}
+
+		if req.ProjectID == "" {
+			req.ProjectID = conv.ProjectID
+		} else if req.ProjectID != conv.ProjectID {
+			return response.StreamError(400, "Project ID does not match the conversation")
 		}
 
-		fullResponse := accumulated.String()
-
-		// Try to parse as JSON
-		var aiData map[string]interface{}
-		if err := json.Unmarshal([]byte(fullResponse), &aiData); err != nil {
-			// If not valid JSON, return as plain text
-			return response.SuccessNoCORS(map[string]interface{}{
-				"stage":    "error",
-				"message":  "AI返回格式异常，请重试",
-				"raw":      fullResponse,
-				"user_did": claims.DID,
-			})
+		if err := t.loadHistory(ctx, conv); err != nil {
+			return response.StreamError(500, err.Error())
 		}
+	}
+
+	if req.ProjectID == "" {
+		return response.StreamError(400, "Project ID is required")
+	}
+
+	// Record every model call of this turn against the user and project
+	ctx = usage.WithScope(ctx, usage.Scope{UserDID: claims.DID, ProjectID: req.ProjectID, Endpoint: "chat"})
+
+	client, err := deepseek.NewProvider(usage.Recorder)
+	if err != nil {
+		return response.StreamError(500, fmt.Sprintf("AI provider error: %v", err))
+	}
 
-		aiData["user_did"] = claims.DID
-		return response.SuccessNoCORS(aiData)
+	// Keep long sessions within the model context
+	t.fitWindow(ctx, client)
+
+	// Handle streaming request: forward deltas to the browser as they arrive
+	if req.Stream {
+		pr, pw := io.Pipe()
+		go func() {
+			defer pw.Close()
+			streamChat(ctx, client, t, sse.NewWriter(pw))
+		}()
+		resp := response.SSE(pr)
+		limit.Apply(resp.Headers)
+		return resp, nil
 	}
 
 	// Handle non-streaming request (original behavior)
-	aiResponse, err := client.Chat(req.Messages)
+	aiResponse, err := client.Chat(ctx, t.history)
 	if err != nil {
-		return response.ErrorNoCORS(500, fmt.Sprintf("AI error: %v", err))
+		return aiError(err)
 	}
 
-	// Parse AI response
-	var aiData map[string]interface{}
-	if err := json.Unmarshal([]byte(aiResponse), &aiData); err != nil {
-		// If JSON parsing fails, return as plain text with error stage
-		return response.SuccessNoCORS(map[string]interface{}{
-			"stage":    "error",
-			"message":  "AI返回格式异常，请重试",
-			"raw":      aiResponse,
-			"user_did": claims.DID,
-		})
+	result := resolve(ctx, client, t.history, aiResponse, claims.DID, nil)
+	t.save(ctx, result)
+
+	resp, err := response.StreamSuccess(result)
+	limit.Apply(resp.Headers)
+	return resp, err
+}
+
+// buildResult parses the model output into the response payload, applying
+// local JSON repairs first. Schema violations are reported in
+// validation_errors; output that still is not JSON or does not fit the typed
+// schema is returned with the error stage so the UI can ask the user to retry.
+func buildResult(aiResponse, userDID string) *ChatResult {
+	result := &ChatResult{UserDID: userDID}
+
+	doc, fixes, err := report.RepairResponse(aiResponse)
+	result.Repairs = fixes
+	if err != nil {
+		result.parseErr = err
+		return result.formatError(aiResponse)
 	}
 
-	// Add user info to response
-	aiData["user_did"] = claims.DID
+	result.ValidationErrors = report.ValidateResponse(doc)
+
+	normalized, _ := json.Marshal(doc)
+	if err := json.Unmarshal(normalized, &result.ConsultantResponse); err != nil {
+		result.parseErr = err
+		return result.formatError(aiResponse)
+	}
 
-	return response.SuccessNoCORS(aiData)
+	return result
+}
+
+// valid reports whether the result parsed and passed schema validation
+func (r *ChatResult) valid() bool {
+	return r.parseErr == nil && len(r.ValidationErrors) == 0
+}
+
+// formatError turns the result into the error stage, keeping the raw output
+func (r *ChatResult) formatError(raw string) *ChatResult {
+	r.ConsultantResponse = report.ConsultantResponse{
+		Stage:   "error",
+		Message: "AI返回格式异常，请重试",
+	}
+	r.Raw = raw
+	return r
+}
+
+// aiError maps a failed model call to the matching HTTP status, passing on
+// the provider's Retry-After when it throttled us
+func aiError(err error) (*events.LambdaFunctionURLStreamingResponse, error) {
+	resp, _ := response.StreamError(deepseek.HTTPStatus(err), fmt.Sprintf("AI error: %v", err))
+	if d := deepseek.RetryAfterOf(err); d > 0 {
+		resp.Headers["Retry-After"] = strconv.Itoa(int(math.Ceil(d.Seconds())))
+	}
+	return resp, nil
 }
 
 func main() {
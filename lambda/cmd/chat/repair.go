@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/x-zero/business-consultant/pkg/deepseek"
+)
+
+// defaultRepairAttempts bounds how often invalid output is sent back to the
+// model; override with CHAT_REPAIR_ATTEMPTS (0 disables re-asking)
+const defaultRepairAttempts = 2
+
+func maxRepairAttempts() int {
+	if v := os.Getenv("CHAT_REPAIR_ATTEMPTS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			return n
+		}
+	}
+	return defaultRepairAttempts
+}
+
+// resolve turns model output into a result. Output that is still invalid
+// after the local repairs is sent back to the model together with the
+// problems found, up to maxRepairAttempts times. onRetry, when set, is called
+// before every re-ask.
+func resolve(ctx context.Context, client deepseek.LLMProvider, messages []deepseek.Message, output, userDID string, onRetry func(attempt int, invalid *ChatResult)) *ChatResult {
+	result := buildResult(output, userDID)
+	maxAttempts := maxRepairAttempts()
+
+	for attempt := 1; !result.valid() && attempt <= maxAttempts; attempt++ {
+		if onRetry != nil {
+			onRetry(attempt, result)
+		}
+
+		messages = append(append([]deepseek.Message{}, messages...),
+			deepseek.Message{Role: "assistant", Content: output},
+			deepseek.Message{Role: "user", Content: repairPrompt(result)},
+		)
+
+		next, err := client.Chat(ctx, messages)
+		if err != nil {
+			fmt.Printf("Repair attempt %d failed: %v\n", attempt, err)
+			result.RepairAttempts = attempt
+			break
+		}
+
+		retried := buildResult(next, userDID)
+		retried.RepairAttempts = attempt
+		// Keep a parsed answer over a retry that is not even JSON
+		if retried.parseErr != nil && result.parseErr == nil {
+			result.RepairAttempts = attempt
+			continue
+		}
+		result, output = retried, next
+	}
+
+	return result
+}
+
+// repairPrompt asks the model to fix the problems found in its last answer
+func repairPrompt(result *ChatResult) string {
+	var sb strings.Builder
+	sb.WriteString("你上一次的回复不符合要求的JSON格式，存在以下问题：\n")
+	if result.parseErr != nil {
+		sb.WriteString("- 不是合法的JSON：")
+		sb.WriteString(result.parseErr.Error())
+		sb.WriteString("\n")
+	}
+	for _, e := range result.ValidationErrors {
+		sb.WriteString("- ")
+		sb.WriteString(e.Path)
+		sb.WriteString(": ")
+		sb.WriteString(e.Message)
+		sb.WriteString("\n")
+	}
+	sb.WriteString("请修正以上问题，重新返回完整的纯JSON对象，不要包含任何其他文字。")
+	return sb.String()
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/x-zero/business-consultant/pkg/deepseek"
+	"github.com/x-zero/business-consultant/pkg/sse"
+)
+
+// SSE event names sent to the browser
+const (
+	eventToken  = "token"  // {"content": "..."} for every model delta
+	eventStage  = "stage"  // {"stage": "questioning"|"recommending"} once detected
+	eventRepair = "repair" // {"attempt": n, ...} when invalid output is sent back to the model
+	eventFinal  = "final"  // the parsed response, same shape as the JSON reply
+	eventError  = "error"  // {"message", "kind", "status"} when the model call fails
+)
+
+var stagePattern = regexp.MustCompile(`"stage"\s*:\s*"([a-z_]+)"`)
+
+// streamChat runs a streaming completion and forwards it as SSE events.
+// Write errors mean the browser went away, so they simply end the stream.
+// ctx stays valid until the stream is closed, so it bounds the model calls
+// and the final save.
+func streamChat(ctx context.Context, client deepseek.LLMProvider, t *turn, stream *sse.Writer) {
+	var accumulated strings.Builder
+	stageSent := false
+
+	result, err := client.ChatStream(ctx, t.history, func(chunk string) error {
+		accumulated.WriteString(chunk)
+		if err := stream.Send(eventToken, map[string]string{"content": chunk}); err != nil {
+			return err
+		}
+
+		if !stageSent {
+			if m := stagePattern.FindStringSubmatch(accumulated.String()); m != nil {
+				stageSent = true
+				return stream.Send(eventStage, map[string]string{"stage": m[1]})
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		stream.Send(eventError, map[string]interface{}{
+			"message": fmt.Sprintf("AI error: %v", err),
+			"kind":    deepseek.KindOf(err),
+			"status":  deepseek.HTTPStatus(err),
+		})
+		return
+	}
+
+	final := resolve(ctx, client, t.history, accumulated.String(), t.userDID, func(attempt int, invalid *ChatResult) {
+		stream.Send(eventRepair, map[string]interface{}{
+			"attempt":           attempt,
+			"validation_errors": invalid.ValidationErrors,
+		})
+	})
+	if final.RepairAttempts == 0 {
+		final.FinishReason = result.FinishReason
+	}
+
+	t.save(ctx, final)
+	stream.Send(eventFinal, final)
+}
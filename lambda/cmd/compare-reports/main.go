@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/google/uuid"
+	"github.com/x-zero/business-consultant/pkg/access"
+	"github.com/x-zero/business-consultant/pkg/auth"
+	"github.com/x-zero/business-consultant/pkg/compare"
+	"github.com/x-zero/business-consultant/pkg/db"
+	"github.com/x-zero/business-consultant/pkg/report"
+	"github.com/x-zero/business-consultant/pkg/response"
+)
+
+// maxReports bounds the number of reports compared at once
+const maxReports = 5
+
+// handler compares reports of the caller side by side:
+// GET /reports/compare?ids=<id>,<id>[,...]
+func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Handle OPTIONS
+	if request.HTTPMethod == "OPTIONS" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 200,
+			Headers: map[string]string{
+				"Access-Control-Allow-Origin":  "*",
+				"Access-Control-Allow-Headers": "Content-Type,Authorization",
+				"Access-Control-Allow-Methods": "GET,OPTIONS",
+			},
+		}, nil
+	}
+
+	// Validate JWT
+	authHeader := request.Headers["Authorization"]
+	if authHeader == "" {
+		authHeader = request.Headers["authorization"]
+	}
+	claims, err := auth.ValidateToken(authHeader)
+	if err != nil {
+		return response.Error(401, fmt.Sprintf("Invalid token: %v", err))
+	}
+
+	var ids []string
+	seen := map[string]bool{}
+	for _, id := range strings.Split(request.QueryStringParameters["ids"], ",") {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		if _, err := uuid.Parse(id); err != nil {
+			return response.Error(400, fmt.Sprintf("Invalid report ID: %s", id))
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	if len(ids) < 2 || len(ids) > maxReports {
+		return response.Error(400, fmt.Sprintf("ids must list between 2 and %d report IDs", maxReports))
+	}
+
+	// Initialize database
+	if err := db.InitDB(); err != nil {
+		return response.Error(500, fmt.Sprintf("Database error: %v", err))
+	}
+
+	pool := db.GetPool()
+
+	var reports []compare.Report
+	for _, id := range ids {
+		grant, err := access.Report(ctx, pool, id, claims.DID, authHeader, access.View)
+		if errors.Is(err, access.ErrNotFound) {
+			return response.Error(404, fmt.Sprintf("Report not found: %s", id))
+		}
+		if err != nil {
+			return access.ErrorResponse(err)
+		}
+
+		var businessGoal string
+		var recommendations, validationErrors []byte
+		err = pool.QueryRow(ctx, `
+			SELECT business_goal, recommendations, validation_errors
+			FROM business_reports
+			WHERE report_id = $1 AND deleted_at IS NULL
+		`, id).Scan(&businessGoal, &recommendations, &validationErrors)
+		if err != nil {
+			return response.Error(404, fmt.Sprintf("Report not found: %s", id))
+		}
+		if validationErrors != nil {
+			return response.Error(422, fmt.Sprintf("Report %s was saved with validation errors and cannot be compared", id))
+		}
+
+		current, err := report.Current(ctx, pool, id, recommendations, true)
+		if err != nil {
+			return response.Error(500, err.Error())
+		}
+		var recs report.Recommendations
+		if err := json.Unmarshal(current, &recs); err != nil {
+			return response.Error(500, "Failed to parse recommendations")
+		}
+
+		reports = append(reports, compare.Report{
+			ReportID:     id,
+			ProjectID:    grant.ProjectID,
+			BusinessGoal: businessGoal,
+			Recs:         &recs,
+		})
+	}
+
+	return response.Success(compare.Compare(reports))
+}
+
+func main() {
+	lambda.Start(handler)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/x-zero/business-consultant/pkg/auth"
+	"github.com/x-zero/business-consultant/pkg/conversation"
+	"github.com/x-zero/business-consultant/pkg/db"
+	"github.com/x-zero/business-consultant/pkg/response"
+)
+
+type CreateConversationRequest struct {
+	ProjectID string `json:"project_id"`
+	Title     string `json:"title"`
+}
+
+func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Handle OPTIONS
+	if request.HTTPMethod == "OPTIONS" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 200,
+			Headers: map[string]string{
+				"Access-Control-Allow-Origin":  "*",
+				"Access-Control-Allow-Headers": "Content-Type,Authorization",
+				"Access-Control-Allow-Methods": "POST,OPTIONS",
+			},
+		}, nil
+	}
+
+	// Validate JWT
+	authHeader := request.Headers["Authorization"]
+	if authHeader == "" {
+		authHeader = request.Headers["authorization"]
+	}
+	claims, err := auth.ValidateToken(authHeader)
+	if err != nil {
+		return response.Error(401, fmt.Sprintf("Invalid token: %v", err))
+	}
+
+	// Parse request body
+	var req CreateConversationRequest
+	if err := json.Unmarshal([]byte(request.Body), &req); err != nil {
+		return response.Error(400, "Invalid request body")
+	}
+
+	if req.ProjectID == "" {
+		return response.Error(400, "Project ID is required")
+	}
+
+	// Initialize database
+	if err := db.InitDB(); err != nil {
+		return response.Error(500, fmt.Sprintf("Database error: %v", err))
+	}
+
+	conv, err := conversation.Create(ctx, db.GetPool(), claims.DID, req.ProjectID, req.Title)
+	if err != nil {
+		return response.Error(500, err.Error())
+	}
+
+	return response.Success(conv)
+}
+
+func main() {
+	lambda.Start(handler)
+}
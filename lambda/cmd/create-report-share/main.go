@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/x-zero/business-consultant/pkg/access"
+	"github.com/x-zero/business-consultant/pkg/auth"
+	"github.com/x-zero/business-consultant/pkg/db"
+	"github.com/x-zero/business-consultant/pkg/response"
+	"github.com/x-zero/business-consultant/pkg/share"
+)
+
+// Limits of a share link request
+const (
+	maxExpiresInDays  = 365
+	minPasswordLength = 4
+	maxPasswordLength = 128
+)
+
+// ShareRequest describes a new share link. Without expires_in_days the link
+// does not expire; without a password anyone with the link can view it.
+type ShareRequest struct {
+	Label         string `json:"label"`
+	Password      string `json:"password"`
+	ExpiresInDays int    `json:"expires_in_days"`
+}
+
+// handler creates a read-only share link of a report:
+// POST /report/{id}/shares
+// The token is only returned here; the link cannot be shown again later.
+func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Handle OPTIONS
+	if request.HTTPMethod == "OPTIONS" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 200,
+			Headers: map[string]string{
+				"Access-Control-Allow-Origin":  "*",
+				"Access-Control-Allow-Headers": "Content-Type,Authorization",
+				"Access-Control-Allow-Methods": "POST,OPTIONS",
+			},
+		}, nil
+	}
+
+	// Validate JWT
+	authHeader := request.Headers["Authorization"]
+	if authHeader == "" {
+		authHeader = request.Headers["authorization"]
+	}
+	claims, err := auth.ValidateToken(authHeader)
+	if err != nil {
+		return response.Error(401, fmt.Sprintf("Invalid token: %v", err))
+	}
+
+	reportID := request.PathParameters["id"]
+	if reportID == "" {
+		return response.Error(400, "Report ID is required")
+	}
+
+	var req ShareRequest
+	if request.Body != "" {
+		if err := json.Unmarshal([]byte(request.Body), &req); err != nil {
+			return response.Error(400, "Invalid request body")
+		}
+	}
+	req.Label = strings.TrimSpace(req.Label)
+	if utf8.RuneCountInString(req.Label) > 255 {
+		return response.Error(400, "label must be at most 255 characters")
+	}
+	if req.ExpiresInDays < 0 || req.ExpiresInDays > maxExpiresInDays {
+		return response.Error(400, fmt.Sprintf("expires_in_days must be between 1 and %d", maxExpiresInDays))
+	}
+	if n := utf8.RuneCountInString(req.Password); n > 0 && (n < minPasswordLength || n > maxPasswordLength) {
+		return response.Error(400, fmt.Sprintf("password must be between %d and %d characters", minPasswordLength, maxPasswordLength))
+	}
+
+	// Initialize database
+	if err := db.InitDB(); err != nil {
+		return response.Error(500, fmt.Sprintf("Database error: %v", err))
+	}
+
+	pool := db.GetPool()
+
+	// Verify access
+	if _, err := access.Report(ctx, pool, reportID, claims.DID, authHeader, access.Manage); err != nil {
+		return access.ErrorResponse(err)
+	}
+
+	s, token, err := share.Create(ctx, pool, reportID, claims.DID, share.Options{
+		Label:         req.Label,
+		Password:      req.Password,
+		ExpiresInDays: req.ExpiresInDays,
+	})
+	if err != nil {
+		return response.Error(500, err.Error())
+	}
+
+	return response.Success(map[string]interface{}{
+		"share": s,
+		"token": token,
+	})
+}
+
+func main() {
+	lambda.Start(handler)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/x-zero/business-consultant/pkg/auth"
+	"github.com/x-zero/business-consultant/pkg/conversation"
+	"github.com/x-zero/business-consultant/pkg/db"
+	"github.com/x-zero/business-consultant/pkg/response"
+)
+
+func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	if request.HTTPMethod == "OPTIONS" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 200,
+			Headers: map[string]string{
+				"Access-Control-Allow-Origin":  "*",
+				"Access-Control-Allow-Headers": "Content-Type,Authorization",
+				"Access-Control-Allow-Methods": "DELETE,OPTIONS",
+			},
+		}, nil
+	}
+
+	authHeader := request.Headers["Authorization"]
+	if authHeader == "" {
+		authHeader = request.Headers["authorization"]
+	}
+	claims, err := auth.ValidateToken(authHeader)
+	if err != nil {
+		return response.Error(401, fmt.Sprintf("Invalid token: %v", err))
+	}
+
+	conversationID := request.PathParameters["id"]
+	if conversationID == "" {
+		return response.Error(400, "Conversation ID is required")
+	}
+
+	if err := db.InitDB(); err != nil {
+		return response.Error(500, fmt.Sprintf("Database error: %v", err))
+	}
+
+	err = conversation.Delete(ctx, db.GetPool(), conversationID, claims.DID)
+	if errors.Is(err, conversation.ErrNotFound) {
+		return response.Error(404, "Conversation not found or access denied")
+	}
+	if err != nil {
+		return response.Error(500, err.Error())
+	}
+
+	return response.Success(map[string]interface{}{
+		"message": "Conversation deleted successfully",
+	})
+}
+
+func main() {
+	lambda.Start(handler)
+}
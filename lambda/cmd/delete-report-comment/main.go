@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/google/uuid"
+	"github.com/x-zero/business-consultant/pkg/access"
+	"github.com/x-zero/business-consultant/pkg/auth"
+	"github.com/x-zero/business-consultant/pkg/db"
+	"github.com/x-zero/business-consultant/pkg/report"
+	"github.com/x-zero/business-consultant/pkg/response"
+)
+
+// handler deletes a comment of a report. Authors may delete their own
+// comments, the report creator and project owners any comment:
+// DELETE /report/{id}/comments/{comment_id}
+func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Handle OPTIONS
+	if request.HTTPMethod == "OPTIONS" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 200,
+			Headers: map[string]string{
+				"Access-Control-Allow-Origin":  "*",
+				"Access-Control-Allow-Headers": "Content-Type,Authorization",
+				"Access-Control-Allow-Methods": "DELETE,OPTIONS",
+			},
+		}, nil
+	}
+
+	// Validate JWT
+	authHeader := request.Headers["Authorization"]
+	if authHeader == "" {
+		authHeader = request.Headers["authorization"]
+	}
+	claims, err := auth.ValidateToken(authHeader)
+	if err != nil {
+		return response.Error(401, fmt.Sprintf("Invalid token: %v", err))
+	}
+
+	reportID := request.PathParameters["id"]
+	if reportID == "" {
+		return response.Error(400, "Report ID is required")
+	}
+	commentID := request.PathParameters["comment_id"]
+	if _, err := uuid.Parse(commentID); err != nil {
+		return response.Error(400, "Invalid comment_id")
+	}
+
+	// Initialize database
+	if err := db.InitDB(); err != nil {
+		return response.Error(500, fmt.Sprintf("Database error: %v", err))
+	}
+
+	pool := db.GetPool()
+
+	// Verify access
+	grant, err := access.Report(ctx, pool, reportID, claims.DID, authHeader, access.Comment)
+	if err != nil {
+		return access.ErrorResponse(err)
+	}
+
+	author, err := report.CommentAuthor(ctx, pool, reportID, commentID)
+	if errors.Is(err, report.ErrCommentNotFound) {
+		return response.Error(404, "Comment not found")
+	}
+	if err != nil {
+		return response.Error(500, err.Error())
+	}
+	if author != claims.DID && !grant.Can(access.Manage) {
+		return response.Error(403, "Access denied")
+	}
+
+	err = report.DeleteComment(ctx, pool, reportID, commentID)
+	if errors.Is(err, report.ErrCommentNotFound) {
+		return response.Error(404, "Comment not found")
+	}
+	if err != nil {
+		return response.Error(500, err.Error())
+	}
+
+	return response.Success(map[string]interface{}{
+		"message":    "Comment deleted",
+		"comment_id": commentID,
+	})
+}
+
+func main() {
+	lambda.Start(handler)
+}
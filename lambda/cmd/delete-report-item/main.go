@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/x-zero/business-consultant/pkg/access"
+	"github.com/x-zero/business-consultant/pkg/auth"
+	"github.com/x-zero/business-consultant/pkg/db"
+	"github.com/x-zero/business-consultant/pkg/report"
+	"github.com/x-zero/business-consultant/pkg/response"
+	"github.com/x-zero/business-consultant/pkg/search"
+)
+
+// handler removes a workflow, role or phase from a report
+func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	if request.HTTPMethod == "OPTIONS" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 200,
+			Headers: map[string]string{
+				"Access-Control-Allow-Origin":  "*",
+				"Access-Control-Allow-Headers": "Content-Type,Authorization,If-Match",
+				"Access-Control-Allow-Methods": "DELETE,OPTIONS",
+			},
+		}, nil
+	}
+
+	authHeader := request.Headers["Authorization"]
+	if authHeader == "" {
+		authHeader = request.Headers["authorization"]
+	}
+	claims, err := auth.ValidateToken(authHeader)
+	if err != nil {
+		return response.Error(401, fmt.Sprintf("Invalid token: %v", err))
+	}
+
+	reportID := request.PathParameters["id"]
+	itemID := request.PathParameters["item_id"]
+	if reportID == "" || itemID == "" {
+		return response.Error(400, "Report ID and Item ID are required")
+	}
+	if report.ListOf(itemID) == "" {
+		return response.Error(404, "Item not found")
+	}
+
+	ifMatch := request.Headers["If-Match"]
+	if ifMatch == "" {
+		ifMatch = request.Headers["if-match"]
+	}
+	expected, err := report.ParseIfMatch(ifMatch)
+	if err != nil {
+		return response.Error(400, err.Error())
+	}
+
+	if err := db.InitDB(); err != nil {
+		return response.Error(500, fmt.Sprintf("Database error: %v", err))
+	}
+
+	pool := db.GetPool()
+
+	if _, err := access.Report(ctx, pool, reportID, claims.DID, authHeader, access.Edit); err != nil {
+		return access.ErrorResponse(err)
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return response.Error(500, fmt.Sprintf("Database error: %v", err))
+	}
+	defer tx.Rollback(ctx)
+
+	version, err := report.LockForEdit(ctx, tx, reportID, expected)
+	if err != nil {
+		return editError(err, version)
+	}
+
+	err = report.RemoveItem(ctx, tx, reportID, itemID)
+	if errors.Is(err, report.ErrItemNotFound) {
+		return response.Error(404, "Item not found")
+	}
+	if errors.Is(err, report.ErrLastItem) {
+		return response.Error(422, "The last item of a list cannot be removed")
+	}
+	if err != nil {
+		return response.Error(500, err.Error())
+	}
+
+	if err := report.RecordRevision(ctx, tx, reportID, claims.DID, report.ActionItemRemove); err != nil {
+		return response.Error(500, err.Error())
+	}
+
+	if err := search.Index(ctx, tx, reportID); err != nil {
+		return response.Error(500, err.Error())
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return response.Error(500, fmt.Sprintf("Failed to update report: %v", err))
+	}
+
+	return response.SuccessWithHeaders(map[string]interface{}{
+		"message": "Item removed successfully",
+		"item_id": itemID,
+		"version": version,
+	}, map[string]string{
+		"ETag":                          report.ETag(version),
+		"Access-Control-Expose-Headers": "ETag",
+	})
+}
+
+// editError maps the errors of report.LockForEdit to responses
+func editError(err error, version int) (events.APIGatewayProxyResponse, error) {
+	switch {
+	case errors.Is(err, report.ErrReportNotFound):
+		return response.Error(404, "Report not found")
+	case errors.Is(err, report.ErrNotEditable):
+		return response.Error(422, "Report was saved with validation errors and cannot be edited")
+	case errors.Is(err, report.ErrVersionConflict):
+		return response.ErrorWithHeaders(409, "Report has been modified, reload and try again", map[string]string{
+			"ETag":                          report.ETag(version),
+			"Access-Control-Expose-Headers": "ETag",
+		})
+	}
+	return response.Error(500, err.Error())
+}
+
+func main() {
+	lambda.Start(handler)
+}
@@ -2,12 +2,15 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/x-zero/business-consultant/pkg/access"
 	"github.com/x-zero/business-consultant/pkg/auth"
 	"github.com/x-zero/business-consultant/pkg/db"
+	"github.com/x-zero/business-consultant/pkg/report"
 	"github.com/x-zero/business-consultant/pkg/response"
 )
 
@@ -43,21 +46,33 @@ func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 
 	pool := db.GetPool()
 
-	result, err := pool.Exec(ctx, `
-		DELETE FROM business_reports
-		WHERE report_id = $1 AND user_did = $2
-	`, reportID, claims.DID)
+	if _, err := access.Report(ctx, pool, reportID, claims.DID, authHeader, access.Delete); err != nil {
+		return access.ErrorResponse(err)
+	}
 
+	// Reports go to the trash first and are purged after the retention
+	// window, so an accidental delete can be undone
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return response.Error(500, fmt.Sprintf("Database error: %v", err))
+	}
+	defer tx.Rollback(ctx)
+
+	purgeAt, err := report.Trash(ctx, tx, reportID, claims.DID)
+	if errors.Is(err, report.ErrReportNotFound) {
+		return response.Error(404, "Report not found")
+	}
 	if err != nil {
 		return response.Error(500, fmt.Sprintf("Failed to delete report: %v", err))
 	}
 
-	if result.RowsAffected() == 0 {
-		return response.Error(404, "Report not found or access denied")
+	if err := tx.Commit(ctx); err != nil {
+		return response.Error(500, fmt.Sprintf("Failed to delete report: %v", err))
 	}
 
 	return response.Success(map[string]interface{}{
-		"message": "Report deleted successfully",
+		"message":  "Report moved to trash",
+		"purge_at": purgeAt,
 	})
 }
 
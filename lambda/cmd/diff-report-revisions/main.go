@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/x-zero/business-consultant/pkg/access"
+	"github.com/x-zero/business-consultant/pkg/auth"
+	"github.com/x-zero/business-consultant/pkg/db"
+	"github.com/x-zero/business-consultant/pkg/report"
+	"github.com/x-zero/business-consultant/pkg/response"
+)
+
+// handler compares two revisions of a report:
+// GET /report/{id}/diff?from=1&to=3
+// from defaults to the first revision (what the AI proposed), to to the
+// current version.
+func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Handle OPTIONS
+	if request.HTTPMethod == "OPTIONS" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 200,
+			Headers: map[string]string{
+				"Access-Control-Allow-Origin":  "*",
+				"Access-Control-Allow-Headers": "Content-Type,Authorization",
+				"Access-Control-Allow-Methods": "GET,OPTIONS",
+			},
+		}, nil
+	}
+
+	// Validate JWT
+	authHeader := request.Headers["Authorization"]
+	if authHeader == "" {
+		authHeader = request.Headers["authorization"]
+	}
+	claims, err := auth.ValidateToken(authHeader)
+	if err != nil {
+		return response.Error(401, fmt.Sprintf("Invalid token: %v", err))
+	}
+
+	reportID := request.PathParameters["id"]
+	if reportID == "" {
+		return response.Error(400, "Report ID is required")
+	}
+
+	// Initialize database
+	if err := db.InitDB(); err != nil {
+		return response.Error(500, fmt.Sprintf("Database error: %v", err))
+	}
+
+	pool := db.GetPool()
+
+	// Verify access
+	if _, err := access.Report(ctx, pool, reportID, claims.DID, authHeader, access.View); err != nil {
+		return access.ErrorResponse(err)
+	}
+	var currentVersion int
+	err = pool.QueryRow(ctx, `
+		SELECT version FROM business_reports WHERE report_id = $1
+	`, reportID).Scan(&currentVersion)
+	if err != nil {
+		return response.Error(404, "Report not found")
+	}
+
+	fromVersion, err := versionParam(request.QueryStringParameters["from"], 1)
+	if err != nil {
+		return response.Error(400, "Invalid from version")
+	}
+	toVersion, err := versionParam(request.QueryStringParameters["to"], currentVersion)
+	if err != nil {
+		return response.Error(400, "Invalid to version")
+	}
+
+	from, err := report.GetRevision(ctx, pool, reportID, fromVersion)
+	if errors.Is(err, report.ErrRevisionNotFound) {
+		return response.Error(404, fmt.Sprintf("Revision %d not found", fromVersion))
+	}
+	if err != nil {
+		return response.Error(500, err.Error())
+	}
+
+	to, err := report.GetRevision(ctx, pool, reportID, toVersion)
+	if errors.Is(err, report.ErrRevisionNotFound) {
+		return response.Error(404, fmt.Sprintf("Revision %d not found", toVersion))
+	}
+	if err != nil {
+		return response.Error(500, err.Error())
+	}
+
+	diff, err := report.DiffRevisions(from, to)
+	if err != nil {
+		return response.Error(500, err.Error())
+	}
+
+	return response.Success(diff)
+}
+
+// versionParam parses a revision version query parameter
+func versionParam(value string, def int) (int, error) {
+	if value == "" {
+		return def, nil
+	}
+	version, err := strconv.Atoi(value)
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("invalid version: %s", value)
+	}
+	return version, nil
+}
+
+func main() {
+	lambda.Start(handler)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/x-zero/business-consultant/pkg/access"
+	"github.com/x-zero/business-consultant/pkg/auth"
+	"github.com/x-zero/business-consultant/pkg/budget"
+	"github.com/x-zero/business-consultant/pkg/db"
+	"github.com/x-zero/business-consultant/pkg/export"
+	"github.com/x-zero/business-consultant/pkg/report"
+	"github.com/x-zero/business-consultant/pkg/response"
+)
+
+// maxNameLength limits the part of the file name taken from the goal
+const maxNameLength = 40
+
+// handler downloads a report as a file:
+// GET /report/{id}/export?format=markdown|pdf|docx|csv|xlsx
+// The csv and xlsx formats hold the budget only. Clients must send the
+// content type in Accept for API Gateway to return PDF, DOCX and XLSX files
+// as binary.
+func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Handle OPTIONS
+	if request.HTTPMethod == "OPTIONS" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 200,
+			Headers: map[string]string{
+				"Access-Control-Allow-Origin":  "*",
+				"Access-Control-Allow-Headers": "Content-Type,Authorization",
+				"Access-Control-Allow-Methods": "GET,OPTIONS",
+			},
+		}, nil
+	}
+
+	// Validate JWT
+	authHeader := request.Headers["Authorization"]
+	if authHeader == "" {
+		authHeader = request.Headers["authorization"]
+	}
+	claims, err := auth.ValidateToken(authHeader)
+	if err != nil {
+		return response.Error(401, fmt.Sprintf("Invalid token: %v", err))
+	}
+
+	reportID := request.PathParameters["id"]
+	if reportID == "" {
+		return response.Error(400, "Report ID is required")
+	}
+
+	format := request.QueryStringParameters["format"]
+	switch format {
+	case export.FormatMarkdown, export.FormatPDF, export.FormatDOCX, export.FormatCSV, export.FormatXLSX:
+	default:
+		return response.Error(400, "format must be markdown, pdf, docx, csv or xlsx")
+	}
+
+	// Initialize database
+	if err := db.InitDB(); err != nil {
+		return response.Error(500, fmt.Sprintf("Database error: %v", err))
+	}
+
+	pool := db.GetPool()
+
+	// Verify access
+	if _, err := access.Report(ctx, pool, reportID, claims.DID, authHeader, access.View); err != nil {
+		return access.ErrorResponse(err)
+	}
+
+	var recommendations, validationErrors []byte
+	r := export.Report{ReportID: reportID}
+	err = pool.QueryRow(ctx, `
+		SELECT business_goal, recommendations, validation_errors, version, created_at, updated_at
+		FROM business_reports
+		WHERE report_id = $1 AND deleted_at IS NULL
+	`, reportID).Scan(&r.BusinessGoal, &recommendations, &validationErrors, &r.Version, &r.CreatedAt, &r.UpdatedAt)
+	if err != nil {
+		return response.Error(404, "Report not found")
+	}
+
+	// Items and their statuses are read from the item tables
+	current, err := report.Current(ctx, pool, reportID, recommendations, validationErrors == nil)
+	if err != nil {
+		return response.Error(500, err.Error())
+	}
+
+	var recs report.Recommendations
+	if err := json.Unmarshal(current, &recs); err != nil {
+		return response.Error(422, "Report recommendations cannot be exported")
+	}
+	r.Recs = &recs
+
+	// Budgets are only derived from valid reports
+	if validationErrors == nil {
+		r.Budget = budget.Compute(&recs)
+	}
+
+	file, err := export.Render(format, &r)
+	if err != nil {
+		return response.Error(500, fmt.Sprintf("Failed to export report: %v", err))
+	}
+
+	return response.File(file.Data, file.ContentType, fileName(r.BusinessGoal, r.UpdatedAt, file.Extension))
+}
+
+// fileName names the download after the goal and the date of the report
+func fileName(goal string, updatedAt time.Time, extension string) string {
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) || r < 0x20 {
+			return -1
+		}
+		return r
+	}, strings.Join(strings.Fields(goal), "_"))
+	if runes := []rune(name); len(runes) > maxNameLength {
+		name = string(runes[:maxNameLength])
+	}
+	if name == "" {
+		name = "report"
+	}
+	return fmt.Sprintf("%s-%s.%s", name, updatedAt.Format("20060102"), extension)
+}
+
+func main() {
+	lambda.Start(handler)
+}
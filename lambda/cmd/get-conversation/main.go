@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/x-zero/business-consultant/pkg/auth"
+	"github.com/x-zero/business-consultant/pkg/conversation"
+	"github.com/x-zero/business-consultant/pkg/db"
+	"github.com/x-zero/business-consultant/pkg/response"
+)
+
+// handler returns a conversation with its full message history, used to
+// resume a session on any device
+func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Handle OPTIONS
+	if request.HTTPMethod == "OPTIONS" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 200,
+			Headers: map[string]string{
+				"Access-Control-Allow-Origin":  "*",
+				"Access-Control-Allow-Headers": "Content-Type,Authorization",
+				"Access-Control-Allow-Methods": "GET,OPTIONS",
+			},
+		}, nil
+	}
+
+	// Validate JWT
+	authHeader := request.Headers["Authorization"]
+	if authHeader == "" {
+		authHeader = request.Headers["authorization"]
+	}
+	claims, err := auth.ValidateToken(authHeader)
+	if err != nil {
+		return response.Error(401, fmt.Sprintf("Invalid token: %v", err))
+	}
+
+	// Get conversation ID from path
+	conversationID := request.PathParameters["id"]
+	if conversationID == "" {
+		return response.Error(400, "Conversation ID is required")
+	}
+
+	// Initialize database
+	if err := db.InitDB(); err != nil {
+		return response.Error(500, fmt.Sprintf("Database error: %v", err))
+	}
+
+	pool := db.GetPool()
+
+	conv, err := conversation.Get(ctx, pool, conversationID, claims.DID)
+	if errors.Is(err, conversation.ErrNotFound) {
+		return response.Error(404, "Conversation not found")
+	}
+	if err != nil {
+		return response.Error(500, err.Error())
+	}
+
+	conv.Messages, err = conversation.Messages(ctx, pool, conversationID)
+	if err != nil {
+		return response.Error(500, err.Error())
+	}
+
+	return response.Success(conv)
+}
+
+func main() {
+	lambda.Start(handler)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/google/uuid"
+	"github.com/x-zero/business-consultant/pkg/access"
+	"github.com/x-zero/business-consultant/pkg/auth"
+	"github.com/x-zero/business-consultant/pkg/db"
+	"github.com/x-zero/business-consultant/pkg/response"
+)
+
+// handler lists the members of a project and their roles; only members may
+// see them:
+// GET /project/{project_id}/members
+func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Handle OPTIONS
+	if request.HTTPMethod == "OPTIONS" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 200,
+			Headers: map[string]string{
+				"Access-Control-Allow-Origin":  "*",
+				"Access-Control-Allow-Headers": "Content-Type,Authorization",
+				"Access-Control-Allow-Methods": "GET,OPTIONS",
+			},
+		}, nil
+	}
+
+	// Validate JWT
+	authHeader := request.Headers["Authorization"]
+	if authHeader == "" {
+		authHeader = request.Headers["authorization"]
+	}
+	claims, err := auth.ValidateToken(authHeader)
+	if err != nil {
+		return response.Error(401, fmt.Sprintf("Invalid token: %v", err))
+	}
+
+	projectID := request.PathParameters["project_id"]
+	if _, err := uuid.Parse(projectID); err != nil {
+		return response.Error(400, "Invalid project_id")
+	}
+
+	// Initialize database
+	if err := db.InitDB(); err != nil {
+		return response.Error(500, fmt.Sprintf("Database error: %v", err))
+	}
+
+	pool := db.GetPool()
+
+	role, err := access.Project(ctx, pool, projectID, claims.DID, authHeader)
+	if err != nil {
+		return response.Error(500, err.Error())
+	}
+	if role == "" {
+		return response.Error(403, "Access denied")
+	}
+
+	members, err := access.Members(ctx, pool, projectID)
+	if err != nil {
+		return response.Error(500, err.Error())
+	}
+
+	return response.Success(map[string]interface{}{
+		"members": members,
+		"role":    role,
+	})
+}
+
+func main() {
+	lambda.Start(handler)
+}
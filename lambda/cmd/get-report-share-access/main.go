@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/google/uuid"
+	"github.com/x-zero/business-consultant/pkg/access"
+	"github.com/x-zero/business-consultant/pkg/auth"
+	"github.com/x-zero/business-consultant/pkg/db"
+	"github.com/x-zero/business-consultant/pkg/response"
+	"github.com/x-zero/business-consultant/pkg/share"
+)
+
+const (
+	defaultLimit = 100
+	maxLimit     = 500
+)
+
+// handler returns the access log of a share link, most recent first:
+// GET /report/{id}/shares/{share_id}/access?limit=100
+func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Handle OPTIONS
+	if request.HTTPMethod == "OPTIONS" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 200,
+			Headers: map[string]string{
+				"Access-Control-Allow-Origin":  "*",
+				"Access-Control-Allow-Headers": "Content-Type,Authorization",
+				"Access-Control-Allow-Methods": "GET,OPTIONS",
+			},
+		}, nil
+	}
+
+	// Validate JWT
+	authHeader := request.Headers["Authorization"]
+	if authHeader == "" {
+		authHeader = request.Headers["authorization"]
+	}
+	claims, err := auth.ValidateToken(authHeader)
+	if err != nil {
+		return response.Error(401, fmt.Sprintf("Invalid token: %v", err))
+	}
+
+	reportID := request.PathParameters["id"]
+	if reportID == "" {
+		return response.Error(400, "Report ID is required")
+	}
+	shareID := request.PathParameters["share_id"]
+	if _, err := uuid.Parse(shareID); err != nil {
+		return response.Error(400, "Invalid share_id")
+	}
+	limit := defaultLimit
+	if v := request.QueryStringParameters["limit"]; v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 || limit > maxLimit {
+			return response.Error(400, fmt.Sprintf("limit must be between 1 and %d", maxLimit))
+		}
+	}
+
+	// Initialize database
+	if err := db.InitDB(); err != nil {
+		return response.Error(500, fmt.Sprintf("Database error: %v", err))
+	}
+
+	pool := db.GetPool()
+
+	// Verify access
+	if _, err := access.Report(ctx, pool, reportID, claims.DID, authHeader, access.Manage); err != nil {
+		return access.ErrorResponse(err)
+	}
+
+	log, err := share.ListAccess(ctx, pool, reportID, shareID, limit)
+	if err != nil {
+		return response.Error(500, err.Error())
+	}
+
+	return response.Success(log)
+}
+
+func main() {
+	lambda.Start(handler)
+}
@@ -7,8 +7,11 @@ import (
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/x-zero/business-consultant/pkg/access"
 	"github.com/x-zero/business-consultant/pkg/auth"
+	"github.com/x-zero/business-consultant/pkg/budget"
 	"github.com/x-zero/business-consultant/pkg/db"
+	"github.com/x-zero/business-consultant/pkg/report"
 	"github.com/x-zero/business-consultant/pkg/response"
 )
 
@@ -48,40 +51,69 @@ func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 
 	pool := db.GetPool()
 
+	// Verify access
+	grant, err := access.Report(ctx, pool, reportID, claims.DID, authHeader, access.View)
+	if err != nil {
+		return access.ErrorResponse(err)
+	}
+
 	// Query report
 	var projectID, businessGoal, userDID string
-	var recommendations []byte
+	var recommendations, validationErrors []byte
+	var conversationID *string
+	var version int
 	var createdAt, updatedAt interface{}
 
 	err = pool.QueryRow(ctx, `
-		SELECT report_id, user_did, project_id, business_goal, recommendations, created_at, updated_at
+		SELECT report_id, user_did, project_id, business_goal, recommendations, validation_errors, conversation_id, version, created_at, updated_at
 		FROM business_reports
-		WHERE report_id = $1
-	`, reportID).Scan(&reportID, &userDID, &projectID, &businessGoal, &recommendations, &createdAt, &updatedAt)
+		WHERE report_id = $1 AND deleted_at IS NULL
+	`, reportID).Scan(&reportID, &userDID, &projectID, &businessGoal, &recommendations, &validationErrors, &conversationID, &version, &createdAt, &updatedAt)
 
 	if err != nil {
 		return response.Error(404, "Report not found")
 	}
 
-	// Verify ownership
-	if userDID != claims.DID {
-		return response.Error(403, "Access denied")
+	// Items and their statuses are read from the item tables
+	current, err := report.Current(ctx, pool, reportID, recommendations, validationErrors == nil)
+	if err != nil {
+		return response.Error(500, err.Error())
 	}
 
 	// Parse recommendations JSON
 	var recsMap map[string]interface{}
-	if err := json.Unmarshal(recommendations, &recsMap); err != nil {
+	if err := json.Unmarshal(current, &recsMap); err != nil {
 		return response.Error(500, "Failed to parse recommendations")
 	}
 
-	return response.Success(map[string]interface{}{
+	result := map[string]interface{}{
 		"report_id":       reportID,
 		"user_did":        userDID,
 		"project_id":      projectID,
+		"role":            grant.Role, // of the caller, the creator counts as an owner
 		"business_goal":   businessGoal,
 		"recommendations": recsMap,
+		"conversation_id": conversationID,
+		"version":         version,
 		"created_at":      createdAt,
 		"updated_at":      updatedAt,
+	}
+
+	// Flag reports saved with allow_invalid; budgets are only derived from
+	// valid reports
+	if validationErrors != nil {
+		result["validation_errors"] = json.RawMessage(validationErrors)
+	} else {
+		var recs report.Recommendations
+		if err := json.Unmarshal(current, &recs); err == nil {
+			result["budget"] = budget.Compute(&recs)
+		}
+	}
+
+	// The ETag is sent back in If-Match to make updates conditional
+	return response.SuccessWithHeaders(result, map[string]string{
+		"ETag":                          report.ETag(version),
+		"Access-Control-Expose-Headers": "ETag",
 	})
 }
 
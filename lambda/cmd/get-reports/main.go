@@ -2,16 +2,35 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"strconv"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/google/uuid"
+	"github.com/x-zero/business-consultant/pkg/access"
 	"github.com/x-zero/business-consultant/pkg/auth"
 	"github.com/x-zero/business-consultant/pkg/db"
 	"github.com/x-zero/business-consultant/pkg/response"
+	"github.com/x-zero/business-consultant/pkg/search"
 )
 
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+// ReportsPage is one page of reports; NextCursor is null on the last page
+type ReportsPage struct {
+	Reports    []search.ReportSummary `json:"reports"`
+	NextCursor *string                `json:"next_cursor"`
+}
+
+// handler lists the reports of a project one page at a time:
+// GET /reports?project_id=&sort=created_at|updated_at|total_budget&order=desc|asc&limit=20&cursor=&fields=summary|full
+// The summary projection leaves out the recommendations body.
 func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 	// Handle OPTIONS
 	if request.HTTPMethod == "OPTIONS" {
@@ -35,11 +54,47 @@ func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 		return response.Error(401, fmt.Sprintf("Invalid token: %v", err))
 	}
 
-	// Get project_id from query params
-	projectID := request.QueryStringParameters["project_id"]
+	params := request.QueryStringParameters
+	projectID := params["project_id"]
 	if projectID == "" {
 		return response.Error(400, "Project ID is required")
 	}
+	if _, err := uuid.Parse(projectID); err != nil {
+		return response.Error(400, "Invalid project_id")
+	}
+
+	opts := search.ListOptions{
+		UserDID:   claims.DID,
+		ProjectID: projectID,
+		Sort:      params["sort"],
+		Limit:     defaultLimit,
+		Cursor:    params["cursor"],
+	}
+	if opts.Sort == "" {
+		opts.Sort = search.SortCreatedAt
+	}
+	if opts.Sort != search.SortCreatedAt && opts.Sort != search.SortUpdatedAt && opts.Sort != search.SortTotalBudget {
+		return response.Error(400, "sort must be created_at, updated_at or total_budget")
+	}
+	switch params["order"] {
+	case "", "desc":
+	case "asc":
+		opts.Ascending = true
+	default:
+		return response.Error(400, "order must be asc or desc")
+	}
+	switch params["fields"] {
+	case "", "summary":
+	case "full":
+		opts.Full = true
+	default:
+		return response.Error(400, "fields must be summary or full")
+	}
+	if v := params["limit"]; v != "" {
+		if opts.Limit, err = strconv.Atoi(v); err != nil || opts.Limit < 1 || opts.Limit > maxLimit {
+			return response.Error(400, fmt.Sprintf("limit must be between 1 and %d", maxLimit))
+		}
+	}
 
 	// Initialize database
 	if err := db.InitDB(); err != nil {
@@ -48,48 +103,29 @@ func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 
 	pool := db.GetPool()
 
-	// Query reports
-	rows, err := pool.Query(ctx, `
-		SELECT report_id, project_id, business_goal, recommendations, created_at, updated_at
-		FROM business_reports
-		WHERE user_did = $1 AND project_id = $2
-		ORDER BY created_at DESC
-	`, claims.DID, projectID)
+	// Members of the X-Zero project own it and see all its reports; when the
+	// X-Zero API fails, the roles already stored still apply
+	if _, err := access.Project(ctx, pool, projectID, claims.DID, authHeader); err != nil {
+		log.Printf("Failed to check X-Zero membership: %v", err)
+	}
 
+	reports, next, err := search.List(ctx, pool, opts)
+	if errors.Is(err, search.ErrInvalidCursor) {
+		return response.Error(400, "Invalid cursor")
+	}
 	if err != nil {
 		return response.Error(500, fmt.Sprintf("Failed to query reports: %v", err))
 	}
-	defer rows.Close()
-
-	// Collect reports
-	reports := []map[string]interface{}{}
-	for rows.Next() {
-		var reportID, projectIDVal, businessGoal string
-		var recommendations []byte
-		var createdAt, updatedAt interface{}
-
-		err := rows.Scan(&reportID, &projectIDVal, &businessGoal, &recommendations, &createdAt, &updatedAt)
-		if err != nil {
-			return response.Error(500, fmt.Sprintf("Failed to scan report: %v", err))
-		}
-
-		// Parse recommendations JSON
-		var recsMap map[string]interface{}
-		if err := json.Unmarshal(recommendations, &recsMap); err != nil {
-			recsMap = nil
-		}
 
-		reports = append(reports, map[string]interface{}{
-			"report_id":       reportID,
-			"project_id":      projectIDVal,
-			"business_goal":   businessGoal,
-			"recommendations": recsMap,
-			"created_at":      createdAt,
-			"updated_at":      updatedAt,
-		})
+	var nextCursor *string
+	if next != "" {
+		nextCursor = &next
 	}
 
-	return response.Success(reports)
+	return response.Success(ReportsPage{
+		Reports:    reports,
+		NextCursor: nextCursor,
+	})
 }
 
 func main() {
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/x-zero/business-consultant/pkg/db"
+	"github.com/x-zero/business-consultant/pkg/response"
+	"github.com/x-zero/business-consultant/pkg/share"
+)
+
+// handler serves the read-only view of a shared report without login:
+// GET /shared/{token}
+// Password protected links take the password in the X-Share-Password
+// header. Refusals use 403 rather than 401, which the frontend treats as an
+// expired login.
+func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Handle OPTIONS
+	if request.HTTPMethod == "OPTIONS" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 200,
+			Headers: map[string]string{
+				"Access-Control-Allow-Origin":  "*",
+				"Access-Control-Allow-Headers": "Content-Type,X-Share-Password",
+				"Access-Control-Allow-Methods": "GET,OPTIONS",
+			},
+		}, nil
+	}
+
+	token := request.PathParameters["token"]
+	if token == "" {
+		return response.Error(404, "Share link not found")
+	}
+
+	password := request.Headers["X-Share-Password"]
+	if password == "" {
+		password = request.Headers["x-share-password"]
+	}
+	userAgent := request.Headers["User-Agent"]
+	if userAgent == "" {
+		userAgent = request.Headers["user-agent"]
+	}
+	visitor := share.Visitor{
+		SourceIP:  request.RequestContext.Identity.SourceIP,
+		UserAgent: userAgent,
+	}
+
+	// Initialize database
+	if err := db.InitDB(); err != nil {
+		return response.Error(500, fmt.Sprintf("Database error: %v", err))
+	}
+
+	pool := db.GetPool()
+
+	reportID, err := share.Open(ctx, pool, token, password, visitor)
+	switch {
+	case errors.Is(err, share.ErrNotFound):
+		return response.Error(404, "Share link not found")
+	case errors.Is(err, share.ErrRevoked):
+		return response.Error(410, "Share link has been revoked")
+	case errors.Is(err, share.ErrExpired):
+		return response.Error(410, "Share link has expired")
+	case errors.Is(err, share.ErrPasswordRequired):
+		return response.ErrorWithDetails(403, "Password required", map[string]bool{"password_required": true})
+	case errors.Is(err, share.ErrWrongPassword):
+		return response.ErrorWithDetails(403, "Incorrect password", map[string]bool{"password_required": true})
+	case errors.Is(err, share.ErrThrottled):
+		return response.ErrorWithHeaders(429, "Too many incorrect passwords, try again later", map[string]string{
+			"Retry-After":                   strconv.Itoa(int(share.FailureWindow.Seconds())),
+			"Access-Control-Expose-Headers": "Retry-After",
+		})
+	case err != nil:
+		return response.Error(500, err.Error())
+	}
+
+	view, err := share.View(ctx, pool, reportID)
+	if err != nil {
+		return response.Error(500, err.Error())
+	}
+
+	return response.Success(view)
+}
+
+func main() {
+	lambda.Start(handler)
+}
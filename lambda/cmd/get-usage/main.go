@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/google/uuid"
+	"github.com/x-zero/business-consultant/pkg/auth"
+	"github.com/x-zero/business-consultant/pkg/db"
+	"github.com/x-zero/business-consultant/pkg/response"
+	"github.com/x-zero/business-consultant/pkg/usage"
+)
+
+const dateLayout = "2006-01-02"
+
+// UsageResponse is the LLM usage of the caller over a date range
+type UsageResponse struct {
+	Period    string         `json:"period"`
+	From      string         `json:"from"`
+	To        string         `json:"to"`
+	ProjectID string         `json:"project_id,omitempty"`
+	Currency  string         `json:"currency"`
+	Buckets   []usage.Bucket `json:"buckets"`
+	Total     usage.Bucket   `json:"total"`
+}
+
+// handler returns daily or monthly usage aggregates of the caller:
+// GET /usage?period=daily|monthly&from=2006-01-02&to=2006-01-02&project_id=...
+// Both dates are inclusive; by default the last 30 days or 12 months are used.
+func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Handle OPTIONS
+	if request.HTTPMethod == "OPTIONS" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 200,
+			Headers: map[string]string{
+				"Access-Control-Allow-Origin":  "*",
+				"Access-Control-Allow-Headers": "Content-Type,Authorization",
+				"Access-Control-Allow-Methods": "GET,OPTIONS",
+			},
+		}, nil
+	}
+
+	// Validate JWT
+	authHeader := request.Headers["Authorization"]
+	if authHeader == "" {
+		authHeader = request.Headers["authorization"]
+	}
+	claims, err := auth.ValidateToken(authHeader)
+	if err != nil {
+		return response.Error(401, fmt.Sprintf("Invalid token: %v", err))
+	}
+
+	params := request.QueryStringParameters
+	period := params["period"]
+	if period == "" {
+		period = usage.Daily
+	}
+	if period != usage.Daily && period != usage.Monthly {
+		return response.Error(400, "period must be daily or monthly")
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	to, err := parseDate(params["to"], today)
+	if err != nil {
+		return response.Error(400, "Invalid to date, expected YYYY-MM-DD")
+	}
+	defaultFrom := to.AddDate(0, 0, -29)
+	if period == usage.Monthly {
+		defaultFrom = time.Date(to.Year(), to.Month()-11, 1, 0, 0, 0, 0, time.UTC)
+	}
+	from, err := parseDate(params["from"], defaultFrom)
+	if err != nil {
+		return response.Error(400, "Invalid from date, expected YYYY-MM-DD")
+	}
+	if from.After(to) {
+		return response.Error(400, "from must not be after to")
+	}
+
+	if projectID := params["project_id"]; projectID != "" {
+		if _, err := uuid.Parse(projectID); err != nil {
+			return response.Error(400, "Invalid project_id")
+		}
+	}
+
+	// Initialize database
+	if err := db.InitDB(); err != nil {
+		return response.Error(500, fmt.Sprintf("Database error: %v", err))
+	}
+
+	buckets, err := usage.Aggregate(ctx, db.GetPool(), usage.Filter{
+		UserDID:   claims.DID,
+		ProjectID: params["project_id"],
+		From:      from,
+		To:        to.AddDate(0, 0, 1),
+	}, period)
+	if err != nil {
+		return response.Error(500, err.Error())
+	}
+
+	return response.Success(UsageResponse{
+		Period:    period,
+		From:      from.Format(dateLayout),
+		To:        to.Format(dateLayout),
+		ProjectID: params["project_id"],
+		Currency:  "USD",
+		Buckets:   buckets,
+		Total:     usage.Total(buckets),
+	})
+}
+
+// parseDate parses a YYYY-MM-DD query parameter, falling back to def
+func parseDate(value string, def time.Time) (time.Time, error) {
+	if value == "" {
+		return def, nil
+	}
+	return time.Parse(dateLayout, value)
+}
+
+func main() {
+	lambda.Start(handler)
+}
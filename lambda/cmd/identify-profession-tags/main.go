@@ -10,11 +10,14 @@ import (
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/x-zero/business-consultant/pkg/auth"
 	"github.com/x-zero/business-consultant/pkg/deepseek"
+	"github.com/x-zero/business-consultant/pkg/ratelimit"
 	"github.com/x-zero/business-consultant/pkg/response"
+	"github.com/x-zero/business-consultant/pkg/usage"
 )
 
 type IdentifyTagsRequest struct {
 	TaskDescription string `json:"task_description"`
+	ProjectID       string `json:"project_id"` // optional, for usage accounting
 }
 
 type IdentifyTagsResponse struct {
@@ -42,7 +45,7 @@ func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 		authHeader = request.Headers["authorization"]
 	}
 
-	_, err := auth.ValidateToken(authHeader)
+	claims, err := auth.ValidateToken(authHeader)
 	if err != nil {
 		fmt.Printf("Token validation error: %v\n", err)
 		return response.Error(401, "Invalid or expired token")
@@ -61,10 +64,18 @@ func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 
 	fmt.Printf("Task description: %s\n", req.TaskDescription)
 
+	// Per-user rate limit and daily token quota
+	limit := ratelimit.Check(ctx, claims.DID, "identify-profession-tags")
+	if !limit.Allowed {
+		return response.ErrorWithHeaders(429, limit.Reason, limit.Headers())
+	}
+
+	ctx = usage.WithScope(ctx, usage.Scope{UserDID: claims.DID, ProjectID: req.ProjectID, Endpoint: "identify-profession-tags"})
+
 	// Call DeepSeek to identify tags
 	tags, err := identifyTags(ctx, req.TaskDescription)
 	if err != nil {
-		fmt.Printf("DeepSeek API error: %v\n", err)
+		fmt.Printf("DeepSeek API error (%s): %v\n", deepseek.KindOf(err), err)
 		// Return empty array on error
 		return response.Success(IdentifyTagsResponse{
 			ProfessionTags: []string{},
@@ -105,8 +116,12 @@ func identifyTags(ctx context.Context, taskDescription string) ([]string, error)
 		},
 	}
 
-	client := deepseek.NewClient()
-	content, err := client.Chat(messages)
+	client, err := deepseek.NewProvider(usage.Recorder)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := client.Chat(ctx, messages)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/x-zero/business-consultant/pkg/db"
+	"github.com/x-zero/business-consultant/pkg/report"
+)
+
+// handler runs on a schedule and permanently deletes reports that have been
+// in the trash longer than TRASH_RETENTION_DAYS
+func handler(ctx context.Context, event events.CloudWatchEvent) error {
+	if err := db.InitDB(); err != nil {
+		return fmt.Errorf("database error: %v", err)
+	}
+
+	purged, err := report.PurgeExpired(ctx, db.GetPool())
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Purged %d reports trashed more than %s ago", purged, report.Retention())
+	return nil
+}
+
+func main() {
+	lambda.Start(handler)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/google/uuid"
+	"github.com/x-zero/business-consultant/pkg/access"
+	"github.com/x-zero/business-consultant/pkg/auth"
+	"github.com/x-zero/business-consultant/pkg/db"
+	"github.com/x-zero/business-consultant/pkg/report"
+	"github.com/x-zero/business-consultant/pkg/response"
+)
+
+// handler permanently deletes trashed reports the caller may delete:
+// DELETE /reports/trash?report_id=... purges one report, without report_id
+// the whole trash
+func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	if request.HTTPMethod == "OPTIONS" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 200,
+			Headers: map[string]string{
+				"Access-Control-Allow-Origin":  "*",
+				"Access-Control-Allow-Headers": "Content-Type,Authorization",
+				"Access-Control-Allow-Methods": "DELETE,OPTIONS",
+			},
+		}, nil
+	}
+
+	authHeader := request.Headers["Authorization"]
+	if authHeader == "" {
+		authHeader = request.Headers["authorization"]
+	}
+	claims, err := auth.ValidateToken(authHeader)
+	if err != nil {
+		return response.Error(401, fmt.Sprintf("Invalid token: %v", err))
+	}
+
+	reportID := request.QueryStringParameters["report_id"]
+	if reportID != "" {
+		if _, err := uuid.Parse(reportID); err != nil {
+			return response.Error(400, "Invalid report_id")
+		}
+	}
+
+	if err := db.InitDB(); err != nil {
+		return response.Error(500, fmt.Sprintf("Database error: %v", err))
+	}
+
+	pool := db.GetPool()
+
+	if reportID != "" {
+		if _, err := access.TrashedReport(ctx, pool, reportID, claims.DID, authHeader, access.Delete); err != nil {
+			if errors.Is(err, access.ErrNotFound) {
+				return response.Error(404, "Report not found in trash")
+			}
+			return access.ErrorResponse(err)
+		}
+	}
+
+	purged, err := report.Purge(ctx, pool, claims.DID, reportID)
+	if err != nil {
+		return response.Error(500, err.Error())
+	}
+	if reportID != "" && purged == 0 {
+		return response.Error(404, "Report not found in trash")
+	}
+
+	return response.Success(map[string]interface{}{
+		"message": "Trash emptied",
+		"purged":  purged,
+	})
+}
+
+func main() {
+	lambda.Start(handler)
+}
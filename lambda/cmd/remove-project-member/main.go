@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/google/uuid"
+	"github.com/x-zero/business-consultant/pkg/access"
+	"github.com/x-zero/business-consultant/pkg/auth"
+	"github.com/x-zero/business-consultant/pkg/db"
+	"github.com/x-zero/business-consultant/pkg/response"
+)
+
+// handler removes a member from a project. Owners may remove anyone, other
+// members only themselves; the last owner cannot be removed. Reports of the
+// removed member stay in the project and remain theirs.
+// DELETE /project/{project_id}/members?user_did=
+func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Handle OPTIONS
+	if request.HTTPMethod == "OPTIONS" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 200,
+			Headers: map[string]string{
+				"Access-Control-Allow-Origin":  "*",
+				"Access-Control-Allow-Headers": "Content-Type,Authorization",
+				"Access-Control-Allow-Methods": "DELETE,OPTIONS",
+			},
+		}, nil
+	}
+
+	// Validate JWT
+	authHeader := request.Headers["Authorization"]
+	if authHeader == "" {
+		authHeader = request.Headers["authorization"]
+	}
+	claims, err := auth.ValidateToken(authHeader)
+	if err != nil {
+		return response.Error(401, fmt.Sprintf("Invalid token: %v", err))
+	}
+
+	projectID := request.PathParameters["project_id"]
+	if _, err := uuid.Parse(projectID); err != nil {
+		return response.Error(400, "Invalid project_id")
+	}
+	userDID := request.QueryStringParameters["user_did"]
+	if userDID == "" {
+		return response.Error(400, "user_did is required")
+	}
+
+	// Initialize database
+	if err := db.InitDB(); err != nil {
+		return response.Error(500, fmt.Sprintf("Database error: %v", err))
+	}
+
+	pool := db.GetPool()
+
+	if userDID != claims.DID {
+		role, err := access.Project(ctx, pool, projectID, claims.DID, authHeader)
+		if err != nil {
+			return response.Error(500, err.Error())
+		}
+		if role != access.RoleOwner {
+			return response.Error(403, "Only project owners can remove other members")
+		}
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return response.Error(500, fmt.Sprintf("Database error: %v", err))
+	}
+	defer tx.Rollback(ctx)
+
+	err = access.RemoveMember(ctx, tx, projectID, userDID)
+	if errors.Is(err, access.ErrMemberNotFound) {
+		return response.Error(404, "Member not found")
+	}
+	if errors.Is(err, access.ErrLastOwner) {
+		return response.Error(409, "A project must keep at least one owner")
+	}
+	if err != nil {
+		return response.Error(500, err.Error())
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return response.Error(500, fmt.Sprintf("Failed to remove member: %v", err))
+	}
+
+	return response.Success(map[string]interface{}{
+		"message":  "Member removed",
+		"user_did": userDID,
+	})
+}
+
+func main() {
+	lambda.Start(handler)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/x-zero/business-consultant/pkg/access"
+	"github.com/x-zero/business-consultant/pkg/auth"
+	"github.com/x-zero/business-consultant/pkg/budget"
+	"github.com/x-zero/business-consultant/pkg/db"
+	"github.com/x-zero/business-consultant/pkg/report"
+	"github.com/x-zero/business-consultant/pkg/response"
+)
+
+// ScenarioRequest holds the constraints of a what-if scenario. The scenario
+// is saved with the report when a name is given.
+type ScenarioRequest struct {
+	budget.Constraints
+	Name string `json:"name"`
+}
+
+// handler recomputes a report under budget constraints without modifying
+// it: POST /report/{id}/scenarios
+func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Handle OPTIONS
+	if request.HTTPMethod == "OPTIONS" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 200,
+			Headers: map[string]string{
+				"Access-Control-Allow-Origin":  "*",
+				"Access-Control-Allow-Headers": "Content-Type,Authorization",
+				"Access-Control-Allow-Methods": "POST,OPTIONS",
+			},
+		}, nil
+	}
+
+	// Validate JWT
+	authHeader := request.Headers["Authorization"]
+	if authHeader == "" {
+		authHeader = request.Headers["authorization"]
+	}
+	claims, err := auth.ValidateToken(authHeader)
+	if err != nil {
+		return response.Error(401, fmt.Sprintf("Invalid token: %v", err))
+	}
+
+	reportID := request.PathParameters["id"]
+	if reportID == "" {
+		return response.Error(400, "Report ID is required")
+	}
+
+	var req ScenarioRequest
+	if err := json.Unmarshal([]byte(request.Body), &req); err != nil {
+		return response.Error(400, "Invalid request body")
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if utf8.RuneCountInString(req.Name) > 255 {
+		return response.Error(400, "name must be at most 255 characters")
+	}
+
+	// Initialize database
+	if err := db.InitDB(); err != nil {
+		return response.Error(500, fmt.Sprintf("Database error: %v", err))
+	}
+
+	pool := db.GetPool()
+
+	// Viewers may run scenarios, saving them takes an editor
+	action := access.View
+	if req.Name != "" {
+		action = access.Edit
+	}
+	if _, err := access.Report(ctx, pool, reportID, claims.DID, authHeader, action); err != nil {
+		return access.ErrorResponse(err)
+	}
+
+	var recommendations, validationErrors []byte
+	var version int
+	err = pool.QueryRow(ctx, `
+		SELECT recommendations, validation_errors, version
+		FROM business_reports
+		WHERE report_id = $1 AND deleted_at IS NULL
+	`, reportID).Scan(&recommendations, &validationErrors, &version)
+	if err != nil {
+		return response.Error(404, "Report not found")
+	}
+	if validationErrors != nil {
+		return response.Error(422, "Report was saved with validation errors, budgets cannot be computed")
+	}
+
+	current, err := report.Current(ctx, pool, reportID, recommendations, true)
+	if err != nil {
+		return response.Error(500, err.Error())
+	}
+	var recs report.Recommendations
+	if err := json.Unmarshal(current, &recs); err != nil {
+		return response.Error(500, "Failed to parse recommendations")
+	}
+
+	scenario, invalid := budget.Run(&recs, req.Constraints)
+	if len(invalid) > 0 {
+		return response.ErrorWithDetails(422, "Invalid constraints", invalid)
+	}
+
+	result := map[string]interface{}{
+		"report_id":      reportID,
+		"report_version": version,
+		"scenario":       scenario,
+	}
+
+	if req.Name != "" {
+		scenarioID, err := budget.SaveScenario(ctx, pool, reportID, claims.DID, req.Name, version, scenario)
+		if err != nil {
+			return response.Error(500, err.Error())
+		}
+		result["scenario_id"] = scenarioID
+		result["name"] = req.Name
+	}
+
+	return response.Success(result)
+}
+
+func main() {
+	lambda.Start(handler)
+}
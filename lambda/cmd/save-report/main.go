@@ -3,20 +3,29 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/google/uuid"
+	"github.com/x-zero/business-consultant/pkg/access"
 	"github.com/x-zero/business-consultant/pkg/auth"
+	"github.com/x-zero/business-consultant/pkg/conversation"
 	"github.com/x-zero/business-consultant/pkg/db"
+	"github.com/x-zero/business-consultant/pkg/report"
 	"github.com/x-zero/business-consultant/pkg/response"
+	"github.com/x-zero/business-consultant/pkg/search"
 )
 
 type SaveReportRequest struct {
-	ProjectID       string                 `json:"project_id"`
-	BusinessGoal    string                 `json:"business_goal"`
-	Recommendations map[string]interface{} `json:"recommendations"`
+	ProjectID       string          `json:"project_id"`
+	BusinessGoal    string          `json:"business_goal"`
+	Recommendations json.RawMessage `json:"recommendations"`
+	ConversationID  string          `json:"conversation_id"`
+	// AllowInvalid stores recommendations that fail schema validation,
+	// flagging them with their validation errors instead of rejecting them
+	AllowInvalid bool `json:"allow_invalid"`
 }
 
 func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
@@ -51,11 +60,43 @@ func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 	if req.ProjectID == "" {
 		return response.Error(400, "Project ID is required")
 	}
+	if _, err := uuid.Parse(req.ProjectID); err != nil {
+		return response.Error(400, "Invalid project ID")
+	}
 
 	if req.BusinessGoal == "" {
 		return response.Error(400, "Business goal is required")
 	}
 
+	if len(req.Recommendations) == 0 {
+		req.Recommendations = json.RawMessage("null")
+	}
+
+	// Validate recommendations against the consultant schema
+	recs, validationErrors, err := report.ParseRecommendations(req.Recommendations)
+	if err != nil {
+		return response.Error(400, err.Error())
+	}
+	if len(validationErrors) > 0 && !req.AllowInvalid {
+		return response.ErrorWithDetails(422, "Invalid recommendations", validationErrors)
+	}
+
+	// Valid recommendations are stored in normalised form, invalid ones as
+	// sent together with their validation errors
+	recommendationsJSON := []byte(req.Recommendations)
+	var validationJSON *string
+	if recs != nil {
+		report.AssignIDs(recs)
+		recommendationsJSON, err = json.Marshal(recs)
+		if err != nil {
+			return response.Error(500, "Failed to marshal recommendations")
+		}
+	} else {
+		b, _ := json.Marshal(validationErrors)
+		v := string(b)
+		validationJSON = &v
+	}
+
 	// Initialize database
 	if err := db.InitDB(); err != nil {
 		return response.Error(500, fmt.Sprintf("Database error: %v", err))
@@ -63,27 +104,82 @@ func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 
 	pool := db.GetPool()
 
-	// Convert recommendations to JSON string
-	recommendationsJSON, err := json.Marshal(req.Recommendations)
+	// The report may be linked to the conversation it was generated in
+	var conversationID *string
+	if req.ConversationID != "" {
+		if _, err := conversation.Get(ctx, pool, req.ConversationID, claims.DID); err != nil {
+			if errors.Is(err, conversation.ErrNotFound) {
+				return response.Error(404, "Conversation not found")
+			}
+			return response.Error(500, err.Error())
+		}
+		conversationID = &req.ConversationID
+	}
+
+	// Only owners and editors add reports to a project
+	role, err := access.Project(ctx, pool, req.ProjectID, claims.DID, authHeader)
+	if err != nil {
+		return response.Error(500, err.Error())
+	}
+	if !access.Allows(role, access.RoleEditor) {
+		return response.Error(403, "Only project owners and editors can save reports in this project")
+	}
+
+	tx, err := pool.Begin(ctx)
 	if err != nil {
-		return response.Error(500, "Failed to marshal recommendations")
+		return response.Error(500, fmt.Sprintf("Database error: %v", err))
 	}
+	defer tx.Rollback(ctx)
 
 	// Insert report
 	reportID := uuid.New().String()
-	_, err = pool.Exec(ctx, `
-		INSERT INTO business_reports (report_id, user_did, project_id, business_goal, recommendations)
-		VALUES ($1, $2, $3, $4, $5::jsonb)
-	`, reportID, claims.DID, req.ProjectID, req.BusinessGoal, string(recommendationsJSON))
+	_, err = tx.Exec(ctx, `
+		INSERT INTO business_reports (report_id, user_did, project_id, business_goal, recommendations, validation_errors, conversation_id)
+		VALUES ($1, $2, $3, $4, $5::jsonb, $6::jsonb, $7)
+	`, reportID, claims.DID, req.ProjectID, req.BusinessGoal, string(recommendationsJSON), validationJSON, conversationID)
 
 	if err != nil {
 		return response.Error(500, fmt.Sprintf("Failed to save report: %v", err))
 	}
 
-	return response.Success(map[string]interface{}{
+	// Items go into their own tables so they can be updated individually
+	if recs != nil {
+		if err := report.SaveItems(ctx, tx, reportID, recs); err != nil {
+			return response.Error(500, err.Error())
+		}
+	}
+
+	if err := report.RecordRevision(ctx, tx, reportID, claims.DID, report.ActionCreate); err != nil {
+		return response.Error(500, err.Error())
+	}
+
+	if err := search.Index(ctx, tx, reportID); err != nil {
+		return response.Error(500, err.Error())
+	}
+
+	if conversationID != nil {
+		if err := conversation.LinkReport(ctx, tx, *conversationID, claims.DID, reportID); err != nil {
+			return response.Error(500, err.Error())
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return response.Error(500, fmt.Sprintf("Failed to save report: %v", err))
+	}
+
+	result := map[string]interface{}{
 		"report_id": reportID,
 		"message":   "Report saved successfully",
-	})
+	}
+	if recs != nil {
+		// Includes the item IDs assigned above
+		result["recommendations"] = recs
+	}
+	if len(validationErrors) > 0 {
+		result["validation_errors"] = validationErrors
+	}
+
+	return response.Success(result)
 }
 
 func main() {
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/google/uuid"
+	"github.com/x-zero/business-consultant/pkg/auth"
+	"github.com/x-zero/business-consultant/pkg/db"
+	"github.com/x-zero/business-consultant/pkg/report"
+	"github.com/x-zero/business-consultant/pkg/response"
+	"github.com/x-zero/business-consultant/pkg/search"
+)
+
+const (
+	dateLayout   = "2006-01-02"
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+// SearchResponse is one page of matching reports
+type SearchResponse struct {
+	Reports []search.Result `json:"reports"`
+	Total   int             `json:"total"`
+	Limit   int             `json:"limit"`
+	Offset  int             `json:"offset"`
+}
+
+// handler searches the reports of the caller:
+// GET /reports/search?q=&project_id=&from=&to=&min_budget=&max_budget=&status=&priority=&limit=&offset=
+// q matches business goal, summary, workflow names and descriptions and role
+// titles. Dates are inclusive; budgets apply to the total plan budget.
+func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Handle OPTIONS
+	if request.HTTPMethod == "OPTIONS" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 200,
+			Headers: map[string]string{
+				"Access-Control-Allow-Origin":  "*",
+				"Access-Control-Allow-Headers": "Content-Type,Authorization",
+				"Access-Control-Allow-Methods": "GET,OPTIONS",
+			},
+		}, nil
+	}
+
+	// Validate JWT
+	authHeader := request.Headers["Authorization"]
+	if authHeader == "" {
+		authHeader = request.Headers["authorization"]
+	}
+	claims, err := auth.ValidateToken(authHeader)
+	if err != nil {
+		return response.Error(401, fmt.Sprintf("Invalid token: %v", err))
+	}
+
+	params := request.QueryStringParameters
+	f := search.Filter{
+		UserDID:   claims.DID,
+		Query:     params["q"],
+		ProjectID: params["project_id"],
+		Status:    params["status"],
+		Priority:  params["priority"],
+		Limit:     defaultLimit,
+	}
+
+	if f.ProjectID != "" {
+		if _, err := uuid.Parse(f.ProjectID); err != nil {
+			return response.Error(400, "Invalid project_id")
+		}
+	}
+	if v := params["from"]; v != "" {
+		if f.From, err = time.Parse(dateLayout, v); err != nil {
+			return response.Error(400, "Invalid from date, expected YYYY-MM-DD")
+		}
+	}
+	if v := params["to"]; v != "" {
+		to, err := time.Parse(dateLayout, v)
+		if err != nil {
+			return response.Error(400, "Invalid to date, expected YYYY-MM-DD")
+		}
+		f.To = to.AddDate(0, 0, 1)
+	}
+	if f.MinBudget, err = parseAmount(params["min_budget"]); err != nil {
+		return response.Error(400, "min_budget must be a number")
+	}
+	if f.MaxBudget, err = parseAmount(params["max_budget"]); err != nil {
+		return response.Error(400, "max_budget must be a number")
+	}
+	if !search.ValidStatus(f.Status) {
+		return response.Error(400, fmt.Sprintf("status must be %s, %s or %s", report.StatusDraftCreated, report.StatusPublished, search.StatusNone))
+	}
+	if f.Priority != "" && f.Priority != report.PriorityHigh && f.Priority != report.PriorityMedium && f.Priority != report.PriorityLow {
+		return response.Error(400, "priority must be high, medium or low")
+	}
+	if v := params["limit"]; v != "" {
+		if f.Limit, err = strconv.Atoi(v); err != nil || f.Limit < 1 || f.Limit > maxLimit {
+			return response.Error(400, fmt.Sprintf("limit must be between 1 and %d", maxLimit))
+		}
+	}
+	if v := params["offset"]; v != "" {
+		if f.Offset, err = strconv.Atoi(v); err != nil || f.Offset < 0 {
+			return response.Error(400, "offset must be a non-negative integer")
+		}
+	}
+
+	// Initialize database
+	if err := db.InitDB(); err != nil {
+		return response.Error(500, fmt.Sprintf("Database error: %v", err))
+	}
+
+	pool := db.GetPool()
+
+	results, total, err := search.Search(ctx, pool, f)
+	if err != nil {
+		return response.Error(500, err.Error())
+	}
+
+	return response.Success(SearchResponse{
+		Reports: results,
+		Total:   total,
+		Limit:   f.Limit,
+		Offset:  f.Offset,
+	})
+}
+
+// parseAmount parses an optional numeric query parameter
+func parseAmount(value string) (*float64, error) {
+	if value == "" {
+		return nil, nil
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+func main() {
+	lambda.Start(handler)
+}
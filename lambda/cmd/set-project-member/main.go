@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/google/uuid"
+	"github.com/x-zero/business-consultant/pkg/access"
+	"github.com/x-zero/business-consultant/pkg/auth"
+	"github.com/x-zero/business-consultant/pkg/db"
+	"github.com/x-zero/business-consultant/pkg/response"
+)
+
+// MemberRequest adds a user to a project or changes their role
+type MemberRequest struct {
+	UserDID string `json:"user_did"`
+	Role    string `json:"role"` // owner, editor or viewer
+}
+
+// handler adds a member to a project or changes the role of a member; only
+// project owners may do so and the last owner cannot be demoted:
+// POST /project/{project_id}/members
+func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Handle OPTIONS
+	if request.HTTPMethod == "OPTIONS" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: 200,
+			Headers: map[string]string{
+				"Access-Control-Allow-Origin":  "*",
+				"Access-Control-Allow-Headers": "Content-Type,Authorization",
+				"Access-Control-Allow-Methods": "POST,OPTIONS",
+			},
+		}, nil
+	}
+
+	// Validate JWT
+	authHeader := request.Headers["Authorization"]
+	if authHeader == "" {
+		authHeader = request.Headers["authorization"]
+	}
+	claims, err := auth.ValidateToken(authHeader)
+	if err != nil {
+		return response.Error(401, fmt.Sprintf("Invalid token: %v", err))
+	}
+
+	projectID := request.PathParameters["project_id"]
+	if _, err := uuid.Parse(projectID); err != nil {
+		return response.Error(400, "Invalid project_id")
+	}
+
+	var req MemberRequest
+	if err := json.Unmarshal([]byte(request.Body), &req); err != nil {
+		return response.Error(400, "Invalid request body")
+	}
+	req.UserDID = strings.TrimSpace(req.UserDID)
+	if req.UserDID == "" || len(req.UserDID) > 255 {
+		return response.Error(400, "user_did is required and must be at most 255 characters")
+	}
+	if !access.ValidRole(req.Role) {
+		return response.Error(400, "role must be owner, editor or viewer")
+	}
+
+	// Initialize database
+	if err := db.InitDB(); err != nil {
+		return response.Error(500, fmt.Sprintf("Database error: %v", err))
+	}
+
+	pool := db.GetPool()
+
+	role, err := access.Project(ctx, pool, projectID, claims.DID, authHeader)
+	if err != nil {
+		return response.Error(500, err.Error())
+	}
+	if role != access.RoleOwner {
+		return response.Error(403, "Only project owners can manage members")
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return response.Error(500, fmt.Sprintf("Database error: %v", err))
+	}
+	defer tx.Rollback(ctx)
+
+	member, err := access.SetMember(ctx, tx, projectID, req.UserDID, req.Role, claims.DID)
+	if errors.Is(err, access.ErrLastOwner) {
+		return response.Error(409, "A project must keep at least one owner")
+	}
+	if err != nil {
+		return response.Error(500, err.Error())
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return response.Error(500, fmt.Sprintf("Failed to save member: %v", err))
+	}
+
+	return response.Success(member)
+}
+
+func main() {
+	lambda.Start(handler)
+}
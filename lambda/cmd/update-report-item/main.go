@@ -3,12 +3,15 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/x-zero/business-consultant/pkg/access"
 	"github.com/x-zero/business-consultant/pkg/auth"
 	"github.com/x-zero/business-consultant/pkg/db"
+	"github.com/x-zero/business-consultant/pkg/report"
 	"github.com/x-zero/business-consultant/pkg/response"
 )
 
@@ -23,7 +26,7 @@ func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 			StatusCode: 200,
 			Headers: map[string]string{
 				"Access-Control-Allow-Origin":  "*",
-				"Access-Control-Allow-Headers": "Content-Type,Authorization",
+				"Access-Control-Allow-Headers": "Content-Type,Authorization,If-Match",
 				"Access-Control-Allow-Methods": "PATCH,OPTIONS",
 			},
 		}, nil
@@ -49,63 +52,99 @@ func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events
 		return response.Error(400, "Invalid request body")
 	}
 
+	// An empty status clears it, like null
+	if req.Status != nil && *req.Status == "" {
+		req.Status = nil
+	}
+	if !report.ValidStatus(req.Status) {
+		return response.Error(400, fmt.Sprintf("Invalid status, expected %s, %s or null", report.StatusDraftCreated, report.StatusPublished))
+	}
+	if !report.HasStatus(itemID) {
+		return response.Error(404, "Item not found")
+	}
+
+	// If-Match makes the update conditional on the version the client read
+	ifMatch := request.Headers["If-Match"]
+	if ifMatch == "" {
+		ifMatch = request.Headers["if-match"]
+	}
+	expected, err := report.ParseIfMatch(ifMatch)
+	if err != nil {
+		return response.Error(400, err.Error())
+	}
+
 	if err := db.InitDB(); err != nil {
 		return response.Error(500, fmt.Sprintf("Database error: %v", err))
 	}
 
 	pool := db.GetPool()
 
-	// Get current report
-	var recommendations []byte
-	var userDID string
-	err = pool.QueryRow(ctx, `
-		SELECT user_did, recommendations FROM business_reports WHERE report_id = $1
-	`, reportID).Scan(&userDID, &recommendations)
-
-	if err != nil {
-		return response.Error(404, "Report not found")
+	// Verify access
+	if _, err := access.Report(ctx, pool, reportID, claims.DID, authHeader, access.UpdateStatus); err != nil {
+		return access.ErrorResponse(err)
 	}
 
-	if userDID != claims.DID {
-		return response.Error(403, "Access denied")
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return response.Error(500, fmt.Sprintf("Database error: %v", err))
 	}
+	defer tx.Rollback(ctx)
 
-	// Parse recommendations
-	var recsMap map[string]interface{}
-	if err := json.Unmarshal(recommendations, &recsMap); err != nil {
-		return response.Error(500, "Failed to parse recommendations")
+	// Bumping the version first locks the report row, so concurrent updates
+	// of the same report are applied one after the other
+	version, err := report.BumpVersion(ctx, tx, reportID, expected)
+	if errors.Is(err, report.ErrReportNotFound) {
+		return response.Error(404, "Report not found")
 	}
-
-	// Update item status (simplified - just store in a status map)
-	// In production, you'd want a more structured approach
-	if recsMap["item_statuses"] == nil {
-		recsMap["item_statuses"] = make(map[string]interface{})
+	if errors.Is(err, report.ErrVersionConflict) {
+		return response.ErrorWithHeaders(409, "Report has been modified, reload and try again", map[string]string{
+			"ETag":                          report.ETag(version),
+			"Access-Control-Expose-Headers": "ETag",
+		})
 	}
-	
-	itemStatuses := recsMap["item_statuses"].(map[string]interface{})
-	itemStatuses[itemID] = map[string]interface{}{
-		"status":  req.Status,
-		"task_id": req.TaskID,
+	if err != nil {
+		return response.Error(500, err.Error())
 	}
 
-	// Save back
-	updatedRecs, err := json.Marshal(recsMap)
+	updated, err := report.UpdateItemStatus(ctx, tx, reportID, itemID, report.ItemStatus{
+		Status: req.Status,
+		TaskID: req.TaskID,
+	})
 	if err != nil {
-		return response.Error(500, "Failed to marshal recommendations")
+		return response.Error(500, err.Error())
+	}
+	if !updated {
+		// Reports saved with allow_invalid or before the item tables keep
+		// their items in the document
+		updated, err = report.UpdateDocumentStatus(ctx, tx, reportID, itemID, report.ItemStatus{
+			Status: req.Status,
+			TaskID: req.TaskID,
+		})
+		if err != nil {
+			return response.Error(500, err.Error())
+		}
+	}
+	if !updated {
+		return response.Error(404, "Item not found")
 	}
 
-	_, err = pool.Exec(ctx, `
-		UPDATE business_reports
-		SET recommendations = $1::jsonb, updated_at = NOW()
-		WHERE report_id = $2
-	`, string(updatedRecs), reportID)
+	if err := report.RecordRevision(ctx, tx, reportID, claims.DID, report.ActionItemStatus); err != nil {
+		return response.Error(500, err.Error())
+	}
 
-	if err != nil {
+	if err := tx.Commit(ctx); err != nil {
 		return response.Error(500, fmt.Sprintf("Failed to update report: %v", err))
 	}
 
-	return response.Success(map[string]interface{}{
+	return response.SuccessWithHeaders(map[string]interface{}{
 		"message": "Item updated successfully",
+		"item_id": itemID,
+		"status":  req.Status,
+		"task_id": req.TaskID,
+		"version": version,
+	}, map[string]string{
+		"ETag":                          report.ETag(version),
+		"Access-Control-Expose-Headers": "ETag",
 	})
 }
 
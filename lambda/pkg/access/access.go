@@ -0,0 +1,179 @@
+// Package access decides what a user may do with a report. The creator of a
+// report may do everything with it; other members of its project may do
+// what their role allows. Members of the X-Zero project own it.
+package access
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/x-zero/business-consultant/pkg/db"
+	"github.com/x-zero/business-consultant/pkg/response"
+)
+
+// Roles of project members, from least to most privileged
+const (
+	RoleViewer = "viewer"
+	RoleEditor = "editor"
+	RoleOwner  = "owner"
+)
+
+var roleRank = map[string]int{RoleViewer: 1, RoleEditor: 2, RoleOwner: 3}
+
+// ValidRole reports whether role is a project member role
+func ValidRole(role string) bool {
+	return roleRank[role] > 0
+}
+
+// Allows reports whether role is least or a more privileged role
+func Allows(role, least string) bool {
+	return role != "" && roleRank[role] >= roleRank[least]
+}
+
+// Action is something done to a report
+type Action int
+
+// Actions on reports
+const (
+	View         Action = iota // read the report, its revisions, scenarios, comments and exports
+	Comment                    // comment on the report
+	UpdateStatus               // set the publishing status of items
+	Edit                       // add, edit, remove and reorder items, save scenarios
+	Manage                     // manage share links, delete comments of others
+	Delete                     // move to the trash, restore and purge
+)
+
+// required is the least role allowed to perform each action
+var required = map[Action]string{
+	View:         RoleViewer,
+	Comment:      RoleViewer,
+	UpdateStatus: RoleEditor,
+	Edit:         RoleEditor,
+	Manage:       RoleOwner,
+	Delete:       RoleOwner,
+}
+
+// Errors of the access checks
+var (
+	ErrNotFound = errors.New("report not found")
+	ErrDenied   = errors.New("access denied")
+)
+
+// Grant is the access of a user to a report
+type Grant struct {
+	ReportID   string `json:"report_id"`
+	ProjectID  string `json:"project_id"`
+	CreatorDID string `json:"creator_did"`
+	Role       string `json:"role"` // the creator counts as an owner
+}
+
+// Can reports whether the grant allows action
+func (g *Grant) Can(action Action) bool {
+	return roleRank[g.Role] >= roleRank[required[action]]
+}
+
+// Report checks that userDID may perform action on a report that is not in
+// the trash. Reports the user may not even view are reported as denied, like
+// the owner checks did before projects had members. A user without a role in
+// the project of the report is asked for at X-Zero like in Project, so do not
+// call it with a transaction open.
+func Report(ctx context.Context, q db.Querier, reportID, userDID, authHeader string, action Action) (*Grant, error) {
+	return check(ctx, q, reportID, userDID, authHeader, action, false)
+}
+
+// TrashedReport is Report for a report in the trash
+func TrashedReport(ctx context.Context, q db.Querier, reportID, userDID, authHeader string, action Action) (*Grant, error) {
+	return check(ctx, q, reportID, userDID, authHeader, action, true)
+}
+
+func check(ctx context.Context, q db.Querier, reportID, userDID, authHeader string, action Action, trashed bool) (*Grant, error) {
+	if _, err := uuid.Parse(reportID); err != nil {
+		return nil, ErrNotFound
+	}
+
+	g := &Grant{ReportID: reportID}
+	var role *string
+	err := q.QueryRow(ctx, `
+		SELECT r.project_id, r.user_did, m.role
+		FROM business_reports r
+		LEFT JOIN project_members m ON m.project_id = r.project_id AND m.user_did = $2
+		WHERE r.report_id = $1 AND (r.deleted_at IS NOT NULL) = $3
+	`, reportID, userDID, trashed).Scan(&g.ProjectID, &g.CreatorDID, &role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report: %v", err)
+	}
+
+	switch {
+	case g.CreatorDID == userDID:
+		g.Role = RoleOwner
+	case role != nil:
+		g.Role = *role
+	default:
+		if g.Role, err = claim(ctx, q, g.ProjectID, userDID, authHeader); err != nil {
+			return nil, err
+		}
+	}
+	if g.Role == "" || !g.Can(action) {
+		return nil, ErrDenied
+	}
+	return g, nil
+}
+
+// ProjectRole returns the role of userDID in a project, or "" if the user is
+// not a member
+func ProjectRole(ctx context.Context, q db.Querier, projectID, userDID string) (string, error) {
+	var role string
+	err := q.QueryRow(ctx, `
+		SELECT role FROM project_members
+		WHERE project_id = $1 AND user_did = $2
+	`, projectID, userDID).Scan(&role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read project role: %v", err)
+	}
+	return role, nil
+}
+
+// Visible returns an SQL condition on business_reports matching the reports
+// the user in placeholder arg may view: their own and those of their
+// projects. prefix qualifies the columns, e.g. "r.".
+func Visible(prefix, arg string) string {
+	return Where(prefix, arg, View)
+}
+
+// Where returns an SQL condition on business_reports matching the reports
+// the user in placeholder arg may perform action on: their own and those of
+// projects where their role allows it
+func Where(prefix, arg string, action Action) string {
+	var roles []string
+	for role, rank := range roleRank {
+		if rank >= roleRank[required[action]] {
+			roles = append(roles, "'"+role+"'")
+		}
+	}
+	sort.Strings(roles)
+	return fmt.Sprintf("(%[1]suser_did = %[2]s OR %[1]sproject_id IN (SELECT project_id FROM project_members WHERE user_did = %[2]s AND role IN (%[3]s)))",
+		prefix, arg, strings.Join(roles, ", "))
+}
+
+// ErrorResponse returns the API response of an error of Report
+func ErrorResponse(err error) (events.APIGatewayProxyResponse, error) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return response.Error(404, "Report not found")
+	case errors.Is(err, ErrDenied):
+		return response.Error(403, "Access denied")
+	}
+	return response.Error(500, err.Error())
+}
@@ -0,0 +1,178 @@
+package access
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/x-zero/business-consultant/pkg/db"
+	"github.com/x-zero/business-consultant/pkg/xzero"
+)
+
+// Errors of the member functions
+var (
+	ErrMemberNotFound = errors.New("member not found")
+	ErrLastOwner      = errors.New("a project must keep at least one owner")
+)
+
+// Member is a member of a project
+type Member struct {
+	ProjectID string    `json:"project_id"`
+	UserDID   string    `json:"user_did"`
+	Role      string    `json:"role"`
+	AddedBy   string    `json:"added_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Members returns the members of a project, owners first
+func Members(ctx context.Context, q db.Querier, projectID string) ([]Member, error) {
+	rows, err := q.Query(ctx, `
+		SELECT project_id, user_did, role, added_by, created_at, updated_at
+		FROM project_members
+		WHERE project_id = $1
+		ORDER BY CASE role WHEN 'owner' THEN 0 WHEN 'editor' THEN 1 ELSE 2 END, created_at, user_did
+	`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query members: %v", err)
+	}
+	defer rows.Close()
+
+	members := []Member{}
+	for rows.Next() {
+		var m Member
+		if err := rows.Scan(&m.ProjectID, &m.UserDID, &m.Role, &m.AddedBy, &m.CreatedAt, &m.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan member: %v", err)
+		}
+		members = append(members, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query members: %v", err)
+	}
+	return members, nil
+}
+
+// lockMembers locks the member rows of a project until the transaction
+// ends, so concurrent changes cannot remove the last owner
+func lockMembers(ctx context.Context, q db.Querier, projectID string) error {
+	rows, err := q.Query(ctx, `SELECT 1 FROM project_members WHERE project_id = $1 FOR UPDATE`, projectID)
+	if err != nil {
+		return fmt.Errorf("failed to lock members: %v", err)
+	}
+	rows.Close()
+	return rows.Err()
+}
+
+// otherOwners counts the owners of a project besides userDID
+func otherOwners(ctx context.Context, q db.Querier, projectID, userDID string) (int, error) {
+	var n int
+	err := q.QueryRow(ctx, `
+		SELECT COUNT(*) FROM project_members
+		WHERE project_id = $1 AND role = $2 AND user_did <> $3
+	`, projectID, RoleOwner, userDID).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count owners: %v", err)
+	}
+	return n, nil
+}
+
+// SetMember adds a member to a project or changes their role. Must run in
+// a transaction.
+func SetMember(ctx context.Context, q db.Querier, projectID, userDID, role, addedBy string) (*Member, error) {
+	if err := lockMembers(ctx, q, projectID); err != nil {
+		return nil, err
+	}
+	if role != RoleOwner {
+		current, err := ProjectRole(ctx, q, projectID, userDID)
+		if err != nil {
+			return nil, err
+		}
+		if current == RoleOwner {
+			n, err := otherOwners(ctx, q, projectID, userDID)
+			if err != nil {
+				return nil, err
+			}
+			if n == 0 {
+				return nil, ErrLastOwner
+			}
+		}
+	}
+
+	m := &Member{ProjectID: projectID, UserDID: userDID, Role: role}
+	err := q.QueryRow(ctx, `
+		INSERT INTO project_members (project_id, user_did, role, added_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (project_id, user_did) DO UPDATE SET role = EXCLUDED.role, updated_at = NOW()
+		RETURNING added_by, created_at, updated_at
+	`, projectID, userDID, role, addedBy).Scan(&m.AddedBy, &m.CreatedAt, &m.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save member: %v", err)
+	}
+	return m, nil
+}
+
+// RemoveMember removes a member from a project. Must run in a transaction.
+func RemoveMember(ctx context.Context, q db.Querier, projectID, userDID string) error {
+	if err := lockMembers(ctx, q, projectID); err != nil {
+		return err
+	}
+	current, err := ProjectRole(ctx, q, projectID, userDID)
+	if err != nil {
+		return err
+	}
+	if current == "" {
+		return ErrMemberNotFound
+	}
+	if current == RoleOwner {
+		n, err := otherOwners(ctx, q, projectID, userDID)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrLastOwner
+		}
+	}
+
+	_, err = q.Exec(ctx, `DELETE FROM project_members WHERE project_id = $1 AND user_did = $2`, projectID, userDID)
+	if err != nil {
+		return fmt.Errorf("failed to remove member: %v", err)
+	}
+	return nil
+}
+
+// Project returns the role of userDID in a project, or "" if the user has
+// none. A stored role decides; a user without one who is a member of the
+// X-Zero project, as its API lists them for the user's Authorization header,
+// is recorded as an owner. The API may be called, so do not call Project
+// with a transaction open.
+func Project(ctx context.Context, q db.Querier, projectID, userDID, authHeader string) (string, error) {
+	role, err := ProjectRole(ctx, q, projectID, userDID)
+	if err != nil || role != "" {
+		return role, err
+	}
+	return claim(ctx, q, projectID, userDID, authHeader)
+}
+
+// claim records userDID as an owner of a project they have no role in when
+// X-Zero lists them as a member, and returns their stored role. Roles given
+// by owners are never overwritten, so demoted X-Zero members stay demoted.
+func claim(ctx context.Context, q db.Querier, projectID, userDID, authHeader string) (string, error) {
+	member, err := xzero.Member(ctx, authHeader, projectID)
+	if err != nil {
+		return "", err
+	}
+	if !member {
+		return "", nil
+	}
+	_, err = q.Exec(ctx, `
+		INSERT INTO project_members (project_id, user_did, role, added_by)
+		VALUES ($1, $2, $3, $2)
+		ON CONFLICT (project_id, user_did) DO NOTHING
+	`, projectID, userDID, RoleOwner)
+	if err != nil {
+		return "", fmt.Errorf("failed to save owner: %v", err)
+	}
+	// A role given concurrently wins over the claim
+	return ProjectRole(ctx, q, projectID, userDID)
+}
@@ -0,0 +1,157 @@
+// Package budget derives totals from the budgets the model writes into a
+// report and flags figures that do not add up.
+package budget
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/x-zero/business-consultant/pkg/report"
+)
+
+// Currency of every amount in a report, see the consultant system prompt
+const Currency = "XZT"
+
+// Issue codes
+const (
+	IssueBreakdownMismatch = "breakdown_mismatch" // budget_breakdown does not sum to monthly_budget
+	IssueBreakdownMissing  = "breakdown_missing"  // phase has no budget_breakdown
+	IssueDurationUnknown   = "duration_unknown"   // duration could not be parsed
+	IssuePhaseUnderfunded  = "phase_underfunded"  // phase cannot pay its high priority roles and workflows
+	IssuePlanUnderfunded   = "plan_underfunded"   // no phase can pay every role and workflow
+)
+
+// Issue is an inconsistency between the budget figures of a report
+type Issue struct {
+	Code     string  `json:"code"`
+	ItemID   string  `json:"item_id,omitempty"`
+	Message  string  `json:"message"`
+	Expected float64 `json:"expected,omitempty"`
+	Actual   float64 `json:"actual,omitempty"`
+}
+
+// PhaseBudget holds the derived figures of one phase. Months and Total are
+// nil when the duration could not be parsed.
+type PhaseBudget struct {
+	ID             string   `json:"id,omitempty"`
+	PhaseName      string   `json:"phase_name"`
+	Duration       string   `json:"duration"`
+	Months         *float64 `json:"months"`
+	MonthlyBudget  float64  `json:"monthly_budget"`
+	BreakdownTotal float64  `json:"breakdown_total"`
+	Total          *float64 `json:"total"`
+}
+
+// Summary holds the derived figures of a plan. Totals only cover phases
+// with a parsed duration; Complete is false when some were left out.
+type Summary struct {
+	Currency           string        `json:"currency"`
+	Phases             []PhaseBudget `json:"phases"`
+	MonthlyRoles       float64       `json:"monthly_roles"`
+	MonthlyWorkflows   float64       `json:"monthly_workflows"`
+	MonthlyRequired    float64       `json:"monthly_required"`
+	PeakMonthlyBudget  float64       `json:"peak_monthly_budget"`
+	AverageMonthlyBurn float64       `json:"average_monthly_burn"`
+	TotalMonths        float64       `json:"total_months"`
+	TotalBudget        float64       `json:"total_budget"`
+	Complete           bool          `json:"complete"`
+	Issues             []Issue       `json:"issues"`
+}
+
+// Compute derives the budget summary of a plan. Workflow costs and role
+// budgets are monthly, like phase budgets.
+func Compute(recs *report.Recommendations) *Summary {
+	s := &Summary{Currency: Currency, Phases: []PhaseBudget{}, Issues: []Issue{}, Complete: true}
+
+	var urgent float64 // monthly cost of high priority roles and workflows
+	for _, wf := range recs.AIWorkflows {
+		s.MonthlyWorkflows += wf.EstimatedCost
+		if wf.Priority == report.PriorityHigh {
+			urgent += wf.EstimatedCost
+		}
+	}
+	for _, role := range recs.HumanRoles {
+		s.MonthlyRoles += role.MonthlyBudget
+		if role.Priority == report.PriorityHigh {
+			urgent += role.MonthlyBudget
+		}
+	}
+	s.MonthlyRequired = s.MonthlyRoles + s.MonthlyWorkflows
+
+	for _, phase := range recs.Phases {
+		pb := PhaseBudget{
+			ID:            phase.ID,
+			PhaseName:     phase.PhaseName,
+			Duration:      phase.Duration,
+			MonthlyBudget: phase.MonthlyBudget,
+		}
+		for _, amount := range phase.BudgetBreakdown {
+			pb.BreakdownTotal += amount
+		}
+		pb.BreakdownTotal = round(pb.BreakdownTotal)
+
+		if len(phase.BudgetBreakdown) == 0 {
+			s.issue(Issue{Code: IssueBreakdownMissing, ItemID: phase.ID,
+				Message: fmt.Sprintf("%s has no budget breakdown", phase.PhaseName)})
+		} else if !equal(pb.BreakdownTotal, phase.MonthlyBudget) {
+			s.issue(Issue{Code: IssueBreakdownMismatch, ItemID: phase.ID,
+				Message:  fmt.Sprintf("budget breakdown of %s sums to %s, not its monthly budget", phase.PhaseName, format(pb.BreakdownTotal)),
+				Expected: phase.MonthlyBudget, Actual: pb.BreakdownTotal})
+		}
+
+		if phase.MonthlyBudget < urgent && !equal(phase.MonthlyBudget, urgent) {
+			s.issue(Issue{Code: IssuePhaseUnderfunded, ItemID: phase.ID,
+				Message:  fmt.Sprintf("monthly budget of %s does not cover the high priority roles and workflows", phase.PhaseName),
+				Expected: round(urgent), Actual: phase.MonthlyBudget})
+		}
+
+		if months, ok := ParseDuration(phase.Duration); ok {
+			months = round(months)
+			total := round(months * phase.MonthlyBudget)
+			pb.Months, pb.Total = &months, &total
+			s.TotalMonths += months
+			s.TotalBudget += total
+		} else {
+			s.Complete = false
+			s.issue(Issue{Code: IssueDurationUnknown, ItemID: phase.ID,
+				Message: fmt.Sprintf("duration %q of %s is not a number of days, weeks, months or years", phase.Duration, phase.PhaseName)})
+		}
+
+		s.PeakMonthlyBudget = math.Max(s.PeakMonthlyBudget, phase.MonthlyBudget)
+		s.Phases = append(s.Phases, pb)
+	}
+
+	if len(recs.Phases) > 0 && s.PeakMonthlyBudget < s.MonthlyRequired && !equal(s.PeakMonthlyBudget, s.MonthlyRequired) {
+		s.issue(Issue{Code: IssuePlanUnderfunded,
+			Message:  "no phase budget covers every recommended role and workflow",
+			Expected: round(s.MonthlyRequired), Actual: s.PeakMonthlyBudget})
+	}
+
+	s.MonthlyRoles = round(s.MonthlyRoles)
+	s.MonthlyWorkflows = round(s.MonthlyWorkflows)
+	s.MonthlyRequired = round(s.MonthlyRequired)
+	s.TotalMonths = round(s.TotalMonths)
+	s.TotalBudget = round(s.TotalBudget)
+	if s.TotalMonths > 0 {
+		s.AverageMonthlyBurn = round(s.TotalBudget / s.TotalMonths)
+	}
+	return s
+}
+
+func (s *Summary) issue(i Issue) {
+	s.Issues = append(s.Issues, i)
+}
+
+// equal compares amounts with a tolerance of 1% or 1 XZT, whichever is
+// larger, so rounding in the model output is not flagged
+func equal(a, b float64) bool {
+	return math.Abs(a-b) <= math.Max(1, math.Abs(b)*0.01)
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+func format(v float64) string {
+	return fmt.Sprintf("%g", v)
+}
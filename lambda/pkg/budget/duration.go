@@ -0,0 +1,97 @@
+package budget
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	durationNumber = regexp.MustCompile(`\d+(?:\.\d+)?`)
+	durationRange  = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*(?:-|~|～|—|到|至)\s*(\d+(?:\.\d+)?)`)
+	chineseNumber  = regexp.MustCompile(`[一二两三四五六七八九十]+`)
+)
+
+var chineseDigits = map[rune]int{
+	'一': 1, '二': 2, '两': 2, '三': 3, '四': 4, '五': 5, '六': 6, '七': 7, '八': 8, '九': 9,
+}
+
+// ParseDuration converts a phase duration such as "3个月", "1-2年",
+// "六周", "半年" or "90 days" into months. Ranges resolve to their upper
+// bound, like budget amounts. Ordinals count the units they name: "第3个月"
+// is one month and "第4-6个月" three. It reports false for durations
+// without a number, e.g. "长期".
+func ParseDuration(s string) (float64, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = chineseNumber.ReplaceAllStringFunc(s, func(m string) string {
+		return strconv.Itoa(parseChinese(m))
+	})
+
+	var value float64
+	if m := durationRange.FindStringSubmatch(s); m != nil {
+		value, _ = strconv.ParseFloat(m[2], 64)
+		// "第4-6个月" names months 4 to 6, i.e. three of them
+		if strings.Contains(s, "第") {
+			from, _ := strconv.ParseFloat(m[1], 64)
+			value -= from - 1
+		}
+	} else if m := durationNumber.FindString(s); m != "" {
+		value, _ = strconv.ParseFloat(m, 64)
+		// "第3个月" names a single month
+		if strings.Contains(s, "第") {
+			value = 1
+		}
+	}
+	// "半年" is half a unit, "1年半" one and a half
+	if strings.Contains(s, "半") {
+		value += 0.5
+	}
+	if value <= 0 {
+		return 0, false
+	}
+
+	switch {
+	case containsAny(s, "年", "year", "yr"):
+		value *= 12
+	case containsAny(s, "周", "星期", "week", "wk"):
+		value *= 12.0 / 52
+	case containsAny(s, "天", "日", "day"):
+		value *= 12.0 / 365
+	case containsAny(s, "季", "quarter"):
+		value *= 3
+	}
+	return value, true
+}
+
+// parseChinese converts Chinese numerals below 100, e.g. "十二" or "二十"
+func parseChinese(s string) int {
+	runes := []rune(s)
+	i := strings.IndexRune(s, '十')
+	if i < 0 {
+		n := 0
+		for _, r := range runes {
+			n = n*10 + chineseDigits[r]
+		}
+		return n
+	}
+
+	tens, ones := 1, 0
+	before := []rune(s[:i])
+	after := []rune(s[i+len("十"):])
+	if len(before) > 0 {
+		tens = chineseDigits[before[len(before)-1]]
+	}
+	if len(after) > 0 {
+		ones = chineseDigits[after[0]]
+	}
+	return tens*10 + ones
+}
+
+func containsAny(s string, substrs ...string) bool {
+	for _, sub := range substrs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
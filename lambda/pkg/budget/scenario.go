@@ -0,0 +1,207 @@
+package budget
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/x-zero/business-consultant/pkg/report"
+)
+
+// Constraints change a plan without touching the saved report. Items are
+// referred to by their report item IDs.
+type Constraints struct {
+	MonthlyBudgetCap *float64           `json:"monthly_budget_cap,omitempty"` // caps every phase budget
+	DropRoles        []string           `json:"drop_roles,omitempty"`         // role IDs
+	WorkflowCosts    map[string]float64 `json:"workflow_costs,omitempty"`     // workflow ID -> monthly cost
+	PhaseDurations   map[string]string  `json:"phase_durations,omitempty"`    // phase ID -> duration, e.g. "6个月"
+}
+
+// Delta compares a figure of the original plan with the scenario
+type Delta struct {
+	Field  string  `json:"field"`
+	Before float64 `json:"before"`
+	After  float64 `json:"after"`
+	Change float64 `json:"change"`
+}
+
+// Scenario is a plan recomputed under constraints. Unfunded lists the
+// workflows and roles that do not fit under the budget cap when items are
+// funded by priority, workflows first.
+type Scenario struct {
+	Constraints Constraints             `json:"constraints"`
+	Plan        *report.Recommendations `json:"plan"`
+	Budget      *Summary                `json:"budget"`
+	Baseline    *Summary                `json:"baseline"`
+	Deltas      []Delta                 `json:"deltas"`
+	Unfunded    []string                `json:"unfunded"`
+}
+
+// Run applies constraints to a copy of recs and compares the result with
+// the original plan. Constraints naming unknown items or unparsable
+// durations are returned as validation errors.
+func Run(recs *report.Recommendations, c Constraints) (*Scenario, report.ValidationErrors) {
+	plan, errs := apply(recs, c)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	s := &Scenario{
+		Constraints: c,
+		Plan:        plan,
+		Budget:      Compute(plan),
+		Baseline:    Compute(recs),
+		Unfunded:    []string{},
+	}
+	s.Deltas = deltas(s.Baseline, s.Budget)
+	if c.MonthlyBudgetCap != nil {
+		s.Unfunded = unfunded(plan, *c.MonthlyBudgetCap)
+	}
+	return s, nil
+}
+
+// apply returns a copy of recs with the constraints applied
+func apply(recs *report.Recommendations, c Constraints) (*report.Recommendations, report.ValidationErrors) {
+	var errs report.ValidationErrors
+	invalid := func(path, format string, args ...interface{}) {
+		errs = append(errs, report.ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	// Deep copy so the caller's plan is never modified
+	raw, _ := json.Marshal(recs)
+	var plan report.Recommendations
+	json.Unmarshal(raw, &plan)
+
+	if c.MonthlyBudgetCap != nil && *c.MonthlyBudgetCap < 0 {
+		invalid("monthly_budget_cap", "must not be negative")
+	}
+
+	drop := map[string]bool{}
+	for _, id := range c.DropRoles {
+		drop[id] = true
+	}
+	roles := []report.HumanRole{}
+	for _, role := range plan.HumanRoles {
+		if drop[role.ID] {
+			delete(drop, role.ID)
+			continue
+		}
+		roles = append(roles, role)
+	}
+	for _, id := range sortedKeys(drop) {
+		invalid("drop_roles", "unknown role %s", id)
+	}
+	plan.HumanRoles = roles
+
+	costs := map[string]float64{}
+	for _, id := range sortedKeys(c.WorkflowCosts) {
+		if c.WorkflowCosts[id] < 0 {
+			invalid("workflow_costs."+id, "must not be negative")
+		}
+		costs[id] = c.WorkflowCosts[id]
+	}
+	for i := range plan.AIWorkflows {
+		if cost, ok := costs[plan.AIWorkflows[i].ID]; ok {
+			plan.AIWorkflows[i].EstimatedCost = cost
+			delete(costs, plan.AIWorkflows[i].ID)
+		}
+	}
+	for _, id := range sortedKeys(costs) {
+		invalid("workflow_costs."+id, "unknown workflow")
+	}
+
+	durations := map[string]string{}
+	for id, d := range c.PhaseDurations {
+		durations[id] = d
+	}
+	for i := range plan.Phases {
+		phase := &plan.Phases[i]
+		if d, ok := durations[phase.ID]; ok {
+			if _, ok := ParseDuration(d); !ok {
+				invalid("phase_durations."+phase.ID, "%q is not a number of days, weeks, months or years", d)
+			}
+			phase.Duration = d
+			delete(durations, phase.ID)
+		}
+
+		// Capped phases keep the proportions of their breakdown
+		if c.MonthlyBudgetCap != nil && phase.MonthlyBudget > *c.MonthlyBudgetCap {
+			ratio := *c.MonthlyBudgetCap / phase.MonthlyBudget
+			for category, amount := range phase.BudgetBreakdown {
+				phase.BudgetBreakdown[category] = round(amount * ratio)
+			}
+			phase.MonthlyBudget = *c.MonthlyBudgetCap
+		}
+	}
+	for _, id := range sortedKeys(durations) {
+		invalid("phase_durations."+id, "unknown phase")
+	}
+
+	return &plan, errs
+}
+
+// unfunded returns the IDs of the items that do not fit under cap when
+// funded in priority order. Workflows come before roles of the same
+// priority since the system prompt prefers automation.
+func unfunded(plan *report.Recommendations, cap float64) []string {
+	type item struct {
+		id       string
+		priority int
+		cost     float64
+	}
+	rank := map[string]int{report.PriorityHigh: 0, report.PriorityMedium: 1, report.PriorityLow: 2}
+
+	var items []item
+	for _, wf := range plan.AIWorkflows {
+		items = append(items, item{wf.ID, rank[wf.Priority], wf.EstimatedCost})
+	}
+	for _, role := range plan.HumanRoles {
+		items = append(items, item{role.ID, rank[role.Priority], role.MonthlyBudget})
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].priority < items[j].priority })
+
+	ids := []string{}
+	remaining := cap
+	for _, it := range items {
+		if it.cost <= remaining {
+			remaining -= it.cost
+			continue
+		}
+		ids = append(ids, it.id)
+	}
+	return ids
+}
+
+// deltas compares the plan-level figures of two summaries
+func deltas(before, after *Summary) []Delta {
+	fields := []struct {
+		name   string
+		before float64
+		after  float64
+	}{
+		{"monthly_roles", before.MonthlyRoles, after.MonthlyRoles},
+		{"monthly_workflows", before.MonthlyWorkflows, after.MonthlyWorkflows},
+		{"monthly_required", before.MonthlyRequired, after.MonthlyRequired},
+		{"peak_monthly_budget", before.PeakMonthlyBudget, after.PeakMonthlyBudget},
+		{"average_monthly_burn", before.AverageMonthlyBurn, after.AverageMonthlyBurn},
+		{"total_months", before.TotalMonths, after.TotalMonths},
+		{"total_budget", before.TotalBudget, after.TotalBudget},
+	}
+
+	ds := []Delta{}
+	for _, f := range fields {
+		if f.before != f.after {
+			ds = append(ds, Delta{Field: f.name, Before: f.before, After: f.after, Change: round(f.after - f.before)})
+		}
+	}
+	return ds
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
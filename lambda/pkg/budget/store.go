@@ -0,0 +1,73 @@
+package budget
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/x-zero/business-consultant/pkg/db"
+)
+
+// SavedScenario is a named scenario stored with its report
+type SavedScenario struct {
+	ScenarioID    string          `json:"scenario_id"`
+	ReportID      string          `json:"report_id"`
+	Name          string          `json:"name"`
+	ReportVersion int             `json:"report_version"`
+	Result        json.RawMessage `json:"result"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+// SaveScenario stores a scenario under a name, replacing an earlier scenario
+// of the report with the same name. version is the report version the
+// scenario was computed from.
+func SaveScenario(ctx context.Context, q db.Querier, reportID, userDID, name string, version int, s *Scenario) (string, error) {
+	constraints, _ := json.Marshal(s.Constraints)
+	result, err := json.Marshal(s)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode scenario: %v", err)
+	}
+
+	var id string
+	err = q.QueryRow(ctx, `
+		INSERT INTO report_scenarios (report_id, user_did, name, report_version, constraints, result)
+		VALUES ($1, $2, $3, $4, $5::jsonb, $6::jsonb)
+		ON CONFLICT (report_id, name) DO UPDATE
+		SET user_did = EXCLUDED.user_did, report_version = EXCLUDED.report_version,
+		    constraints = EXCLUDED.constraints, result = EXCLUDED.result, updated_at = NOW()
+		RETURNING scenario_id
+	`, reportID, userDID, name, version, string(constraints), string(result)).Scan(&id)
+	if err != nil {
+		return "", fmt.Errorf("failed to save scenario: %v", err)
+	}
+	return id, nil
+}
+
+// ListScenarios returns the saved scenarios of a report, most recently
+// saved first
+func ListScenarios(ctx context.Context, q db.Querier, reportID string) ([]SavedScenario, error) {
+	rows, err := q.Query(ctx, `
+		SELECT scenario_id, report_id, name, report_version, result, created_at, updated_at
+		FROM report_scenarios
+		WHERE report_id = $1
+		ORDER BY updated_at DESC
+	`, reportID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query scenarios: %v", err)
+	}
+	defer rows.Close()
+
+	scenarios := []SavedScenario{}
+	for rows.Next() {
+		var s SavedScenario
+		var result []byte
+		if err := rows.Scan(&s.ScenarioID, &s.ReportID, &s.Name, &s.ReportVersion, &result, &s.CreatedAt, &s.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan scenario: %v", err)
+		}
+		s.Result = result
+		scenarios = append(scenarios, s)
+	}
+	return scenarios, rows.Err()
+}
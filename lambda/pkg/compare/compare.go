@@ -0,0 +1,139 @@
+// Package compare lines up several reports side by side.
+package compare
+
+import (
+	"math"
+
+	"github.com/x-zero/business-consultant/pkg/budget"
+	"github.com/x-zero/business-consultant/pkg/report"
+)
+
+// Report is one of the compared reports
+type Report struct {
+	ReportID     string
+	ProjectID    string
+	BusinessGoal string
+	Recs         *report.Recommendations
+}
+
+// Entry is a workflow or role of one report
+type Entry struct {
+	ReportID string  `json:"report_id"`
+	ItemID   string  `json:"item_id"`
+	Name     string  `json:"name"`
+	Monthly  float64 `json:"monthly_cost"`
+	Priority string  `json:"priority"`
+}
+
+// Group holds items of different reports whose names match. Name is the
+// name of the first item.
+type Group struct {
+	Name    string  `json:"name"`
+	Entries []Entry `json:"entries"`
+}
+
+// Overlap splits the workflows or roles of the compared reports into groups
+// found in several reports and groups found in one
+type Overlap struct {
+	Shared []Group `json:"shared"`
+	Unique []Group `json:"unique"`
+}
+
+// Column holds the figures of one report
+type Column struct {
+	ReportID     string          `json:"report_id"`
+	ProjectID    string          `json:"project_id"`
+	BusinessGoal string          `json:"business_goal"`
+	Budget       *budget.Summary `json:"budget"`
+	MonthlyAI    float64         `json:"monthly_ai"`
+	MonthlyHuman float64         `json:"monthly_human"`
+	AIRatio      *float64        `json:"ai_ratio"` // AI share of the monthly AI and human cost
+}
+
+// Comparison is the side-by-side view of several reports
+type Comparison struct {
+	Reports   []Column `json:"reports"`
+	Workflows Overlap  `json:"ai_workflows"`
+	Roles     Overlap  `json:"human_roles"`
+}
+
+// Compare lines up reports in the given order
+func Compare(reports []Report) *Comparison {
+	c := &Comparison{Reports: []Column{}}
+	var workflows, roles [][]Entry
+
+	for _, r := range reports {
+		summary := budget.Compute(r.Recs)
+		col := Column{
+			ReportID:     r.ReportID,
+			ProjectID:    r.ProjectID,
+			BusinessGoal: r.BusinessGoal,
+			Budget:       summary,
+			MonthlyAI:    summary.MonthlyWorkflows,
+			MonthlyHuman: summary.MonthlyRoles,
+		}
+		if total := col.MonthlyAI + col.MonthlyHuman; total > 0 {
+			ratio := math.Round(col.MonthlyAI/total*10000) / 10000
+			col.AIRatio = &ratio
+		}
+		c.Reports = append(c.Reports, col)
+
+		var wfs, rs []Entry
+		for _, wf := range r.Recs.AIWorkflows {
+			wfs = append(wfs, Entry{r.ReportID, wf.ID, wf.Name, wf.EstimatedCost, wf.Priority})
+		}
+		for _, role := range r.Recs.HumanRoles {
+			rs = append(rs, Entry{r.ReportID, role.ID, role.Title, role.MonthlyBudget, role.Priority})
+		}
+		workflows = append(workflows, wfs)
+		roles = append(roles, rs)
+	}
+
+	c.Workflows = overlap(workflows)
+	c.Roles = overlap(roles)
+	return c
+}
+
+// overlap groups the items of several reports by name. Each item joins the
+// most similar group that has no item of its report yet, so a group holds
+// at most one item per report.
+func overlap(lists [][]Entry) Overlap {
+	var groups []Group
+	for _, list := range lists {
+		for _, e := range list {
+			best, bestScore := -1, 0.0
+			for i, g := range groups {
+				if hasReport(g, e.ReportID) {
+					continue
+				}
+				if score := Similarity(g.Name, e.Name); score >= MatchThreshold && score > bestScore {
+					best, bestScore = i, score
+				}
+			}
+			if best < 0 {
+				groups = append(groups, Group{Name: e.Name, Entries: []Entry{e}})
+			} else {
+				groups[best].Entries = append(groups[best].Entries, e)
+			}
+		}
+	}
+
+	o := Overlap{Shared: []Group{}, Unique: []Group{}}
+	for _, g := range groups {
+		if len(g.Entries) > 1 {
+			o.Shared = append(o.Shared, g)
+		} else {
+			o.Unique = append(o.Unique, g)
+		}
+	}
+	return o
+}
+
+func hasReport(g Group, reportID string) bool {
+	for _, e := range g.Entries {
+		if e.ReportID == reportID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package compare
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// MatchThreshold is the similarity above which two names are taken to mean
+// the same workflow or role
+const MatchThreshold = 0.6
+
+// qualifiers such as "（兼职）" or "(part-time)" do not change what a role is
+var qualifier = regexp.MustCompile(`[（(][^）)]*[）)]`)
+
+// normalize strips qualifiers, punctuation and case from a name
+func normalize(name string) []rune {
+	name = qualifier.ReplaceAllString(strings.ToLower(name), "")
+	var runes []rune
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			runes = append(runes, r)
+		}
+	}
+	return runes
+}
+
+// Similarity returns the Dice coefficient of the character bigrams of two
+// names, from 0 (nothing shared) to 1 (same name). Bigrams work for Chinese
+// names, which have no word boundaries, as well as for English ones.
+func Similarity(a, b string) float64 {
+	ra, rb := normalize(a), normalize(b)
+	if string(ra) == string(rb) {
+		if len(ra) == 0 {
+			return 0
+		}
+		return 1
+	}
+	if len(ra) < 2 || len(rb) < 2 {
+		return 0
+	}
+
+	bigrams := map[string]int{}
+	for i := 0; i+1 < len(ra); i++ {
+		bigrams[string(ra[i:i+2])]++
+	}
+	shared := 0
+	for i := 0; i+1 < len(rb); i++ {
+		g := string(rb[i : i+2])
+		if bigrams[g] > 0 {
+			bigrams[g]--
+			shared++
+		}
+	}
+	return 2 * float64(shared) / float64(len(ra)-1+len(rb)-1)
+}
@@ -0,0 +1,223 @@
+package conversation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/x-zero/business-consultant/pkg/db"
+)
+
+// ErrNotFound is returned when a conversation does not exist or belongs to another user
+var ErrNotFound = errors.New("conversation not found")
+
+// maxTitleLength is the number of characters of the first user message used as title
+const maxTitleLength = 50
+
+// Conversation is a consulting session of one user within a project
+type Conversation struct {
+	ConversationID string    `json:"conversation_id"`
+	UserDID        string    `json:"user_did"`
+	ProjectID      string    `json:"project_id"`
+	Title          string    `json:"title"`
+	Stage          string    `json:"stage"`
+	ReportID       *string   `json:"report_id"`
+	Summary        *Summary  `json:"-"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	Messages       []Message `json:"messages,omitempty"`
+}
+
+// Message is one turn of a conversation. Assistant messages keep the raw
+// model output in Content and the parsed response in Payload.
+type Message struct {
+	MessageID int64           `json:"message_id"`
+	Role      string          `json:"role"`
+	Content   string          `json:"content"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+const selectColumns = `conversation_id, user_did, project_id, title, stage, report_id, summary, summary_message_count, created_at, updated_at`
+
+func scan(row pgx.Row) (*Conversation, error) {
+	var c Conversation
+	var summary *string
+	var covered int
+	err := row.Scan(&c.ConversationID, &c.UserDID, &c.ProjectID, &c.Title, &c.Stage, &c.ReportID, &summary, &covered, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if summary != nil && covered > 0 {
+		c.Summary = &Summary{Text: *summary, Covered: covered}
+	}
+	return &c, nil
+}
+
+// Create starts a new conversation
+func Create(ctx context.Context, q db.Querier, userDID, projectID, title string) (*Conversation, error) {
+	c, err := scan(q.QueryRow(ctx, `
+		INSERT INTO conversations (user_did, project_id, title)
+		VALUES ($1, $2, $3)
+		RETURNING `+selectColumns,
+		userDID, projectID, title))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create conversation: %v", err)
+	}
+	return c, nil
+}
+
+// Get returns a conversation owned by userDID, without its messages
+func Get(ctx context.Context, q db.Querier, conversationID, userDID string) (*Conversation, error) {
+	c, err := scan(q.QueryRow(ctx, `
+		SELECT `+selectColumns+`
+		FROM conversations
+		WHERE conversation_id = $1 AND user_did = $2
+	`, conversationID, userDID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversation: %v", err)
+	}
+	return c, nil
+}
+
+// List returns the conversations of a user in a project, most recent first
+func List(ctx context.Context, q db.Querier, userDID, projectID string) ([]Conversation, error) {
+	rows, err := q.Query(ctx, `
+		SELECT `+selectColumns+`
+		FROM conversations
+		WHERE user_did = $1 AND project_id = $2
+		ORDER BY updated_at DESC
+	`, userDID, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query conversations: %v", err)
+	}
+	defer rows.Close()
+
+	conversations := []Conversation{}
+	for rows.Next() {
+		c, err := scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan conversation: %v", err)
+		}
+		conversations = append(conversations, *c)
+	}
+	return conversations, rows.Err()
+}
+
+// Delete removes a conversation and its messages
+func Delete(ctx context.Context, q db.Querier, conversationID, userDID string) error {
+	result, err := q.Exec(ctx, `
+		DELETE FROM conversations
+		WHERE conversation_id = $1 AND user_did = $2
+	`, conversationID, userDID)
+	if err != nil {
+		return fmt.Errorf("failed to delete conversation: %v", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Messages returns the messages of a conversation in order
+func Messages(ctx context.Context, q db.Querier, conversationID string) ([]Message, error) {
+	rows, err := q.Query(ctx, `
+		SELECT message_id, role, content, payload, created_at
+		FROM messages
+		WHERE conversation_id = $1
+		ORDER BY message_id
+	`, conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query messages: %v", err)
+	}
+	defer rows.Close()
+
+	messages := []Message{}
+	for rows.Next() {
+		var m Message
+		var payload []byte
+		if err := rows.Scan(&m.MessageID, &m.Role, &m.Content, &payload, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan message: %v", err)
+		}
+		if payload != nil {
+			m.Payload = payload
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
+
+// Append stores new turns and updates the conversation stage. The title is
+// taken from the first user message when the conversation has none yet. Run
+// it in a transaction, so a turn is stored completely or not at all.
+func Append(ctx context.Context, q db.Querier, conversationID, stage string, messages ...Message) error {
+	title := ""
+	for _, m := range messages {
+		if err := insertMessage(ctx, q, conversationID, m); err != nil {
+			return err
+		}
+		if m.Role == "user" && title == "" {
+			title = truncate(strings.TrimSpace(m.Content), maxTitleLength)
+		}
+	}
+
+	_, err := q.Exec(ctx, `
+		UPDATE conversations
+		SET stage = COALESCE(NULLIF($2, ''), stage),
+		    title = CASE WHEN title = '' THEN $3 ELSE title END,
+		    updated_at = NOW()
+		WHERE conversation_id = $1
+	`, conversationID, stage, title)
+	if err != nil {
+		return fmt.Errorf("failed to update conversation: %v", err)
+	}
+	return nil
+}
+
+func insertMessage(ctx context.Context, q db.Querier, conversationID string, m Message) error {
+	var payload *string
+	if len(m.Payload) > 0 {
+		p := string(m.Payload)
+		payload = &p
+	}
+	_, err := q.Exec(ctx, `
+		INSERT INTO messages (conversation_id, role, content, payload)
+		VALUES ($1, $2, $3, $4::jsonb)
+	`, conversationID, m.Role, m.Content, payload)
+	if err != nil {
+		return fmt.Errorf("failed to store message: %v", err)
+	}
+	return nil
+}
+
+// LinkReport records the report generated from a conversation
+func LinkReport(ctx context.Context, q db.Querier, conversationID, userDID, reportID string) error {
+	result, err := q.Exec(ctx, `
+		UPDATE conversations
+		SET report_id = $3, updated_at = NOW()
+		WHERE conversation_id = $1 AND user_did = $2
+	`, conversationID, userDID, reportID)
+	if err != nil {
+		return fmt.Errorf("failed to link report: %v", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// truncate shortens s to at most n characters
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "…"
+}
@@ -0,0 +1,194 @@
+package conversation
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/x-zero/business-consultant/pkg/db"
+	"github.com/x-zero/business-consultant/pkg/deepseek"
+)
+
+// Defaults of the context window policy, overridable with CHAT_CONTEXT_TOKENS
+// and CHAT_KEEP_RECENT_MESSAGES
+const (
+	defaultContextTokens = 12000
+	defaultKeepRecent    = 6
+
+	// messageOverhead approximates the per-message tokens of the chat format
+	messageOverhead = 4
+	// summaryReserve is kept free in the window for the summary message
+	summaryReserve = 800
+)
+
+// WindowPolicy decides how much history is sent to the model verbatim
+type WindowPolicy struct {
+	// MaxTokens is the token budget for the history, excluding the system prompt
+	MaxTokens int
+	// KeepRecent is the number of most recent messages that are always kept
+	KeepRecent int
+}
+
+// PolicyFromEnv returns the window policy configured for the Lambda
+func PolicyFromEnv() WindowPolicy {
+	return WindowPolicy{
+		MaxTokens:  envInt("CHAT_CONTEXT_TOKENS", defaultContextTokens),
+		KeepRecent: envInt("CHAT_KEEP_RECENT_MESSAGES", defaultKeepRecent),
+	}
+}
+
+// Summary is a cached summary of the first Covered messages of a conversation
+type Summary struct {
+	Text    string
+	Covered int
+}
+
+// Summarizer condenses messages into a summary, extending previous if set
+type Summarizer func(previous string, messages []deepseek.Message) (string, error)
+
+// EstimateTokens approximates the token count of text without a tokenizer:
+// CJK characters are roughly one token each, other text about four
+// characters per token.
+func EstimateTokens(text string) int {
+	cjk, other := 0, 0
+	for _, r := range text {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) || isCJKPunct(r) {
+			cjk++
+		} else {
+			other++
+		}
+	}
+	return cjk + (other+3)/4
+}
+
+// isCJKPunct reports CJK symbols and full-width forms such as ，。：
+func isCJKPunct(r rune) bool {
+	return (r >= 0x3000 && r <= 0x303F) || (r >= 0xFF00 && r <= 0xFFEF)
+}
+
+// EstimateMessages approximates the token count of a message list
+func EstimateMessages(messages []deepseek.Message) int {
+	total := 0
+	for _, m := range messages {
+		total += EstimateTokens(m.Content) + messageOverhead
+	}
+	return total
+}
+
+// Fit returns the messages to send to the model: the consultant system
+// prompt, a summary of older turns when the history exceeds the budget, and
+// the most recent turns verbatim. cached is reused when it still covers the
+// older turns and extended otherwise; the summary that was used is returned
+// so the caller can cache it (nil when the history fits as is).
+func (p WindowPolicy) Fit(history []deepseek.Message, cached *Summary, summarize Summarizer) ([]deepseek.Message, *Summary, error) {
+	if len(history) > 0 && history[0].Role == "system" {
+		// The caller manages its own system prompt; leave it alone
+		return history, nil, nil
+	}
+	if EstimateMessages(history) <= p.MaxTokens {
+		return history, nil, nil
+	}
+
+	// Keep the longest suffix that fits next to the summary, but at least
+	// KeepRecent messages
+	budget := p.MaxTokens - summaryReserve
+	split := len(history)
+	used := 0
+	for split > 0 {
+		cost := EstimateTokens(history[split-1].Content) + messageOverhead
+		if used+cost > budget && len(history)-split >= p.KeepRecent {
+			break
+		}
+		used += cost
+		split--
+	}
+	if split == 0 {
+		return history, nil, nil
+	}
+
+	summary := cached
+	switch {
+	case summary != nil && summary.Covered >= split && summary.Covered <= len(history):
+		// The cached summary already covers the older turns
+		split = summary.Covered
+	default:
+		previous, from := "", 0
+		if summary != nil && summary.Covered < split {
+			previous, from = summary.Text, summary.Covered
+		}
+		text, err := summarize(previous, history[from:split])
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to summarise conversation: %v", err)
+		}
+		summary = &Summary{Text: text, Covered: split}
+	}
+
+	messages := make([]deepseek.Message, 0, len(history)-split+2)
+	messages = append(messages,
+		deepseek.Message{Role: "system", Content: deepseek.SystemPrompt()},
+		deepseek.Message{Role: "system", Content: "以下是本次咨询早前对话的摘要，请结合摘要和后续对话继续：\n" + summary.Text},
+	)
+	messages = append(messages, history[split:]...)
+	return messages, summary, nil
+}
+
+// NewSummarizer returns a Summarizer backed by a plain-text model call bound to ctx
+func NewSummarizer(ctx context.Context, client deepseek.LLMProvider) Summarizer {
+	return func(previous string, messages []deepseek.Message) (string, error) {
+		var transcript strings.Builder
+		if previous != "" {
+			transcript.WriteString("已有摘要：\n")
+			transcript.WriteString(previous)
+			transcript.WriteString("\n\n新增对话：\n")
+		}
+		for _, m := range messages {
+			role := "用户"
+			if m.Role == "assistant" {
+				role = "顾问"
+			}
+			transcript.WriteString(role)
+			transcript.WriteString("：")
+			transcript.WriteString(m.Content)
+			transcript.WriteString("\n")
+		}
+
+		return client.ChatText(ctx, []deepseek.Message{
+			{
+				Role: "system",
+				Content: `你负责压缩商业咨询对话的上下文。请把对话整理成简洁的中文摘要，保留：
+1. 用户的商业目标
+2. 用户已回答的关键信息（预算、背景、技能、时间、目标市场等）
+3. 顾问已提出但尚未得到回答的问题
+4. 已给出的推荐方案要点（如有）
+只输出摘要正文，不超过500字。`,
+			},
+			{Role: "user", Content: transcript.String()},
+		})
+	}
+}
+
+// SaveSummary caches the summary of a stored conversation
+func SaveSummary(ctx context.Context, q db.Querier, conversationID string, summary *Summary) error {
+	_, err := q.Exec(ctx, `
+		UPDATE conversations
+		SET summary = $2, summary_message_count = $3
+		WHERE conversation_id = $1
+	`, conversationID, summary.Text, summary.Covered)
+	if err != nil {
+		return fmt.Errorf("failed to save summary: %v", err)
+	}
+	return nil
+}
+
+// envInt reads a positive integer env var, falling back to def
+func envInt(key string, def int) int {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return def
+}
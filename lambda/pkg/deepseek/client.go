@@ -1,19 +1,27 @@
 package deepseek
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"strings"
+	"time"
+
+	"github.com/x-zero/business-consultant/pkg/sse"
 )
 
 const (
 	DeepSeekAPIURL = "https://api.deepseek.com/v1/chat/completions"
 )
 
+// ResponseFormat selects the output format of a chat completion
+type ResponseFormat struct {
+	Type string `json:"type"`
+}
+
 // Message represents a chat message
 type Message struct {
 	Role    string `json:"role"`
@@ -22,14 +30,18 @@ type Message struct {
 
 // ChatRequest represents the request to DeepSeek API
 type ChatRequest struct {
-	Model          string    `json:"model"`
-	Messages       []Message `json:"messages"`
-	Temperature    float64   `json:"temperature"`
-	MaxTokens      int       `json:"max_tokens"`
-	Stream         bool      `json:"stream"`
-	ResponseFormat *struct {
-		Type string `json:"type"`
-	} `json:"response_format,omitempty"`
+	Model          string          `json:"model"`
+	Messages       []Message       `json:"messages"`
+	Temperature    float64         `json:"temperature"`
+	MaxTokens      int             `json:"max_tokens"`
+	Stream         bool            `json:"stream"`
+	StreamOptions  *StreamOptions  `json:"stream_options,omitempty"`
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+}
+
+// StreamOptions configures streaming completions
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
 // ChatResponse represents the response from DeepSeek API
@@ -46,19 +58,53 @@ type ChatResponse struct {
 		} `json:"message"`
 		FinishReason string `json:"finish_reason"`
 	} `json:"choices"`
-	Usage struct {
-		PromptTokens     int `json:"prompt_tokens"`
-		CompletionTokens int `json:"completion_tokens"`
-		TotalTokens      int `json:"total_tokens"`
-	} `json:"usage"`
+	Usage Usage `json:"usage"`
+}
+
+// Usage reports the token usage of a completion
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// StreamChunk is one data frame of a streaming completion
+type StreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *Usage     `json:"usage"`
+	Error *ErrorBody `json:"error"`
+}
+
+// ErrorBody is the error object returned by OpenAI-compatible APIs
+type ErrorBody struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+	Code    any    `json:"code"`
 }
 
-// Client represents a DeepSeek API client
+// StreamResult summarises a finished streaming completion
+type StreamResult struct {
+	FinishReason string
+	Usage        *Usage
+}
+
+// Client is a chat completion client for DeepSeek and any other backend that
+// speaks the OpenAI chat completions protocol
 type Client struct {
+	Provider   string
+	BaseURL    string
 	APIKey     string
+	APIKeyEnv  string // name of the env var holding APIKey, used in error messages
 	Model      string
 	MaxTokens  int
 	HTTPClient *http.Client
+	Retry      RetryPolicy
+	OnCall     CallHook // optional, called after every call
 }
 
 // NewClient creates a new DeepSeek client
@@ -69,24 +115,57 @@ func NewClient() *Client {
 		model = "deepseek-chat"
 	}
 
-	maxTokens := 2000
-	if mt := os.Getenv("DEEPSEEK_MAX_TOKENS"); mt != "" {
-		fmt.Sscanf(mt, "%d", &maxTokens)
+	baseURL := os.Getenv("DEEPSEEK_API_URL")
+	if baseURL == "" {
+		baseURL = DeepSeekAPIURL
 	}
 
 	return &Client{
+		Provider:   ProviderDeepSeek,
+		BaseURL:    baseURL,
 		APIKey:     apiKey,
+		APIKeyEnv:  "DEEPSEEK_API_KEY",
 		Model:      model,
-		MaxTokens:  maxTokens,
+		MaxTokens:  envInt("DEEPSEEK_MAX_TOKENS", 2000),
 		HTTPClient: &http.Client{},
+		Retry:      RetryPolicyFromEnv(),
+	}
+}
+
+// Name returns the provider name of the client
+func (c *Client) Name() string {
+	return c.Provider
+}
+
+// Chat sends a chat request in JSON mode and returns the message content
+func (c *Client) Chat(ctx context.Context, messages []Message) (string, error) {
+	content, err := c.complete(ctx, messages, &ResponseFormat{Type: "json_object"})
+	if err != nil {
+		return "", err
 	}
+
+	// Try to parse and fix JSON if needed
+	return fixJSON(content), nil
+}
+
+// ChatText sends a chat request without forcing JSON output
+func (c *Client) ChatText(ctx context.Context, messages []Message) (string, error) {
+	return c.complete(ctx, messages, nil)
 }
 
-// Chat sends a chat request to DeepSeek API
-func (c *Client) Chat(messages []Message) (string, error) {
-	if c.APIKey == "" {
-		return "", fmt.Errorf("DEEPSEEK_API_KEY not set")
+// complete sends a non-streaming chat completion request
+func (c *Client) complete(ctx context.Context, messages []Message, format *ResponseFormat) (content string, err error) {
+	if err := c.checkAPIKey(); err != nil {
+		return "", err
+	}
+
+	callType := CallText
+	if format != nil {
+		callType = CallJSON
 	}
+	var tokens *Usage
+	start := time.Now()
+	defer func() { c.track(ctx, callType, start, tokens, err) }()
 
 	// Add system prompt if not present
 	if len(messages) == 0 || messages[0].Role != "system" {
@@ -96,16 +175,12 @@ func (c *Client) Chat(messages []Message) (string, error) {
 
 	// Prepare request
 	reqBody := ChatRequest{
-		Model:       c.Model,
-		Messages:    messages,
-		Temperature: 0.7,
-		MaxTokens:   c.MaxTokens,
-		Stream:      false,
-		ResponseFormat: &struct {
-			Type string `json:"type"`
-		}{
-			Type: "json_object",
-		},
+		Model:          c.Model,
+		Messages:       messages,
+		Temperature:    0.7,
+		MaxTokens:      c.MaxTokens,
+		Stream:         false,
+		ResponseFormat: format,
 	}
 
 	jsonData, err := json.Marshal(reqBody)
@@ -113,56 +188,48 @@ func (c *Client) Chat(messages []Message) (string, error) {
 		return "", fmt.Errorf("failed to marshal request: %v", err)
 	}
 
-	// Create HTTP request
-	req, err := http.NewRequest("POST", DeepSeekAPIURL, bytes.NewBuffer(jsonData))
+	// Send request with retries
+	resp, err := c.post(ctx, jsonData, false)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %v", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.APIKey)
-
-	// Send request
-	resp, err := c.HTTPClient.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("failed to send request: %v", err)
+		return "", err
 	}
 	defer resp.Body.Close()
 
 	// Read response
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %v", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+		return "", transportError(err, "failed to read response")
 	}
 
 	// Parse response
 	var chatResp ChatResponse
 	if err := json.Unmarshal(body, &chatResp); err != nil {
-		return "", fmt.Errorf("failed to parse response: %v", err)
+		return "", &Error{Kind: KindUpstream, Message: fmt.Sprintf("failed to parse response: %v", err), Err: err}
 	}
 
 	if len(chatResp.Choices) == 0 {
-		return "", fmt.Errorf("no response from API")
+		return "", &Error{Kind: KindUpstream, Message: "no response from API"}
 	}
 
-	content := chatResp.Choices[0].Message.Content
-
-	// Try to parse and fix JSON if needed
-	content = fixJSON(content)
-
-	return content, nil
+	tokens = &chatResp.Usage
+	return chatResp.Choices[0].Message.Content, nil
 }
 
-// ChatStream sends a streaming chat request to DeepSeek API
-func (c *Client) ChatStream(messages []Message, callback func(string) error) error {
-	if c.APIKey == "" {
-		return fmt.Errorf("DEEPSEEK_API_KEY not set")
+// ChatStream sends a streaming chat request and calls callback for every content delta
+func (c *Client) ChatStream(ctx context.Context, messages []Message, callback func(string) error) (result *StreamResult, err error) {
+	if err := c.checkAPIKey(); err != nil {
+		return nil, err
 	}
 
+	start := time.Now()
+	defer func() {
+		var tokens *Usage
+		if result != nil {
+			tokens = result.Usage
+		}
+		c.track(ctx, CallStream, start, tokens, err)
+	}()
+
 	// Add system prompt if not present
 	if len(messages) == 0 || messages[0].Role != "system" {
 		systemPrompt := getSystemPrompt()
@@ -176,94 +243,117 @@ func (c *Client) ChatStream(messages []Message, callback func(string) error) err
 		Temperature: 0.7,
 		MaxTokens:   c.MaxTokens,
 		Stream:      true,
+		StreamOptions: &StreamOptions{
+			IncludeUsage: true,
+		},
 	}
 
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
-		return fmt.Errorf("failed to marshal request: %v", err)
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
 	}
 
-	// Create HTTP request
-	req, err := http.NewRequest("POST", DeepSeekAPIURL, bytes.NewBuffer(jsonData))
+	// Send request with retries. Only establishing the stream is retried:
+	// once deltas reached the callback a retry would duplicate them.
+	resp, err := c.post(ctx, jsonData, true)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %v", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.APIKey)
-	req.Header.Set("Accept", "text/event-stream")
-
-	// Send request
-	resp, err := c.HTTPClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to send request: %v", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
-	}
-
 	// Read streaming response
-	reader := io.Reader(resp.Body)
-	buf := make([]byte, 4096)
-	var accumulated strings.Builder
-
-	for {
-		n, err := reader.Read(buf)
-		if n > 0 {
-			chunk := string(buf[:n])
-			lines := strings.Split(chunk, "\n")
-
-			for _, line := range lines {
-				line = strings.TrimSpace(line)
-				if line == "" || line == "data: [DONE]" {
-					continue
-				}
+	return readStream(resp.Body, callback)
+}
 
-				if strings.HasPrefix(line, "data: ") {
-					data := strings.TrimPrefix(line, "data: ")
-					
-					var streamResp struct {
-						Choices []struct {
-							Delta struct {
-								Content string `json:"content"`
-							} `json:"delta"`
-						} `json:"choices"`
-					}
-
-					if err := json.Unmarshal([]byte(data), &streamResp); err == nil {
-						if len(streamResp.Choices) > 0 {
-							content := streamResp.Choices[0].Delta.Content
-							if content != "" {
-								accumulated.WriteString(content)
-								if err := callback(content); err != nil {
-									return err
-								}
-							}
-						}
-					}
-				}
-			}
-		}
+// readStream decodes the SSE body of a streaming completion. Frames that
+// cannot be decoded are reported as errors so no tokens are silently lost.
+func readStream(body io.Reader, callback func(string) error) (*StreamResult, error) {
+	reader := sse.NewReader(body)
+	result := &StreamResult{}
+	done := false
 
+	for !done {
+		ev, err := reader.Next()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			return fmt.Errorf("failed to read stream: %v", err)
+			return nil, transportError(err, "failed to read stream")
+		}
+
+		if ev.Event == "error" {
+			return nil, &Error{Kind: KindUpstream, Message: fmt.Sprintf("stream error: %s", truncate(ev.Data, 500))}
+		}
+
+		data := strings.TrimSpace(ev.Data)
+		if data == "[DONE]" {
+			done = true
+			continue
+		}
+
+		var chunk StreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, &Error{Kind: KindUpstream, Message: fmt.Sprintf("malformed stream frame: %v (data: %s)", err, truncate(data, 200)), Err: err}
+		}
+
+		if chunk.Error != nil {
+			return nil, &Error{Kind: KindUpstream, Message: fmt.Sprintf("stream error: %s", chunk.Error.Message)}
 		}
+
+		if chunk.Usage != nil {
+			result.Usage = chunk.Usage
+		}
+
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content != "" {
+				if err := callback(choice.Delta.Content); err != nil {
+					return nil, err
+				}
+			}
+			if choice.FinishReason != nil && *choice.FinishReason != "" {
+				result.FinishReason = *choice.FinishReason
+			}
+		}
+	}
+
+	// Some servers close the stream without [DONE]; only a stream that also
+	// never reported a finish reason was cut off
+	if !done && result.FinishReason == "" {
+		return nil, &Error{Kind: KindUpstream, Message: "stream ended unexpectedly"}
+	}
+
+	return result, nil
+}
+
+// truncate shortens s to at most n bytes for error messages
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
 	}
+	return s[:n] + "..."
+}
 
+// checkAPIKey reports a missing API key for providers that require one
+func (c *Client) checkAPIKey() error {
+	if c.APIKey == "" && c.APIKeyEnv != "" {
+		return fmt.Errorf("%s not set", c.APIKeyEnv)
+	}
 	return nil
 }
 
+// setHeaders sets the common request headers
+func (c *Client) setHeaders(req *http.Request) {
+	req.Header.Set("Content-Type", "application/json")
+	if c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+}
+
 // fixJSON attempts to extract and fix JSON from the response
 func fixJSON(text string) string {
 	// Remove markdown code blocks if present
 	text = strings.TrimSpace(text)
-	
+
 	// Remove ```json and ``` markers
 	if strings.HasPrefix(text, "```json") {
 		text = strings.TrimPrefix(text, "```json")
@@ -278,6 +368,12 @@ func fixJSON(text string) string {
 	return text
 }
 
+// SystemPrompt returns the consultant system prompt that Chat and ChatStream
+// add when the messages don't start with a system message
+func SystemPrompt() string {
+	return getSystemPrompt()
+}
+
 // getSystemPrompt returns the system prompt for the AI
 func getSystemPrompt() string {
 	return `你是一位专业的一人公司商业顾问，专门帮助创业者规划资源配置和预算。
@@ -0,0 +1,161 @@
+package deepseek
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ErrorKind classifies failures of a provider call
+type ErrorKind string
+
+const (
+	// KindRateLimited means the provider throttled us (HTTP 429)
+	KindRateLimited ErrorKind = "rate_limited"
+	// KindAuth means the provider rejected our credentials (HTTP 401/403)
+	KindAuth ErrorKind = "auth"
+	// KindTimeout means the call did not finish within its deadline
+	KindTimeout ErrorKind = "timeout"
+	// KindUpstream covers 5xx responses, network errors and unexpected replies
+	KindUpstream ErrorKind = "upstream"
+	// KindCanceled means the caller cancelled the call
+	KindCanceled ErrorKind = "canceled"
+)
+
+// Error is returned by provider calls that failed at the HTTP level
+type Error struct {
+	Kind       ErrorKind
+	StatusCode int           // HTTP status of the provider response, 0 for network errors
+	RetryAfter time.Duration // delay requested by the provider, if any
+	Message    string
+	Attempts   int
+	Err        error
+}
+
+func (e *Error) Error() string {
+	msg := e.Message
+	if e.StatusCode != 0 {
+		msg = fmt.Sprintf("API error (status %d): %s", e.StatusCode, e.Message)
+	}
+	if e.Attempts > 1 {
+		msg = fmt.Sprintf("%s (after %d attempts)", msg, e.Attempts)
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// retryable reports whether the call may succeed when sent again
+func (e *Error) retryable() bool {
+	switch e.Kind {
+	case KindRateLimited, KindTimeout:
+		return true
+	case KindUpstream:
+		return e.StatusCode == 0 || e.StatusCode == http.StatusRequestTimeout || e.StatusCode >= 500
+	}
+	return false
+}
+
+// KindOf returns the kind of a provider error, or "" for other errors
+func KindOf(err error) ErrorKind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return ""
+}
+
+// HTTPStatus maps a provider error to the status a handler should return:
+// 429 when rate limited, 504 on timeouts, 502 for auth and upstream failures
+// (the caller did nothing wrong) and 500 for anything else, including
+// cancelled calls.
+func HTTPStatus(err error) int {
+	switch KindOf(err) {
+	case KindRateLimited:
+		return http.StatusTooManyRequests
+	case KindTimeout:
+		return http.StatusGatewayTimeout
+	case KindAuth, KindUpstream:
+		return http.StatusBadGateway
+	}
+	return http.StatusInternalServerError
+}
+
+// RetryAfterOf returns the delay requested by the provider, if any
+func RetryAfterOf(err error) time.Duration {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.RetryAfter
+	}
+	return 0
+}
+
+// statusError classifies a non-200 provider response
+func statusError(resp *http.Response, body []byte) *Error {
+	e := &Error{
+		StatusCode: resp.StatusCode,
+		Message:    truncate(string(body), 500),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		e.Kind = KindRateLimited
+	case resp.StatusCode == http.StatusUnauthorized, resp.StatusCode == http.StatusForbidden:
+		e.Kind = KindAuth
+	case resp.StatusCode == http.StatusGatewayTimeout:
+		e.Kind = KindTimeout
+	default:
+		e.Kind = KindUpstream
+	}
+	return e
+}
+
+// transportError classifies an error raised while sending or reading a request
+func transportError(err error, op string) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return &Error{Kind: KindTimeout, Message: fmt.Sprintf("%s: timed out", op), Err: err}
+	}
+	return &Error{Kind: KindUpstream, Message: fmt.Sprintf("%s: %v", op, err), Err: err}
+}
+
+// contextError classifies a request whose context is done: the attempt
+// timeout and the call deadline are timeouts, a cancelled parent stays a
+// cancellation. It returns nil while the context is live.
+func contextError(ctx context.Context, op string) *Error {
+	cause := context.Cause(ctx)
+	switch {
+	case cause == nil:
+		return nil
+	case errors.Is(cause, errAttemptTimeout), errors.Is(cause, context.DeadlineExceeded):
+		return &Error{Kind: KindTimeout, Message: fmt.Sprintf("%s: timed out", op), Err: context.DeadlineExceeded}
+	}
+	return &Error{Kind: KindCanceled, Message: fmt.Sprintf("%s: %v", op, cause), Err: cause}
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,55 @@
+package deepseek
+
+import (
+	"context"
+	"time"
+)
+
+// Call types reported to the call hook
+const (
+	CallJSON   = "json"
+	CallText   = "text"
+	CallStream = "stream"
+)
+
+// Call describes a finished model call
+type Call struct {
+	Provider         string
+	Model            string
+	Type             string // json / text / stream
+	PromptTokens     int
+	CompletionTokens int
+	Latency          time.Duration
+	ErrorKind        string // empty on success
+}
+
+// CallHook is called after every model call of a client, failed ones
+// included, e.g. to record token usage. It runs on the calling goroutine
+// and must not fail the call.
+type CallHook func(ctx context.Context, call Call)
+
+// track reports a finished call to the client's hook, if any. Failed calls
+// carry their error kind, so latency and error rates can be tracked next to
+// the token counts.
+func (c *Client) track(ctx context.Context, callType string, start time.Time, tokens *Usage, err error) {
+	if c.OnCall == nil {
+		return
+	}
+	call := Call{
+		Provider: c.Provider,
+		Model:    c.Model,
+		Type:     callType,
+		Latency:  time.Since(start),
+	}
+	if tokens != nil {
+		call.PromptTokens = tokens.PromptTokens
+		call.CompletionTokens = tokens.CompletionTokens
+	}
+	if err != nil {
+		call.ErrorKind = string(KindOf(err))
+		if call.ErrorKind == "" {
+			call.ErrorKind = "client"
+		}
+	}
+	c.OnCall(ctx, call)
+}
@@ -0,0 +1,122 @@
+package deepseek
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Supported values of the LLM_PROVIDER env var
+const (
+	ProviderDeepSeek = "deepseek"
+	ProviderOpenAI   = "openai"
+	ProviderLocal    = "local"
+)
+
+const (
+	OpenAIAPIURL   = "https://api.openai.com/v1/chat/completions"
+	LocalLLMAPIURL = "http://localhost:11434/v1/chat/completions"
+)
+
+// LLMProvider is a chat completion backend used by the handlers. Calls are
+// bounded by ctx and the client's RetryPolicy; failures are reported as
+// *Error so handlers can map them with HTTPStatus.
+type LLMProvider interface {
+	// Name returns the provider name (deepseek, openai, local)
+	Name() string
+	// Chat sends a request in JSON mode and returns the JSON content
+	Chat(ctx context.Context, messages []Message) (string, error)
+	// ChatText sends a request and returns the plain text content
+	ChatText(ctx context.Context, messages []Message) (string, error)
+	// ChatStream streams the response, calling callback for every content
+	// delta, and reports the finish reason and usage of the final chunk
+	ChatStream(ctx context.Context, messages []Message, callback func(string) error) (*StreamResult, error)
+}
+
+// NewProvider creates the provider selected by LLM_PROVIDER (default: deepseek).
+// LLM_BASE_URL, when set, overrides the endpoint of any provider, which is
+// useful for pointing the handlers at a fake server. onCall, if not nil, is
+// called after every model call.
+func NewProvider(onCall CallHook) (LLMProvider, error) {
+	name := strings.ToLower(strings.TrimSpace(os.Getenv("LLM_PROVIDER")))
+
+	var client *Client
+	switch name {
+	case "", ProviderDeepSeek:
+		client = NewClient()
+	case ProviderOpenAI:
+		client = NewOpenAIClient()
+	case ProviderLocal, "ollama", "llamacpp":
+		client = NewLocalClient()
+	default:
+		return nil, fmt.Errorf("unsupported LLM_PROVIDER: %s", name)
+	}
+
+	if baseURL := os.Getenv("LLM_BASE_URL"); baseURL != "" {
+		client.BaseURL = baseURL
+	}
+	client.OnCall = onCall
+
+	return client, nil
+}
+
+// NewOpenAIClient creates a client for the OpenAI API or any OpenAI-compatible service
+func NewOpenAIClient() *Client {
+	baseURL := os.Getenv("OPENAI_BASE_URL")
+	if baseURL == "" {
+		baseURL = OpenAIAPIURL
+	}
+
+	model := os.Getenv("OPENAI_MODEL")
+	if model == "" {
+		model = "gpt-4o-mini"
+	}
+
+	return &Client{
+		Provider:   ProviderOpenAI,
+		BaseURL:    baseURL,
+		APIKey:     os.Getenv("OPENAI_API_KEY"),
+		APIKeyEnv:  "OPENAI_API_KEY",
+		Model:      model,
+		MaxTokens:  envInt("OPENAI_MAX_TOKENS", 2000),
+		HTTPClient: &http.Client{},
+		Retry:      RetryPolicyFromEnv(),
+	}
+}
+
+// NewLocalClient creates a client for a local model server exposing the
+// OpenAI-compatible endpoint (Ollama, llama.cpp server). No API key is required.
+func NewLocalClient() *Client {
+	baseURL := os.Getenv("LOCAL_LLM_URL")
+	if baseURL == "" {
+		baseURL = LocalLLMAPIURL
+	}
+
+	model := os.Getenv("LOCAL_LLM_MODEL")
+	if model == "" {
+		model = "qwen2.5:7b"
+	}
+
+	return &Client{
+		Provider:   ProviderLocal,
+		BaseURL:    baseURL,
+		APIKey:     os.Getenv("LOCAL_LLM_API_KEY"),
+		Model:      model,
+		MaxTokens:  envInt("LOCAL_LLM_MAX_TOKENS", 2000),
+		HTTPClient: &http.Client{},
+		Retry:      RetryPolicyFromEnv(),
+	}
+}
+
+// envInt reads an integer env var, falling back to def when unset or invalid
+func envInt(key string, def int) int {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+			return n
+		}
+	}
+	return def
+}
@@ -0,0 +1,205 @@
+package deepseek
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"time"
+)
+
+// RetryPolicy controls timeouts and retries of provider calls
+type RetryPolicy struct {
+	// MaxAttempts is the number of tries including the first one
+	MaxAttempts int
+	// AttemptTimeout bounds a single try. For streams it only covers the
+	// wait for the response headers; the body is bounded by TotalTimeout.
+	AttemptTimeout time.Duration
+	// TotalTimeout bounds the whole call including retries and backoff
+	TotalTimeout time.Duration
+	// BaseDelay and MaxDelay bound the exponential backoff between tries
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// SafetyMargin is kept free before the Lambda deadline so the handler
+	// can still answer after the last try gave up
+	SafetyMargin time.Duration
+}
+
+// RetryPolicyFromEnv reads LLM_MAX_ATTEMPTS, LLM_ATTEMPT_TIMEOUT and
+// LLM_TOTAL_TIMEOUT (Go durations such as "45s"), with defaults suited to
+// the 120s Lambda timeout
+func RetryPolicyFromEnv() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    envInt("LLM_MAX_ATTEMPTS", 3),
+		AttemptTimeout: envDuration("LLM_ATTEMPT_TIMEOUT", 60*time.Second),
+		TotalTimeout:   envDuration("LLM_TOTAL_TIMEOUT", 110*time.Second),
+		BaseDelay:      500 * time.Millisecond,
+		MaxDelay:       8 * time.Second,
+		SafetyMargin:   3 * time.Second,
+	}
+}
+
+// deadline derives the context of a whole call: TotalTimeout from now, but
+// never past the Lambda deadline minus SafetyMargin
+func (p RetryPolicy) deadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	deadline := time.Now().Add(p.TotalTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Add(-p.SafetyMargin).Before(deadline) {
+		deadline = d.Add(-p.SafetyMargin)
+	}
+	return context.WithDeadline(ctx, deadline)
+}
+
+// backoff returns the delay before the given retry: exponential with full
+// jitter, or the provider's Retry-After when it asks for longer
+func (p RetryPolicy) backoff(retry int, retryAfter time.Duration) time.Duration {
+	ceiling := p.BaseDelay << (retry - 1)
+	if ceiling > p.MaxDelay || ceiling <= 0 {
+		ceiling = p.MaxDelay
+	}
+	delay := time.Duration(rand.Int64N(int64(ceiling) + 1))
+	if retryAfter > delay {
+		delay = retryAfter
+	}
+	return delay
+}
+
+// post sends a chat completion request, retrying rate limits, timeouts and
+// upstream failures. On success the caller must close the response body,
+// which also releases the call's deadline.
+func (c *Client) post(ctx context.Context, payload []byte, stream bool) (*http.Response, error) {
+	policy := c.Retry
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+
+	callCtx, cancelCall := policy.deadline(ctx)
+
+	for attempt := 1; ; attempt++ {
+		resp, err := c.attempt(callCtx, policy, payload, stream)
+		if err == nil {
+			resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancelCall}
+			return resp, nil
+		}
+		err.Attempts = attempt
+
+		if !err.retryable() || attempt >= policy.MaxAttempts {
+			cancelCall()
+			return nil, err
+		}
+
+		delay := policy.backoff(attempt, err.RetryAfter)
+		if d, ok := callCtx.Deadline(); ok && time.Until(d) < delay {
+			// Not enough time left for another try
+			cancelCall()
+			return nil, err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-callCtx.Done():
+			timer.Stop()
+			cancelCall()
+			return nil, err
+		case <-timer.C:
+		}
+	}
+}
+
+// errAttemptTimeout is the cancel cause of an attempt that ran out of time
+var errAttemptTimeout = errors.New("attempt timed out")
+
+// attempt performs a single try of post
+func (c *Client) attempt(ctx context.Context, policy RetryPolicy, payload []byte, stream bool) (*http.Response, *Error) {
+	attemptCtx, cancel := context.WithCancelCause(ctx)
+	timer := time.AfterFunc(policy.AttemptTimeout, func() { cancel(errAttemptTimeout) })
+	release := func() {
+		timer.Stop()
+		cancel(nil)
+	}
+
+	req, err := http.NewRequestWithContext(attemptCtx, "POST", c.BaseURL, bytes.NewReader(payload))
+	if err != nil {
+		release()
+		return nil, &Error{Kind: KindUpstream, Message: "failed to create request", Err: err}
+	}
+
+	c.setHeaders(req)
+	if stream {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		// Classify before release, which cancels the context itself
+		e := contextError(attemptCtx, "failed to send request")
+		release()
+		if e == nil {
+			e = transportError(err, "failed to send request")
+		}
+		return nil, e
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		release()
+		return nil, statusError(resp, body)
+	}
+
+	// Streams may run longer than one attempt; only the call deadline applies
+	if stream {
+		timer.Stop()
+	}
+	resp.Body = &attemptBody{ReadCloser: resp.Body, ctx: attemptCtx, release: release}
+	return resp, nil
+}
+
+// attemptBody classifies read errors of a response by the attempt's context,
+// so a body cut off by a timeout reports KindTimeout, and releases the
+// context when closed
+type attemptBody struct {
+	io.ReadCloser
+	ctx     context.Context
+	release func()
+}
+
+func (b *attemptBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF {
+		if e := contextError(b.ctx, "failed to read response"); e != nil {
+			return n, e
+		}
+	}
+	return n, err
+}
+
+func (b *attemptBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
+
+// cancelOnClose releases a request context when the response body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// envDuration reads a Go duration env var, falling back to def
+func envDuration(key string, def time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return def
+}
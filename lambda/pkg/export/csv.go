@@ -0,0 +1,58 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"math"
+)
+
+// CSV renders the budget sheet of a report: one row per phase and budget
+// category, then the phase totals and the plan summary. It starts with a
+// UTF-8 BOM so spreadsheet applications detect the encoding.
+func CSV(r *Report) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("\uFEFF")
+	w := csv.NewWriter(&buf)
+
+	w.Write([]string{"阶段", "时长", "月数", "项目", "月度金额(XZT)", "阶段金额(XZT)"})
+	for i, phase := range r.Recs.Phases {
+		var months *float64
+		var total *float64
+		if r.Budget != nil && i < len(r.Budget.Phases) {
+			months = r.Budget.Phases[i].Months
+			total = r.Budget.Phases[i].Total
+		}
+		for _, category := range Categories(phase.BudgetBreakdown) {
+			amount := phase.BudgetBreakdown[category]
+			w.Write([]string{phase.PhaseName, phase.Duration, optional(months), category, Number(amount), product(amount, months)})
+		}
+		w.Write([]string{phase.PhaseName, phase.Duration, optional(months), "阶段合计", Number(phase.MonthlyBudget), optional(total)})
+	}
+
+	if b := r.Budget; b != nil {
+		w.Write(nil)
+		w.Write([]string{"汇总", "", "", "月度人力成本", Number(b.MonthlyRoles), ""})
+		w.Write([]string{"汇总", "", "", "月度AI工具成本", Number(b.MonthlyWorkflows), ""})
+		w.Write([]string{"汇总", "", "", "月度所需合计", Number(b.MonthlyRequired), ""})
+		w.Write([]string{"汇总", "", Number(b.TotalMonths), "总预算", "", Number(b.TotalBudget)})
+	}
+
+	w.Flush()
+	return buf.Bytes()
+}
+
+// optional formats a number that may be unknown
+func optional(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return Number(*v)
+}
+
+// product formats amount × months, empty when the months are unknown
+func product(amount float64, months *float64) string {
+	if months == nil {
+		return ""
+	}
+	return Number(math.Round(amount**months*100) / 100)
+}
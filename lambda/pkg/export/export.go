@@ -0,0 +1,250 @@
+// Package export renders saved reports to Markdown, PDF, DOCX, CSV and XLSX.
+package export
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/x-zero/business-consultant/pkg/budget"
+	"github.com/x-zero/business-consultant/pkg/report"
+)
+
+// Export formats
+const (
+	FormatMarkdown = "markdown"
+	FormatPDF      = "pdf"
+	FormatDOCX     = "docx"
+	FormatCSV      = "csv"
+	FormatXLSX     = "xlsx"
+)
+
+// Report is a saved report with its derived budget. Budget is nil for
+// reports saved with validation errors.
+type Report struct {
+	ReportID     string
+	BusinessGoal string
+	Version      int
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Recs         *report.Recommendations
+	Budget       *budget.Summary
+}
+
+// File is a rendered export
+type File struct {
+	Data        []byte
+	ContentType string
+	Extension   string
+}
+
+// Render renders a report in the given format
+func Render(format string, r *Report) (*File, error) {
+	switch format {
+	case FormatMarkdown:
+		return &File{Data: []byte(Markdown(r)), ContentType: "text/markdown; charset=utf-8", Extension: "md"}, nil
+	case FormatCSV:
+		return &File{Data: CSV(r), ContentType: "text/csv; charset=utf-8", Extension: "csv"}, nil
+	case FormatDOCX:
+		data, err := DOCX(r)
+		if err != nil {
+			return nil, err
+		}
+		return &File{Data: data, ContentType: "application/vnd.openxmlformats-officedocument.wordprocessingml.document", Extension: "docx"}, nil
+	case FormatXLSX:
+		data, err := XLSX(r)
+		if err != nil {
+			return nil, err
+		}
+		return &File{Data: data, ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", Extension: "xlsx"}, nil
+	case FormatPDF:
+		data, err := PDF(r)
+		if err != nil {
+			return nil, err
+		}
+		return &File{Data: data, ContentType: "application/pdf", Extension: "pdf"}, nil
+	}
+	return nil, fmt.Errorf("unknown export format: %s", format)
+}
+
+// block kinds of the document model shared by the Markdown, DOCX and PDF
+// renderers
+const (
+	blockHeading = iota
+	blockParagraph
+	blockList
+	blockTable
+)
+
+// block is one element of a rendered report
+type block struct {
+	kind  int
+	level int        // heading level, 1 to 3
+	text  string     // heading or paragraph
+	items []string   // list items
+	rows  [][]string // table rows, the first is the header
+}
+
+// document lays out a report as blocks in the order every format shows it
+func document(r *Report) []block {
+	recs := r.Recs
+	doc := []block{
+		{kind: blockHeading, level: 1, text: r.BusinessGoal},
+		{kind: blockParagraph, text: fmt.Sprintf("报告编号：%s　版本：%d　创建时间：%s　更新时间：%s",
+			r.ReportID, r.Version, r.CreatedAt.Format("2006-01-02 15:04"), r.UpdatedAt.Format("2006-01-02 15:04"))},
+	}
+
+	if recs.Summary != "" {
+		doc = append(doc,
+			block{kind: blockHeading, level: 2, text: "方案概述"},
+			block{kind: blockParagraph, text: recs.Summary})
+	}
+
+	if len(recs.AIWorkflows) > 0 {
+		doc = append(doc, block{kind: blockHeading, level: 2, text: fmt.Sprintf("AI工作流（%d）", len(recs.AIWorkflows))})
+		for i, wf := range recs.AIWorkflows {
+			doc = append(doc,
+				block{kind: blockHeading, level: 3, text: fmt.Sprintf("%d. %s", i+1, wf.Name)},
+				block{kind: blockList, items: []string{
+					"描述：" + wf.Description,
+					"输入要求：" + wf.InputRequirements,
+					"输出要求：" + wf.OutputRequirements,
+					"预估成本：" + Money(wf.EstimatedCost) + "/月",
+					"优先级：" + PriorityLabel(wf.Priority),
+					"状态：" + StatusLabel(wf.Status),
+				}})
+		}
+	}
+
+	if len(recs.HumanRoles) > 0 {
+		doc = append(doc, block{kind: blockHeading, level: 2, text: fmt.Sprintf("真人岗位（%d）", len(recs.HumanRoles))})
+		for i, role := range recs.HumanRoles {
+			doc = append(doc,
+				block{kind: blockHeading, level: 3, text: fmt.Sprintf("%d. %s", i+1, role.Title)},
+				block{kind: blockList, items: []string{
+					"职责：" + strings.Join(role.Responsibilities, "；"),
+					"要求：" + strings.Join(role.Requirements, "；"),
+					"工作时间：" + role.WorkHours,
+					"月度预算：" + Money(role.MonthlyBudget),
+					"优先级：" + PriorityLabel(role.Priority),
+					"状态：" + StatusLabel(role.Status),
+				}})
+		}
+	}
+
+	if len(recs.Phases) > 0 {
+		doc = append(doc, block{kind: blockHeading, level: 2, text: "分阶段预算"})
+		rows := [][]string{{"阶段", "时长", "月度预算", "明细合计", "阶段总预算"}}
+		for i, phase := range recs.Phases {
+			breakdownTotal, total := "", ""
+			if r.Budget != nil && i < len(r.Budget.Phases) {
+				pb := r.Budget.Phases[i]
+				breakdownTotal = Money(pb.BreakdownTotal)
+				if pb.Total != nil {
+					total = Money(*pb.Total)
+				}
+			}
+			rows = append(rows, []string{phase.PhaseName, phase.Duration, Money(phase.MonthlyBudget), breakdownTotal, total})
+		}
+		doc = append(doc, block{kind: blockTable, rows: rows})
+
+		for _, phase := range recs.Phases {
+			if len(phase.BudgetBreakdown) == 0 {
+				continue
+			}
+			var items []string
+			for _, category := range Categories(phase.BudgetBreakdown) {
+				items = append(items, fmt.Sprintf("%s：%s", category, Money(phase.BudgetBreakdown[category])))
+			}
+			doc = append(doc,
+				block{kind: blockHeading, level: 3, text: phase.PhaseName + " 预算明细"},
+				block{kind: blockList, items: items})
+		}
+	}
+
+	if b := r.Budget; b != nil {
+		items := []string{
+			"月度人力成本：" + Money(b.MonthlyRoles),
+			"月度AI工具成本：" + Money(b.MonthlyWorkflows),
+			"月度所需合计：" + Money(b.MonthlyRequired),
+			"最高阶段月预算：" + Money(b.PeakMonthlyBudget),
+			"平均月支出：" + Money(b.AverageMonthlyBurn),
+			"总时长：" + Number(b.TotalMonths) + " 个月",
+			"总预算：" + Money(b.TotalBudget),
+		}
+		if !b.Complete {
+			items = append(items, "注意：部分阶段时长无法识别，未计入总时长和总预算")
+		}
+		doc = append(doc,
+			block{kind: blockHeading, level: 2, text: "预算汇总"},
+			block{kind: blockList, items: items})
+
+		if len(b.Issues) > 0 {
+			var issues []string
+			for _, issue := range b.Issues {
+				issues = append(issues, issue.Message)
+			}
+			doc = append(doc,
+				block{kind: blockHeading, level: 3, text: "预算检查"},
+				block{kind: blockList, items: issues})
+		}
+	}
+
+	doc = append(doc, block{kind: blockParagraph, text: "预算单位：XZT（1 XZT ≈ 1 CNY）。以上建议仅供参考，实际执行时请根据市场变化和个人情况调整。"})
+	return doc
+}
+
+// StatusLabel describes the publishing status of an item
+func StatusLabel(status *string) string {
+	if status == nil {
+		return "未发布"
+	}
+	switch *status {
+	case report.StatusPublished:
+		return "已发布"
+	case report.StatusDraftCreated:
+		return "等待发布"
+	}
+	return *status
+}
+
+// PriorityLabel translates a priority
+func PriorityLabel(priority string) string {
+	switch priority {
+	case report.PriorityHigh:
+		return "高"
+	case report.PriorityMedium:
+		return "中"
+	case report.PriorityLow:
+		return "低"
+	}
+	return priority
+}
+
+// Money formats an amount in XZT
+func Money(v float64) string {
+	return Number(v) + " XZT"
+}
+
+// Number formats a number without trailing zeros
+func Number(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// Categories returns the categories of a budget breakdown, largest first
+func Categories(breakdown map[string]float64) []string {
+	categories := make([]string, 0, len(breakdown))
+	for category := range breakdown {
+		categories = append(categories, category)
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		a, b := breakdown[categories[i]], breakdown[categories[j]]
+		if a != b {
+			return a > b
+		}
+		return categories[i] < categories[j]
+	})
+	return categories
+}
@@ -0,0 +1,399 @@
+package export
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// ttf is a parsed TrueType font. Only fonts with glyf outlines are supported:
+// CFF based OpenType fonts (.otf) cannot be embedded as CIDFontType2.
+type ttf struct {
+	tables          map[string][]byte
+	unitsPerEm      int
+	numGlyphs       int
+	numHMetrics     int
+	ascent, descent int
+	bbox            [4]int
+	longLoca        bool
+	lookup          func(r rune) uint16
+}
+
+// loadTTF reads a TrueType font or the first font of a TrueType collection
+func loadTTF(path string) (*ttf, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read font: %v", err)
+	}
+	f, err := parseTTF(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse font %s: %v", path, err)
+	}
+	return f, nil
+}
+
+var errTruncated = errors.New("truncated font data")
+
+func parseTTF(data []byte) (*ttf, error) {
+	if len(data) < 12 {
+		return nil, errTruncated
+	}
+	offset := 0
+	if string(data[:4]) == "ttcf" {
+		if len(data) < 16 {
+			return nil, errTruncated
+		}
+		offset = int(binary.BigEndian.Uint32(data[12:]))
+	}
+	if offset+12 > len(data) {
+		return nil, errTruncated
+	}
+	switch binary.BigEndian.Uint32(data[offset:]) {
+	case 0x00010000, 0x74727565: // 1.0 or "true"
+	case 0x4f54544f: // "OTTO"
+		return nil, errors.New("CFF outlines are not supported, use a TrueType font")
+	default:
+		return nil, errors.New("not a TrueType font")
+	}
+
+	f := &ttf{tables: map[string][]byte{}}
+	numTables := int(binary.BigEndian.Uint16(data[offset+4:]))
+	for i := 0; i < numTables; i++ {
+		rec := offset + 12 + 16*i
+		if rec+16 > len(data) {
+			return nil, errTruncated
+		}
+		start := int(binary.BigEndian.Uint32(data[rec+8:]))
+		length := int(binary.BigEndian.Uint32(data[rec+12:]))
+		if start < 0 || length < 0 || start+length > len(data) {
+			return nil, errTruncated
+		}
+		f.tables[string(data[rec:rec+4])] = data[start : start+length]
+	}
+	for _, tag := range []string{"head", "hhea", "maxp", "hmtx", "loca", "glyf", "cmap"} {
+		if f.tables[tag] == nil {
+			return nil, fmt.Errorf("missing %s table", tag)
+		}
+	}
+
+	head := f.tables["head"]
+	if len(head) < 54 {
+		return nil, errTruncated
+	}
+	f.unitsPerEm = int(binary.BigEndian.Uint16(head[18:]))
+	if f.unitsPerEm == 0 {
+		return nil, errors.New("invalid unitsPerEm")
+	}
+	for i := range f.bbox {
+		f.bbox[i] = int(int16(binary.BigEndian.Uint16(head[36+2*i:])))
+	}
+	f.longLoca = binary.BigEndian.Uint16(head[50:]) == 1
+
+	hhea := f.tables["hhea"]
+	if len(hhea) < 36 {
+		return nil, errTruncated
+	}
+	f.ascent = int(int16(binary.BigEndian.Uint16(hhea[4:])))
+	f.descent = int(int16(binary.BigEndian.Uint16(hhea[6:])))
+	f.numHMetrics = int(binary.BigEndian.Uint16(hhea[34:]))
+
+	maxp := f.tables["maxp"]
+	if len(maxp) < 6 {
+		return nil, errTruncated
+	}
+	f.numGlyphs = int(binary.BigEndian.Uint16(maxp[4:]))
+	if f.numHMetrics == 0 || len(f.tables["hmtx"]) < 4*f.numHMetrics {
+		return nil, errTruncated
+	}
+	locaSize := 2
+	if f.longLoca {
+		locaSize = 4
+	}
+	if len(f.tables["loca"]) < locaSize*(f.numGlyphs+1) {
+		return nil, errTruncated
+	}
+
+	if f.lookup, _ = cmapLookup(f.tables["cmap"]); f.lookup == nil {
+		return nil, errors.New("no Unicode cmap subtable")
+	}
+	return f, nil
+}
+
+// cmapLookup returns the rune to glyph lookup of the best Unicode subtable:
+// format 12 covers characters outside the BMP, format 4 only the BMP
+func cmapLookup(cmap []byte) (func(rune) uint16, error) {
+	if len(cmap) < 4 {
+		return nil, errTruncated
+	}
+	var format4, format12 []byte
+	n := int(binary.BigEndian.Uint16(cmap[2:]))
+	for i := 0; i < n; i++ {
+		rec := 4 + 8*i
+		if rec+8 > len(cmap) {
+			return nil, errTruncated
+		}
+		platform := binary.BigEndian.Uint16(cmap[rec:])
+		encoding := binary.BigEndian.Uint16(cmap[rec+2:])
+		off := int(binary.BigEndian.Uint32(cmap[rec+4:]))
+		if off+2 > len(cmap) {
+			continue
+		}
+		unicode := platform == 0 || (platform == 3 && (encoding == 1 || encoding == 10))
+		if !unicode {
+			continue
+		}
+		switch binary.BigEndian.Uint16(cmap[off:]) {
+		case 4:
+			format4 = cmap[off:]
+		case 12:
+			format12 = cmap[off:]
+		}
+	}
+
+	if t := format12; len(t) >= 16 {
+		groups := int(binary.BigEndian.Uint32(t[12:]))
+		if 16+12*groups <= len(t) {
+			return func(r rune) uint16 {
+				lo, hi := 0, groups
+				for lo < hi {
+					mid := (lo + hi) / 2
+					g := t[16+12*mid:]
+					start, end := rune(binary.BigEndian.Uint32(g)), rune(binary.BigEndian.Uint32(g[4:]))
+					switch {
+					case r < start:
+						hi = mid
+					case r > end:
+						lo = mid + 1
+					default:
+						return uint16(binary.BigEndian.Uint32(g[8:]) + uint32(r-start))
+					}
+				}
+				return 0
+			}, nil
+		}
+	}
+
+	if t := format4; len(t) >= 14 {
+		segs := int(binary.BigEndian.Uint16(t[6:])) / 2
+		if 16+8*segs > len(t) {
+			return nil, errTruncated
+		}
+		ends, starts := t[14:], t[16+2*segs:]
+		deltas, rangeOffsets := t[16+4*segs:], t[16+6*segs:]
+		return func(r rune) uint16 {
+			if r > 0xffff {
+				return 0
+			}
+			c := uint16(r)
+			for i := 0; i < segs; i++ {
+				if c > binary.BigEndian.Uint16(ends[2*i:]) {
+					continue
+				}
+				start := binary.BigEndian.Uint16(starts[2*i:])
+				if c < start {
+					return 0
+				}
+				delta := binary.BigEndian.Uint16(deltas[2*i:])
+				ro := int(binary.BigEndian.Uint16(rangeOffsets[2*i:]))
+				if ro == 0 {
+					return c + delta
+				}
+				// idRangeOffset is relative to its own position in the table
+				pos := 16 + 6*segs + 2*i + ro + 2*int(c-start)
+				if pos+2 > len(t) {
+					return 0
+				}
+				gid := binary.BigEndian.Uint16(t[pos:])
+				if gid == 0 {
+					return 0
+				}
+				return gid + delta
+			}
+			return 0
+		}, nil
+	}
+	return nil, nil
+}
+
+// glyph returns the glyph of a rune, 0 (.notdef) when the font lacks it
+func (f *ttf) glyph(r rune) uint16 {
+	gid := f.lookup(r)
+	if int(gid) >= f.numGlyphs {
+		return 0
+	}
+	return gid
+}
+
+// advance returns the advance width of a glyph in 1/1000 em
+func (f *ttf) advance(gid uint16) int {
+	i := int(gid)
+	if i >= f.numHMetrics {
+		i = f.numHMetrics - 1
+	}
+	return int(binary.BigEndian.Uint16(f.tables["hmtx"][4*i:])) * 1000 / f.unitsPerEm
+}
+
+// glyphData returns the outline of a glyph
+func (f *ttf) glyphData(gid uint16) []byte {
+	loca, glyf := f.tables["loca"], f.tables["glyf"]
+	var start, end int
+	if f.longLoca {
+		start = int(binary.BigEndian.Uint32(loca[4*int(gid):]))
+		end = int(binary.BigEndian.Uint32(loca[4*int(gid)+4:]))
+	} else {
+		start = 2 * int(binary.BigEndian.Uint16(loca[2*int(gid):]))
+		end = 2 * int(binary.BigEndian.Uint16(loca[2*int(gid)+2:]))
+	}
+	if start >= end || end > len(glyf) {
+		return nil
+	}
+	return glyf[start:end]
+}
+
+// Composite glyph flags
+const (
+	argsAreWords   = 0x0001
+	haveScale      = 0x0008
+	moreComponents = 0x0020
+	haveXYScale    = 0x0040
+	haveTwoByTwo   = 0x0080
+)
+
+// components returns the glyphs a composite glyph is built from
+func components(data []byte) []uint16 {
+	if len(data) < 10 || int16(binary.BigEndian.Uint16(data)) >= 0 {
+		return nil
+	}
+	var gids []uint16
+	for p := 10; p+4 <= len(data); {
+		flags := binary.BigEndian.Uint16(data[p:])
+		gids = append(gids, binary.BigEndian.Uint16(data[p+2:]))
+		p += 4
+		if flags&argsAreWords != 0 {
+			p += 4
+		} else {
+			p += 2
+		}
+		switch {
+		case flags&haveScale != 0:
+			p += 2
+		case flags&haveXYScale != 0:
+			p += 4
+		case flags&haveTwoByTwo != 0:
+			p += 8
+		}
+		if flags&moreComponents == 0 {
+			break
+		}
+	}
+	return gids
+}
+
+// subset returns a font program containing only the outlines of the used
+// glyphs. Glyph IDs are kept, so the content streams can address glyphs
+// directly through an Identity CIDToGIDMap; unused glyphs are left empty.
+func (f *ttf) subset(used map[uint16]bool) []byte {
+	keep := map[uint16]bool{}
+	var visit func(gid uint16)
+	visit = func(gid uint16) {
+		if keep[gid] || int(gid) >= f.numGlyphs {
+			return
+		}
+		keep[gid] = true
+		for _, c := range components(f.glyphData(gid)) {
+			visit(c)
+		}
+	}
+	visit(0)
+	for gid := range used {
+		visit(gid)
+	}
+
+	var glyf []byte
+	loca := make([]byte, 4*(f.numGlyphs+1))
+	for gid := 0; gid < f.numGlyphs; gid++ {
+		binary.BigEndian.PutUint32(loca[4*gid:], uint32(len(glyf)))
+		if keep[uint16(gid)] {
+			glyf = append(glyf, f.glyphData(uint16(gid))...)
+			for len(glyf)%4 != 0 {
+				glyf = append(glyf, 0)
+			}
+		}
+	}
+	binary.BigEndian.PutUint32(loca[4*f.numGlyphs:], uint32(len(glyf)))
+
+	head := append([]byte(nil), f.tables["head"]...)
+	binary.BigEndian.PutUint32(head[8:], 0)  // checkSumAdjustment, set below
+	binary.BigEndian.PutUint16(head[50:], 1) // long loca offsets
+
+	tables := map[string][]byte{
+		"head": head,
+		"hhea": f.tables["hhea"],
+		"maxp": f.tables["maxp"],
+		"hmtx": f.tables["hmtx"],
+		"loca": loca,
+		"glyf": glyf,
+	}
+	// Hinting programs are kept for the glyph instructions that use them
+	for _, tag := range []string{"cvt ", "fpgm", "prep"} {
+		if t := f.tables[tag]; t != nil {
+			tables[tag] = t
+		}
+	}
+	return writeTTF(tables)
+}
+
+// writeTTF assembles a font file from its tables
+func writeTTF(tables map[string][]byte) []byte {
+	tags := make([]string, 0, len(tables))
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	n := len(tags)
+	entrySelector := 0
+	for 1<<(entrySelector+1) <= n {
+		entrySelector++
+	}
+	searchRange := 16 << entrySelector
+
+	out := make([]byte, 12+16*n)
+	binary.BigEndian.PutUint32(out, 0x00010000)
+	binary.BigEndian.PutUint16(out[4:], uint16(n))
+	binary.BigEndian.PutUint16(out[6:], uint16(searchRange))
+	binary.BigEndian.PutUint16(out[8:], uint16(entrySelector))
+	binary.BigEndian.PutUint16(out[10:], uint16(16*n-searchRange))
+
+	headOffset := 0
+	for i, tag := range tags {
+		t := tables[tag]
+		rec := out[12+16*i:]
+		copy(rec, tag)
+		binary.BigEndian.PutUint32(rec[4:], checksum(t))
+		binary.BigEndian.PutUint32(rec[8:], uint32(len(out)))
+		binary.BigEndian.PutUint32(rec[12:], uint32(len(t)))
+		if tag == "head" {
+			headOffset = len(out)
+		}
+		out = append(out, t...)
+		for len(out)%4 != 0 {
+			out = append(out, 0)
+		}
+	}
+	binary.BigEndian.PutUint32(out[headOffset+8:], 0xb1b0afba-checksum(out))
+	return out
+}
+
+// checksum is the TrueType table checksum: the sum of the big endian words
+func checksum(data []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(data); i += 4 {
+		var word [4]byte
+		copy(word[:], data[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
@@ -0,0 +1,207 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// Glyphs of the test font
+const (
+	gidZhong     = 1 // 中
+	gidWen       = 2 // 文, a composite of gidComponent
+	gidComponent = 3
+	gidUnused    = 4 // 龘
+)
+
+var testRunes = map[rune]uint16{'中': gidZhong, '文': gidWen, '龘': gidUnused}
+
+// testGlyphs are the outlines of the test font. Simple glyphs only need a
+// distinct body; the composite must be well formed for components.
+var testGlyphs = [][]byte{
+	simpleGlyph(0x10),
+	simpleGlyph(0x20),
+	{0xff, 0xff, 0, 0, 0, 0, 0x03, 0xe8, 0x03, 0xe8, 0x00, 0x00, 0x00, gidComponent, 0, 0},
+	simpleGlyph(0x30),
+	simpleGlyph(0x40),
+}
+
+var testAdvances = []uint16{500, 1000, 1000, 625, 1000}
+
+func simpleGlyph(fill byte) []byte {
+	g := []byte{0, 1, 0, 0, 0, 0, 0x03, 0xe8, 0x03, 0xe8}
+	return append(g, bytes.Repeat([]byte{fill}, 6)...)
+}
+
+// testFontTables builds the tables of a minimal TrueType font with 2048
+// units per em and short loca offsets
+func testFontTables() map[string][]byte {
+	u16 := func(b []byte, v int) { binary.BigEndian.PutUint16(b, uint16(v)) }
+
+	head := make([]byte, 54)
+	binary.BigEndian.PutUint32(head, 0x00010000)
+	u16(head[18:], 2048)
+	u16(head[40:], 2048) // xMax
+	u16(head[42:], 1800) // yMax
+
+	hhea := make([]byte, 36)
+	u16(hhea[4:], 1800)
+	u16(hhea[6:], -400)
+	u16(hhea[34:], len(testGlyphs))
+
+	maxp := make([]byte, 6)
+	binary.BigEndian.PutUint32(maxp, 0x00005000)
+	u16(maxp[4:], len(testGlyphs))
+
+	hmtx := make([]byte, 4*len(testGlyphs))
+	for i, adv := range testAdvances {
+		u16(hmtx[4*i:], int(adv)*2048/1000)
+	}
+
+	var glyf []byte
+	loca := make([]byte, 2*(len(testGlyphs)+1))
+	for i, g := range testGlyphs {
+		u16(loca[2*i:], len(glyf)/2)
+		glyf = append(glyf, g...)
+	}
+	u16(loca[2*len(testGlyphs):], len(glyf)/2)
+
+	// cmap with a format 4 subtable of one segment per rune, in code order
+	codes := []rune{'中', '文', '龘', 0xffff}
+	segs := len(codes)
+	sub := make([]byte, 16+8*segs)
+	u16(sub, 4)
+	u16(sub[2:], len(sub))
+	u16(sub[6:], 2*segs)
+	for i, c := range codes {
+		u16(sub[14+2*i:], int(c))
+		u16(sub[16+2*segs+2*i:], int(c))
+		delta := 1
+		if c != 0xffff {
+			delta = int(testRunes[c]) - int(c)
+		}
+		u16(sub[16+4*segs+2*i:], delta)
+	}
+	cmap := make([]byte, 12)
+	u16(cmap[2:], 1)
+	u16(cmap[4:], 3)
+	u16(cmap[6:], 1)
+	binary.BigEndian.PutUint32(cmap[8:], 12)
+	cmap = append(cmap, sub...)
+
+	return map[string][]byte{
+		"head": head, "hhea": hhea, "maxp": maxp, "hmtx": hmtx,
+		"loca": loca, "glyf": glyf, "cmap": cmap,
+		"prep": {0xb0, 0x01},
+	}
+}
+
+func testFont(t *testing.T) *ttf {
+	t.Helper()
+	f, err := parseTTF(writeTTF(testFontTables()))
+	if err != nil {
+		t.Fatalf("parseTTF: %v", err)
+	}
+	return f
+}
+
+// readTables splits a font file into its tables
+func readTables(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+	tables := map[string][]byte{}
+	n := int(binary.BigEndian.Uint16(data[4:]))
+	for i := 0; i < n; i++ {
+		rec := data[12+16*i:]
+		start, length := binary.BigEndian.Uint32(rec[8:]), binary.BigEndian.Uint32(rec[12:])
+		if int(start+length) > len(data) {
+			t.Fatalf("table %q exceeds the file", rec[:4])
+		}
+		table := data[start : start+length]
+		if got, want := checksum(table), binary.BigEndian.Uint32(rec[4:]); string(rec[:4]) != "head" && got != want {
+			t.Errorf("checksum of %q = %#x, want %#x", rec[:4], got, want)
+		}
+		tables[string(rec[:4])] = table
+	}
+	return tables
+}
+
+func TestParseTTF(t *testing.T) {
+	f := testFont(t)
+
+	if f.numGlyphs != len(testGlyphs) || f.unitsPerEm != 2048 || f.longLoca {
+		t.Errorf("numGlyphs, unitsPerEm, longLoca = %d, %d, %v", f.numGlyphs, f.unitsPerEm, f.longLoca)
+	}
+	for r, want := range testRunes {
+		if got := f.glyph(r); got != want {
+			t.Errorf("glyph(%q) = %d, want %d", r, got, want)
+		}
+	}
+	if got := f.glyph('A'); got != 0 {
+		t.Errorf("glyph('A') = %d, want .notdef", got)
+	}
+	for gid, want := range testAdvances {
+		if got := f.advance(uint16(gid)); got != int(want) {
+			t.Errorf("advance(%d) = %d, want %d", gid, got, want)
+		}
+	}
+	if got := components(f.glyphData(gidWen)); len(got) != 1 || got[0] != gidComponent {
+		t.Errorf("components of the composite = %v, want [%d]", got, gidComponent)
+	}
+}
+
+func TestParseTTFRejectsCFF(t *testing.T) {
+	data := writeTTF(testFontTables())
+	binary.BigEndian.PutUint32(data, 0x4f54544f)
+	if _, err := parseTTF(data); err == nil {
+		t.Error("parseTTF accepted a CFF font")
+	}
+	if _, err := parseTTF(data[:8]); err == nil {
+		t.Error("parseTTF accepted truncated data")
+	}
+}
+
+func TestSubsetRoundTrip(t *testing.T) {
+	f := testFont(t)
+	data := f.subset(map[uint16]bool{gidZhong: true, gidWen: true})
+
+	var sum uint32
+	for i := 0; i < len(data); i += 4 {
+		sum += binary.BigEndian.Uint32(data[i:])
+	}
+	if sum != 0xb1b0afba {
+		t.Errorf("font checksum = %#x, want 0xb1b0afba", sum)
+	}
+
+	tables := readTables(t, data)
+	if _, ok := tables["cmap"]; ok {
+		t.Error("subset keeps the cmap, glyphs are addressed by ID")
+	}
+	if !bytes.Equal(tables["prep"], []byte{0xb0, 0x01}) {
+		t.Error("subset dropped the hinting program")
+	}
+	if !bytes.Equal(tables["hmtx"], f.tables["hmtx"]) {
+		t.Error("subset changed the metrics")
+	}
+
+	tables["cmap"] = f.tables["cmap"]
+	s, err := parseTTF(writeTTF(tables))
+	if err != nil {
+		t.Fatalf("parse subset: %v", err)
+	}
+	if !s.longLoca || s.numGlyphs != f.numGlyphs {
+		t.Errorf("longLoca, numGlyphs = %v, %d; want true, %d", s.longLoca, s.numGlyphs, f.numGlyphs)
+	}
+	// .notdef and the component of the composite are kept with the used glyphs
+	for gid := range testGlyphs {
+		got := s.glyphData(uint16(gid))
+		if gid == gidUnused {
+			if got != nil {
+				t.Errorf("unused glyph %d kept", gid)
+			}
+			continue
+		}
+		if !bytes.Equal(got, testGlyphs[gid]) {
+			t.Errorf("glyph %d = %x, want %x", gid, got, testGlyphs[gid])
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package export
+
+import (
+	"strings"
+)
+
+// Markdown renders a report as a Markdown document
+func Markdown(r *Report) string {
+	var b strings.Builder
+	for i, bl := range document(r) {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		switch bl.kind {
+		case blockHeading:
+			b.WriteString(strings.Repeat("#", bl.level) + " " + mdEscape(bl.text) + "\n")
+		case blockParagraph:
+			b.WriteString(mdEscape(bl.text) + "\n")
+		case blockList:
+			for _, item := range bl.items {
+				b.WriteString("- " + mdEscape(item) + "\n")
+			}
+		case blockTable:
+			for j, row := range bl.rows {
+				cells := make([]string, len(row))
+				for k, cell := range row {
+					cells[k] = strings.ReplaceAll(mdEscape(cell), "|", `\|`)
+				}
+				b.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+				if j == 0 {
+					b.WriteString(strings.Repeat("| --- ", len(row)) + "|\n")
+				}
+			}
+		}
+	}
+	return b.String()
+}
+
+// mdEscape keeps model text on one line and stops it from being read as
+// Markdown markup
+func mdEscape(s string) string {
+	s = strings.Join(strings.Fields(strings.ReplaceAll(s, "\r", "")), " ")
+	return strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "#", `\#`, "<", "&lt;").Replace(s)
+}
@@ -0,0 +1,587 @@
+package export
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf16"
+)
+
+// A4 page in points and the layout of the text block
+const (
+	pageWidth    = 595.28
+	pageHeight   = 841.89
+	marginX      = 56.7
+	marginTop    = 62
+	marginBottom = 62
+	textWidth    = pageWidth - 2*marginX
+	lineSpacing  = 1.6
+	bodySize     = 10.5
+	tableSize    = 9.5
+	cellPadding  = 4
+)
+
+// headingSizes are the font sizes of heading levels 1 to 3
+var headingSizes = [...]float64{0, 18, 14, 12}
+
+var (
+	fontOnce sync.Once
+	font     *ttf
+	fontErr  error
+)
+
+// reportFont loads the TrueType font at PDF_FONT_PATH once per container.
+// Without it PDFs use the STSong-Light font that PDF readers provide for
+// Simplified Chinese, which is not embedded.
+func reportFont() (*ttf, error) {
+	fontOnce.Do(func() {
+		if path := os.Getenv("PDF_FONT_PATH"); path != "" {
+			font, fontErr = loadTTF(path)
+		}
+	})
+	return font, fontErr
+}
+
+// PDF renders a report as an A4 PDF document. The glyphs used are embedded
+// as a font subset when PDF_FONT_PATH is set.
+func PDF(r *Report) ([]byte, error) {
+	f, err := reportFont()
+	if err != nil {
+		return nil, err
+	}
+	return renderPDF(r, f)
+}
+
+// renderPDF renders a report with an embedded font, or STSong-Light when f
+// is nil
+func renderPDF(r *Report, f *ttf) ([]byte, error) {
+	p := &pdfLayout{font: &pdfFont{ttf: f, used: map[uint16]rune{}}}
+	p.newPage()
+	for _, bl := range document(r) {
+		switch bl.kind {
+		case blockHeading:
+			p.heading(bl.level, bl.text)
+		case blockParagraph:
+			p.paragraph(bl.text)
+		case blockList:
+			p.list(bl.items)
+		case blockTable:
+			p.table(bl.rows)
+		}
+	}
+	return p.render(r.BusinessGoal, r.UpdatedAt)
+}
+
+// pdfFont encodes text as two byte codes of a Type0 font: glyph IDs with an
+// embedded font, UCS-2 codes with STSong-Light
+type pdfFont struct {
+	ttf  *ttf
+	used map[uint16]rune // embedded glyphs and the first rune drawn with each
+}
+
+// width returns the advance width of a rune in 1/1000 em
+func (f *pdfFont) width(r rune) float64 {
+	if f.ttf != nil {
+		return float64(f.ttf.advance(f.ttf.glyph(r)))
+	}
+	if r >= 0x20 && r <= 0x7e {
+		return 500
+	}
+	return 1000
+}
+
+// measure returns the width of s in points at the given size
+func (f *pdfFont) measure(s string, size float64) float64 {
+	var w float64
+	for _, r := range s {
+		w += f.width(r)
+	}
+	return w * size / 1000
+}
+
+// encode returns s as a hex string operand and records the glyphs used
+func (f *pdfFont) encode(s string) string {
+	var b strings.Builder
+	b.WriteString("<")
+	for _, r := range s {
+		var code uint16
+		if f.ttf != nil {
+			code = f.ttf.glyph(r)
+			if _, ok := f.used[code]; !ok {
+				f.used[code] = r
+			}
+		} else if r <= 0xffff {
+			code = uint16(r)
+		} else {
+			code = '?'
+		}
+		fmt.Fprintf(&b, "%04X", code)
+	}
+	b.WriteString(">")
+	return b.String()
+}
+
+// pdfLayout places blocks on pages top to bottom
+type pdfLayout struct {
+	font  *pdfFont
+	pages []*bytes.Buffer
+	page  *bytes.Buffer
+	y     float64 // top of the next line
+}
+
+func (p *pdfLayout) newPage() {
+	p.page = &bytes.Buffer{}
+	p.pages = append(p.pages, p.page)
+	p.y = pageHeight - marginTop
+}
+
+// ensure starts a new page unless height points fit on the current one
+func (p *pdfLayout) ensure(height float64) {
+	if p.y-height < marginBottom && p.y < pageHeight-marginTop {
+		p.newPage()
+	}
+}
+
+// text draws one line with its baseline at y. Bold is simulated by stroking
+// the glyph outlines.
+func (p *pdfLayout) text(x, y, size float64, bold bool, s string) {
+	if s == "" {
+		return
+	}
+	if bold {
+		fmt.Fprintf(p.page, "q 2 Tr %s w BT /F1 %s Tf %s %s Td %s Tj ET Q\n",
+			num(size*0.03), num(size), num(x), num(y), p.font.encode(s))
+		return
+	}
+	fmt.Fprintf(p.page, "BT /F1 %s Tf %s %s Td %s Tj ET\n", num(size), num(x), num(y), p.font.encode(s))
+}
+
+// lines draws wrapped text from the current position
+func (p *pdfLayout) lines(x, width, size float64, bold bool, s string) {
+	lineHeight := size * lineSpacing
+	for _, line := range p.wrap(s, size, width) {
+		p.ensure(lineHeight)
+		p.y -= lineHeight
+		p.text(x, p.y+(lineHeight-size)/2+size*0.15, size, bold, line)
+	}
+}
+
+func (p *pdfLayout) heading(level int, s string) {
+	size := headingSizes[level]
+	space := size * 0.8
+	// Keep a heading on the page of the text that follows it
+	p.ensure(space + size*lineSpacing + 3*bodySize*lineSpacing)
+	if p.y < pageHeight-marginTop {
+		p.y -= space
+	}
+	p.lines(marginX, textWidth, size, true, s)
+	if level == 1 {
+		p.y -= 4
+		fmt.Fprintf(p.page, "q 0.6 G 0.8 w %s %s m %s %s l S Q\n", num(marginX), num(p.y), num(marginX+textWidth), num(p.y))
+		p.y -= 4
+	}
+}
+
+func (p *pdfLayout) paragraph(s string) {
+	p.lines(marginX, textWidth, bodySize, false, s)
+	p.y -= bodySize * 0.5
+}
+
+func (p *pdfLayout) list(items []string) {
+	indent := bodySize * 1.4
+	lineHeight := bodySize * lineSpacing
+	for _, item := range items {
+		p.ensure(lineHeight)
+		// The bullet is drawn as a square so it does not depend on the font
+		bullet := bodySize * 0.3
+		top := p.y - (lineHeight-bodySize)/2 - bodySize*0.5
+		fmt.Fprintf(p.page, "%s %s %s %s re f\n", num(marginX+bodySize*0.4), num(top-bullet/2), num(bullet), num(bullet))
+		p.lines(marginX+indent, textWidth-indent, bodySize, false, item)
+	}
+	p.y -= bodySize * 0.5
+}
+
+// table draws a table with borders. Column widths follow the widest cell of
+// each column; the header row is repeated on every page the table spans.
+func (p *pdfLayout) table(rows [][]string) {
+	if len(rows) == 0 {
+		return
+	}
+	cols := len(rows[0])
+	natural := make([]float64, cols)
+	var total float64
+	for c := range natural {
+		for _, row := range rows {
+			if c < len(row) {
+				natural[c] = max(natural[c], p.font.measure(row[c], tableSize)+2*cellPadding)
+			}
+		}
+		natural[c] = min(natural[c], textWidth/2)
+		total += natural[c]
+	}
+	widths := make([]float64, cols)
+	for c := range widths {
+		widths[c] = natural[c] * textWidth / total
+	}
+
+	lineHeight := tableSize * lineSpacing
+	height := func(row []string) float64 {
+		n := 1
+		for c, cell := range row {
+			n = max(n, len(p.wrap(cell, tableSize, widths[c]-2*cellPadding)))
+		}
+		return float64(n)*lineHeight + 2*cellPadding
+	}
+	drawRow := func(row []string, header bool) {
+		h := height(row)
+		x := marginX
+		bottom := p.y - h
+		for c, cell := range row {
+			if header {
+				fmt.Fprintf(p.page, "q 0.93 g %s %s %s %s re f Q\n", num(x), num(bottom), num(widths[c]), num(h))
+			}
+			fmt.Fprintf(p.page, "q 0.5 w 0.4 G %s %s %s %s re S Q\n", num(x), num(bottom), num(widths[c]), num(h))
+			y := p.y - cellPadding
+			for _, line := range p.wrap(cell, tableSize, widths[c]-2*cellPadding) {
+				y -= lineHeight
+				p.text(x+cellPadding, y+(lineHeight-tableSize)/2+tableSize*0.15, tableSize, header, line)
+			}
+			x += widths[c]
+		}
+		p.y = bottom
+	}
+
+	p.ensure(height(rows[0]) + min(height(rows[min(1, len(rows)-1)]), 100))
+	drawRow(rows[0], true)
+	for _, row := range rows[1:] {
+		h := height(row)
+		if p.y-h < marginBottom {
+			p.newPage()
+			drawRow(rows[0], true)
+		}
+		drawRow(row, false)
+	}
+	p.y -= bodySize
+}
+
+// wrap breaks text into lines no wider than width. Lines break between
+// Chinese characters and at spaces between words; words longer than a line
+// are broken anywhere.
+func (p *pdfLayout) wrap(s string, size, width float64) []string {
+	var lines []string
+	for _, para := range strings.Split(strings.ReplaceAll(s, "\r", ""), "\n") {
+		var line strings.Builder
+		var lineWidth float64
+		flush := func() {
+			lines = append(lines, strings.TrimRight(line.String(), " "))
+			line.Reset()
+			lineWidth = 0
+		}
+		for _, tok := range tokens(para) {
+			w := p.font.measure(tok, size)
+			if lineWidth+w > width && line.Len() > 0 {
+				flush()
+				if tok == " " {
+					continue
+				}
+			}
+			if w <= width {
+				line.WriteString(tok)
+				lineWidth += w
+				continue
+			}
+			for _, r := range tok {
+				rw := p.font.measure(string(r), size)
+				if lineWidth+rw > width && line.Len() > 0 {
+					flush()
+				}
+				line.WriteRune(r)
+				lineWidth += rw
+			}
+		}
+		flush()
+	}
+	return lines
+}
+
+// tokens splits text into the units kept on one line: words of Latin text,
+// single spaces and single Chinese characters. Closing punctuation sticks to
+// the previous token so that no line starts with it.
+func tokens(s string) []string {
+	var toks []string
+	var word strings.Builder
+	endWord := func() {
+		if word.Len() > 0 {
+			toks = append(toks, word.String())
+			word.Reset()
+		}
+	}
+	for _, r := range s {
+		switch {
+		case unicode.IsSpace(r):
+			endWord()
+			toks = append(toks, " ")
+		case !unicode.IsPrint(r):
+		case strings.ContainsRune("，。、；：？！）》」』】", r) && (word.Len() > 0 || len(toks) > 0):
+			if word.Len() > 0 {
+				word.WriteRune(r)
+			} else {
+				toks[len(toks)-1] += string(r)
+			}
+		case r < 0x2e80:
+			word.WriteRune(r)
+		default:
+			endWord()
+			toks = append(toks, string(r))
+		}
+	}
+	endWord()
+	return toks
+}
+
+// render writes the pages, page numbers and font into a PDF file
+func (p *pdfLayout) render(title string, modified time.Time) ([]byte, error) {
+	for i, page := range p.pages {
+		p.page = page
+		label := fmt.Sprintf("%d / %d", i+1, len(p.pages))
+		p.text((pageWidth-p.font.measure(label, 9))/2, marginBottom/2, 9, false, label)
+	}
+
+	w := &pdfWriter{}
+	catalog, pages, fontRef := w.reserve(), w.reserve(), w.reserve()
+
+	var kids []string
+	for _, page := range p.pages {
+		content, err := w.stream("", page.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		ref := w.add(fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 %d 0 R >> >> /Contents %d 0 R >>",
+			pages, num(pageWidth), num(pageHeight), fontRef, content))
+		kids = append(kids, fmt.Sprintf("%d 0 R", ref))
+	}
+	w.set(catalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pages))
+	w.set(pages, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids)))
+
+	if err := p.font.write(w, fontRef); err != nil {
+		return nil, err
+	}
+
+	info := w.add(fmt.Sprintf("<< /Title %s /ModDate (D:%s) /CreationDate (D:%s) >>",
+		pdfString(title), modified.UTC().Format("20060102150405Z"), time.Now().UTC().Format("20060102150405Z")))
+	return w.bytes(catalog, info), nil
+}
+
+// write adds the font objects, with fontRef as the Type0 font
+func (f *pdfFont) write(w *pdfWriter, fontRef int) error {
+	if f.ttf == nil {
+		descriptor := w.add("<< /Type /FontDescriptor /FontName /STSong-Light /Flags 6 /FontBBox [-25 -254 1000 880] /ItalicAngle 0 /Ascent 880 /Descent -120 /CapHeight 880 /StemV 93 >>")
+		cidFont := w.add(fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType0 /BaseFont /STSong-Light /CIDSystemInfo << /Registry (Adobe) /Ordering (GB1) /Supplement 2 >> /FontDescriptor %d 0 R /DW 1000 /W [1 95 500] >>", descriptor))
+		w.set(fontRef, fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /STSong-Light /Encoding /UniGB-UCS2-H /DescendantFonts [%d 0 R] >>", cidFont))
+		return nil
+	}
+
+	gids := make([]int, 0, len(f.used))
+	for gid := range f.used {
+		gids = append(gids, int(gid))
+	}
+	sort.Ints(gids)
+
+	// Subset fonts are named with a tag derived from the glyphs they contain
+	sum := sha256.Sum256(fmt.Append(nil, gids))
+	var tag [6]byte
+	for i := range tag {
+		tag[i] = 'A' + sum[i]%26
+	}
+	name := string(tag[:]) + "+" + f.ttf.postScriptName()
+
+	used := map[uint16]bool{}
+	for gid := range f.used {
+		used[gid] = true
+	}
+	program := f.ttf.subset(used)
+	fontFile, err := w.stream(fmt.Sprintf("/Length1 %d", len(program)), program)
+	if err != nil {
+		return err
+	}
+
+	scale := func(v int) string { return strconv.Itoa(v * 1000 / f.ttf.unitsPerEm) }
+	descriptor := w.add(fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 4 /FontBBox [%s %s %s %s] /ItalicAngle 0 /Ascent %s /Descent %s /CapHeight %s /StemV 80 /FontFile2 %d 0 R >>",
+		name, scale(f.ttf.bbox[0]), scale(f.ttf.bbox[1]), scale(f.ttf.bbox[2]), scale(f.ttf.bbox[3]),
+		scale(f.ttf.ascent), scale(f.ttf.descent), scale(f.ttf.ascent), fontFile))
+
+	var widths strings.Builder
+	for _, gid := range gids {
+		fmt.Fprintf(&widths, "%d [%d] ", gid, f.ttf.advance(uint16(gid)))
+	}
+	cidFont := w.add(fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor %d 0 R /DW 1000 /W [%s] /CIDToGIDMap /Identity >>",
+		name, descriptor, strings.TrimSpace(widths.String())))
+
+	toUnicode, err := w.stream("", f.toUnicode(gids))
+	if err != nil {
+		return err
+	}
+	w.set(fontRef, fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>",
+		name, cidFont, toUnicode))
+	return nil
+}
+
+// toUnicode returns the CMap mapping the glyphs back to text, used when
+// text is copied or searched
+func (f *pdfFont) toUnicode(gids []int) []byte {
+	var b bytes.Buffer
+	b.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n" +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n" +
+		"/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n" +
+		"1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	var mapped []int
+	for _, gid := range gids {
+		if gid != 0 {
+			mapped = append(mapped, gid)
+		}
+	}
+	// bfchar sections hold at most 100 entries
+	for start := 0; start < len(mapped); start += 100 {
+		chunk := mapped[start:min(start+100, len(mapped))]
+		fmt.Fprintf(&b, "%d beginbfchar\n", len(chunk))
+		for _, gid := range chunk {
+			fmt.Fprintf(&b, "<%04X> <", gid)
+			for _, u := range utf16.Encode([]rune{f.used[uint16(gid)]}) {
+				fmt.Fprintf(&b, "%04X", u)
+			}
+			b.WriteString(">\n")
+		}
+		b.WriteString("endbfchar\n")
+	}
+	b.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	return b.Bytes()
+}
+
+// postScriptName returns the PostScript name of the font from its name
+// table, reduced to the characters allowed in a PDF name
+func (f *ttf) postScriptName() string {
+	name := ""
+	if t := f.tables["name"]; len(t) >= 6 {
+		count := int(binary.BigEndian.Uint16(t[2:]))
+		storage := int(binary.BigEndian.Uint16(t[4:]))
+		for i := 0; i < count && 6+12*i+12 <= len(t) && name == ""; i++ {
+			rec := t[6+12*i:]
+			platform := binary.BigEndian.Uint16(rec)
+			nameID := binary.BigEndian.Uint16(rec[6:])
+			length := int(binary.BigEndian.Uint16(rec[8:]))
+			offset := storage + int(binary.BigEndian.Uint16(rec[10:]))
+			if nameID != 6 || offset+length > len(t) {
+				continue
+			}
+			raw := t[offset : offset+length]
+			switch platform {
+			case 0, 3:
+				units := make([]uint16, len(raw)/2)
+				for j := range units {
+					units[j] = binary.BigEndian.Uint16(raw[2*j:])
+				}
+				name = string(utf16.Decode(units))
+			case 1:
+				name = string(raw)
+			}
+		}
+	}
+	name = strings.Map(func(r rune) rune {
+		if r < 0x7f && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-') {
+			return r
+		}
+		return -1
+	}, name)
+	if name == "" {
+		return "ReportFont"
+	}
+	return name
+}
+
+// pdfWriter collects numbered objects and writes them with the
+// cross-reference table
+type pdfWriter struct {
+	objects [][]byte
+}
+
+// reserve allocates an object number to be set later
+func (w *pdfWriter) reserve() int {
+	w.objects = append(w.objects, nil)
+	return len(w.objects)
+}
+
+func (w *pdfWriter) set(ref int, body string) {
+	w.objects[ref-1] = []byte(body)
+}
+
+func (w *pdfWriter) add(body string) int {
+	ref := w.reserve()
+	w.set(ref, body)
+	return ref
+}
+
+// stream adds a Flate compressed stream; entries are added to its dictionary
+func (w *pdfWriter) stream(entries string, data []byte) (int, error) {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return 0, fmt.Errorf("failed to compress stream: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		return 0, fmt.Errorf("failed to compress stream: %v", err)
+	}
+	if entries != "" {
+		entries = " " + entries
+	}
+	body := fmt.Sprintf("<< /Length %d /Filter /FlateDecode%s >>\nstream\n", buf.Len(), entries)
+	ref := w.reserve()
+	w.objects[ref-1] = append(append([]byte(body), buf.Bytes()...), "\nendstream"...)
+	return ref, nil
+}
+
+func (w *pdfWriter) bytes(root, info int) []byte {
+	var out bytes.Buffer
+	// The binary comment marks the file as binary for transfer programs
+	out.WriteString("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(w.objects))
+	for i, body := range w.objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n", i+1)
+		out.Write(body)
+		out.WriteString("\nendobj\n")
+	}
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(w.objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(w.objects)+1, root, info, xref)
+	return out.Bytes()
+}
+
+// pdfString encodes text as a UTF-16 hex string
+func pdfString(s string) string {
+	var b strings.Builder
+	b.WriteString("<FEFF")
+	for _, u := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&b, "%04X", u)
+	}
+	b.WriteString(">")
+	return b.String()
+}
+
+// num formats a coordinate with at most two decimals
+func num(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 2, 64)
+	return strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
+}
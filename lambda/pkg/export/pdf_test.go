@@ -0,0 +1,177 @@
+package export
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/x-zero/business-consultant/pkg/report"
+)
+
+func testReport() *Report {
+	updated := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	return &Report{
+		ReportID:     "7d7a8f52-0b7e-4c55-9d8b-2f1e3c4b5a69",
+		BusinessGoal: "中文",
+		Version:      3,
+		CreatedAt:    updated.Add(-time.Hour),
+		UpdatedAt:    updated,
+		Recs: &report.Recommendations{
+			Summary: strings.Repeat("中文", 3000),
+			Phases:  []report.Phase{{PhaseName: "文", Duration: "3个月", MonthlyBudget: 1000}},
+		},
+	}
+}
+
+// pdfObjects checks the cross-reference table of a PDF file and returns its
+// objects by number
+func pdfObjects(t *testing.T, data []byte) map[int][]byte {
+	t.Helper()
+	if !bytes.HasPrefix(data, []byte("%PDF-1.7\n")) || !bytes.HasSuffix(data, []byte("%%EOF\n")) {
+		t.Fatal("missing PDF header or trailer")
+	}
+	m := regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`).FindSubmatch(data)
+	if m == nil {
+		t.Fatal("missing startxref")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(data[xref:], []byte("xref\n0 ")) {
+		t.Fatalf("startxref %d does not point to the xref table", xref)
+	}
+
+	var n int
+	if _, err := fmt.Sscanf(string(data[xref:]), "xref\n0 %d\n", &n); err != nil {
+		t.Fatalf("read xref: %v", err)
+	}
+	entries := data[bytes.IndexByte(data[xref+5:], '\n')+xref+6:]
+	objects := map[int][]byte{}
+	for i := 1; i < n; i++ {
+		entry := string(entries[20*i : 20*i+20])
+		offset, err := strconv.Atoi(entry[:10])
+		if err != nil || !strings.HasSuffix(entry, " n \n") {
+			t.Fatalf("invalid xref entry %d: %q", i, entry)
+		}
+		header := fmt.Sprintf("%d 0 obj\n", i)
+		if !bytes.HasPrefix(data[offset:], []byte(header)) {
+			t.Fatalf("xref entry %d points to %q", i, data[offset:offset+len(header)])
+		}
+		body := data[offset+len(header):]
+		objects[i] = body[:bytes.Index(body, []byte("\nendobj\n"))]
+	}
+	return objects
+}
+
+// streamData inflates a stream object
+func streamData(t *testing.T, obj []byte) []byte {
+	t.Helper()
+	m := regexp.MustCompile(`^<< /Length (\d+) /Filter /FlateDecode[^>]*>>\nstream\n`).FindSubmatch(obj)
+	if m == nil {
+		t.Fatalf("not a Flate stream: %.60q", obj)
+	}
+	length, _ := strconv.Atoi(string(m[1]))
+	raw := obj[len(m[0]):]
+	if len(raw) != length+len("\nendstream") {
+		t.Fatalf("stream length %d, want %d", len(raw)-len("\nendstream"), length)
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(raw[:length]))
+	if err != nil {
+		t.Fatalf("inflate: %v", err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("inflate: %v", err)
+	}
+	return data
+}
+
+// ref returns the object number of an indirect reference in a dictionary
+func ref(t *testing.T, obj []byte, key string) int {
+	t.Helper()
+	m := regexp.MustCompile(`/` + key + ` ?(\d+) 0 R`).FindSubmatch(obj)
+	if m == nil {
+		t.Fatalf("missing /%s in %.80q", key, obj)
+	}
+	n, _ := strconv.Atoi(string(m[1]))
+	return n
+}
+
+func TestPDFWithoutFont(t *testing.T) {
+	data, err := renderPDF(testReport(), nil)
+	if err != nil {
+		t.Fatalf("renderPDF: %v", err)
+	}
+	objects := pdfObjects(t, data)
+
+	pages := objects[2]
+	if !bytes.Contains(pages, []byte("/Type /Pages")) || bytes.Contains(pages, []byte("/Count 1 ")) {
+		t.Errorf("long summary should span several pages: %q", pages)
+	}
+	font := objects[3]
+	if !bytes.Contains(font, []byte("/BaseFont /STSong-Light /Encoding /UniGB-UCS2-H")) {
+		t.Errorf("font = %q", font)
+	}
+	for _, obj := range objects {
+		if bytes.Contains(obj, []byte("/FontFile2")) {
+			t.Error("font embedded without PDF_FONT_PATH")
+		}
+	}
+
+	// Text is encoded as UCS-2
+	page := objects[ref(t, objects[2], "Kids \\[")]
+	if content := streamData(t, objects[ref(t, page, "Contents")]); !bytes.Contains(content, []byte("<4E2D6587> Tj")) {
+		t.Errorf("first page does not show the title: %q", content)
+	}
+}
+
+func TestPDFEmbedsFontSubset(t *testing.T) {
+	f := testFont(t)
+	data, err := renderPDF(testReport(), f)
+	if err != nil {
+		t.Fatalf("renderPDF: %v", err)
+	}
+	objects := pdfObjects(t, data)
+
+	font := objects[3]
+	if !regexp.MustCompile(`^<< /Type /Font /Subtype /Type0 /BaseFont /[A-Z]{6}\+ReportFont /Encoding /Identity-H `).Match(font) {
+		t.Errorf("font = %q", font)
+	}
+	cidFont := objects[ref(t, font, "DescendantFonts \\[")]
+	if !bytes.Contains(cidFont, []byte("/Subtype /CIDFontType2")) || !bytes.Contains(cidFont, []byte("/CIDToGIDMap /Identity")) {
+		t.Errorf("CID font = %q", cidFont)
+	}
+	if !bytes.Contains(cidFont, []byte(fmt.Sprintf("%d [1000]", gidZhong))) {
+		t.Errorf("CID font lacks the width of 中: %q", cidFont)
+	}
+
+	// Text is encoded as glyph IDs
+	page := objects[ref(t, objects[2], "Kids \\[")]
+	if content := streamData(t, objects[ref(t, page, "Contents")]); !bytes.Contains(content, []byte("<00010002> Tj")) {
+		t.Errorf("first page does not show the title: %q", content)
+	}
+
+	cmap := streamData(t, objects[ref(t, font, "ToUnicode")])
+	if !bytes.Contains(cmap, []byte("<0001> <4E2D>")) || !bytes.Contains(cmap, []byte("<0002> <6587>")) {
+		t.Errorf("ToUnicode = %q", cmap)
+	}
+
+	descriptor := objects[ref(t, cidFont, "FontDescriptor")]
+	program := objects[ref(t, descriptor, "FontFile2")]
+	tables := readTables(t, streamData(t, program))
+	tables["cmap"] = f.tables["cmap"]
+	s, err := parseTTF(writeTTF(tables))
+	if err != nil {
+		t.Fatalf("parse embedded font: %v", err)
+	}
+	if s.glyphData(gidZhong) == nil || s.glyphData(gidComponent) == nil {
+		t.Error("embedded font lacks used glyphs")
+	}
+	if s.glyphData(gidUnused) != nil {
+		t.Error("embedded font keeps an unused glyph")
+	}
+}
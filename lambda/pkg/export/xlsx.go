@@ -0,0 +1,395 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/x-zero/business-consultant/pkg/budget"
+)
+
+// maxMonthRows caps the monthly rows of a phase sheet
+const maxMonthRows = 120
+
+// Sheet names of the XLSX export besides the phase sheets
+const (
+	sheetSummary = "汇总"
+	sheetCosts   = "岗位与工具"
+)
+
+// Cell styles, indexes into cellXfs of xlsxStyles
+const (
+	styleText = iota
+	styleBold
+	styleMoney
+	styleHeader
+	styleTotal
+)
+
+// xlsxCell is a text, number or formula cell
+type xlsxCell struct {
+	text    string
+	number  float64
+	formula string
+	isText  bool
+	style   int
+}
+
+func textCell(s string, style int) xlsxCell    { return xlsxCell{text: s, isText: true, style: style} }
+func numberCell(v float64, style int) xlsxCell { return xlsxCell{number: v, style: style} }
+func formulaCell(f string, style int) xlsxCell { return xlsxCell{formula: f, style: style} }
+func headerCells(names ...string) []xlsxCell {
+	row := make([]xlsxCell, len(names))
+	for i, name := range names {
+		row[i] = textCell(name, styleHeader)
+	}
+	return row
+}
+
+// xlsxSheet is a worksheet; rows are numbered from 1 as in Excel
+type xlsxSheet struct {
+	name   string
+	rows   [][]xlsxCell
+	widths []float64
+}
+
+// add appends a row and returns its number
+func (s *xlsxSheet) add(cells ...xlsxCell) int {
+	s.rows = append(s.rows, cells)
+	return len(s.rows)
+}
+
+// phaseSheet records where a phase sheet keeps the cells the summary
+// refers to
+type phaseSheet struct {
+	monthsCell string
+	planCell   string
+	totalCell  string
+}
+
+// XLSX renders the budget of a report as a workbook: a summary sheet, the
+// monthly cost of every role and workflow, and one sheet per phase with a row
+// per month and a column per budget category. Totals are formulas, so the
+// workbook stays consistent when amounts are edited.
+func XLSX(r *Report) ([]byte, error) {
+	costs, required := costSheet(r)
+
+	names := map[string]bool{sheetSummary: true, sheetCosts: true}
+	var phases []*xlsxSheet
+	var refs []phaseSheet
+	for _, phase := range r.Recs.Phases {
+		name := sheetName(phase.PhaseName, names)
+		sheet, ref := phaseBudgetSheet(name, phase.PhaseName, phase.Duration, phase.MonthlyBudget, phase.BudgetBreakdown, required)
+		phases = append(phases, sheet)
+		refs = append(refs, ref)
+	}
+
+	sheets := append([]*xlsxSheet{summarySheet(r, refs, required)}, costs)
+	sheets = append(sheets, phases...)
+	return writeXLSX(sheets)
+}
+
+// costSheet lists the monthly costs of the roles and workflows. It returns
+// the sheet and a reference to the cell with their sum.
+func costSheet(r *Report) (*xlsxSheet, string) {
+	s := &xlsxSheet{name: sheetCosts, widths: []float64{12, 28, 16, 10, 12}}
+	s.add(headerCells("类型", "名称", "月度成本(XZT)", "优先级", "状态")...)
+	first := len(s.rows) + 1
+	for _, role := range r.Recs.HumanRoles {
+		s.add(textCell("真人岗位", styleText), textCell(role.Title, styleText), numberCell(role.MonthlyBudget, styleMoney),
+			textCell(PriorityLabel(role.Priority), styleText), textCell(StatusLabel(role.Status), styleText))
+	}
+	for _, wf := range r.Recs.AIWorkflows {
+		s.add(textCell("AI工作流", styleText), textCell(wf.Name, styleText), numberCell(wf.EstimatedCost, styleMoney),
+			textCell(PriorityLabel(wf.Priority), styleText), textCell(StatusLabel(wf.Status), styleText))
+	}
+	last := max(len(s.rows), first)
+
+	s.add()
+	lines := fmt.Sprintf("$A$%d:$A$%d", first, last)
+	amounts := fmt.Sprintf("$C$%d:$C$%d", first, last)
+	s.add(textCell("", styleText), textCell("月度人力成本", styleBold), formulaCell(fmt.Sprintf(`SUMIF(%s,"真人岗位",%s)`, lines, amounts), styleMoney))
+	s.add(textCell("", styleText), textCell("月度AI工具成本", styleBold), formulaCell(fmt.Sprintf(`SUMIF(%s,"AI工作流",%s)`, lines, amounts), styleMoney))
+	row := s.add(textCell("", styleText), textCell("月度所需合计", styleBold), formulaCell("SUM("+amounts+")", styleTotal))
+	return s, sheetRef(sheetCosts, fmt.Sprintf("$C$%d", row))
+}
+
+// phaseBudgetSheet lays out one phase: the monthly plan per category, a row
+// per month of the duration that refers to the plan, and the phase totals.
+// Each month is compared with the monthly cost of the roles and workflows.
+func phaseBudgetSheet(name, phaseName, duration string, monthlyBudget float64, breakdown map[string]float64, required string) (*xlsxSheet, phaseSheet) {
+	categories := Categories(breakdown)
+	amounts := make([]float64, len(categories))
+	for i, category := range categories {
+		amounts[i] = breakdown[category]
+	}
+	// Phases without a breakdown get a single column with the monthly budget
+	if len(categories) == 0 {
+		categories, amounts = []string{"月度预算"}, []float64{monthlyBudget}
+	}
+
+	n := len(categories)
+	totalCol, requiredCol := col(n+1), col(n+2)
+	s := &xlsxSheet{name: name, widths: []float64{14}}
+	for i := 0; i < n+3; i++ {
+		s.widths = append(s.widths, 14)
+	}
+
+	s.add(textCell(phaseName, styleBold))
+	s.add(textCell("时长", styleBold), textCell(duration, styleText))
+	months, known := budget.ParseDuration(duration)
+	var monthsRow int
+	if known {
+		monthsRow = s.add(textCell("月数", styleBold), numberCell(months, styleText))
+	} else {
+		monthsRow = s.add(textCell("月数", styleBold), textCell("", styleText), textCell("时长无法识别，请填写月数", styleText))
+	}
+	if sum := sumOf(amounts); len(breakdown) > 0 && math.Abs(sum-monthlyBudget) > 0.005 {
+		s.add(textCell("注意", styleBold), textCell(fmt.Sprintf("报告中的月度预算为 %s，与明细合计 %s 不一致", Money(monthlyBudget), Money(sum)), styleText))
+	}
+	s.add()
+
+	s.add(append(append(headerCells("月份"), headerCells(categories...)...), headerCells("合计", "月度所需", "结余")...)...)
+	plan := []xlsxCell{textCell("月度计划", styleBold)}
+	for _, amount := range amounts {
+		plan = append(plan, numberCell(amount, styleMoney))
+	}
+	planRow := len(s.rows) + 1
+	plan = append(plan,
+		formulaCell(fmt.Sprintf("SUM(B%d:%s%d)", planRow, col(n), planRow), styleTotal),
+		formulaCell(required, styleMoney),
+		formulaCell(fmt.Sprintf("%s%d-%s%d", totalCol, planRow, requiredCol, planRow), styleMoney))
+	s.add(plan...)
+
+	// Months follow the plan row, the last one prorated for durations such
+	// as 1.5 months. Phases of unknown duration have no month rows; their
+	// totals multiply the plan by the months filled in above.
+	var factors []float64
+	if known {
+		for m := 0.0; m < months && len(factors) < maxMonthRows; m++ {
+			factors = append(factors, math.Min(1, months-m))
+		}
+	}
+	first := len(s.rows) + 1
+	for i, factor := range factors {
+		row := first + i
+		cells := []xlsxCell{textCell(fmt.Sprintf("第%d月", i+1), styleText)}
+		scale := ""
+		if factor != 1 {
+			scale = "*" + strconv.FormatFloat(factor, 'f', -1, 64)
+		}
+		for c := 1; c <= n; c++ {
+			cells = append(cells, formulaCell(fmt.Sprintf("%s$%d%s", col(c), planRow, scale), styleMoney))
+		}
+		cells = append(cells,
+			formulaCell(fmt.Sprintf("SUM(B%d:%s%d)", row, col(n), row), styleTotal),
+			formulaCell(fmt.Sprintf("$%s$%d%s", requiredCol, planRow, scale), styleMoney),
+			formulaCell(fmt.Sprintf("%s%d-%s%d", totalCol, row, requiredCol, row), styleMoney))
+		s.add(cells...)
+	}
+	last := len(s.rows)
+
+	totals := []xlsxCell{textCell("阶段合计", styleBold)}
+	for c := 1; c <= n+3; c++ {
+		if len(factors) == 0 {
+			totals = append(totals, formulaCell(fmt.Sprintf("%s%d*$B$%d", col(c), planRow, monthsRow), styleTotal))
+		} else {
+			totals = append(totals, formulaCell(fmt.Sprintf("SUM(%s%d:%s%d)", col(c), first, col(c), last), styleTotal))
+		}
+	}
+	totalRow := s.add(totals...)
+	if len(factors) == maxMonthRows && months > maxMonthRows {
+		s.add(textCell(fmt.Sprintf("仅展开前 %d 个月", maxMonthRows), styleText))
+	}
+
+	return s, phaseSheet{
+		monthsCell: sheetRef(name, fmt.Sprintf("$B$%d", monthsRow)),
+		planCell:   sheetRef(name, fmt.Sprintf("$%s$%d", totalCol, planRow)),
+		totalCell:  sheetRef(name, fmt.Sprintf("$%s$%d", totalCol, totalRow)),
+	}
+}
+
+// summarySheet lists the phases with cells referring to their sheets and
+// the plan totals
+func summarySheet(r *Report, phases []phaseSheet, required string) *xlsxSheet {
+	s := &xlsxSheet{name: sheetSummary, widths: []float64{24, 16, 10, 16, 16}}
+	s.add(textCell(r.BusinessGoal, styleBold))
+	s.add(textCell("预算单位：XZT（1 XZT ≈ 1 CNY）", styleText))
+	s.add()
+	s.add(headerCells("阶段", "时长", "月数", "月度预算", "阶段总预算")...)
+	first := len(s.rows) + 1
+	for i, phase := range r.Recs.Phases {
+		ref := phases[i]
+		s.add(textCell(phase.PhaseName, styleText), textCell(phase.Duration, styleText),
+			formulaCell(ref.monthsCell, styleText), formulaCell(ref.planCell, styleMoney), formulaCell(ref.totalCell, styleMoney))
+	}
+	last := max(len(s.rows), first)
+
+	s.add()
+	monthsRow := s.add(textCell("总时长(月)", styleBold), textCell("", styleText), formulaCell(fmt.Sprintf("SUM(C%d:C%d)", first, last), styleTotal))
+	totalRow := s.add(textCell("总预算", styleBold), textCell("", styleText), textCell("", styleText), textCell("", styleText),
+		formulaCell(fmt.Sprintf("SUM(E%d:E%d)", first, last), styleTotal))
+	s.add(textCell("最高阶段月预算", styleBold), textCell("", styleText), textCell("", styleText), formulaCell(fmt.Sprintf("MAX(D%d:D%d)", first, last), styleMoney))
+	s.add(textCell("平均月支出", styleBold), textCell("", styleText), textCell("", styleText),
+		formulaCell(fmt.Sprintf("IF(C%d>0,E%d/C%d,0)", monthsRow, totalRow, monthsRow), styleMoney))
+	s.add(textCell("岗位与工具月度成本", styleBold), textCell("", styleText), textCell("", styleText), formulaCell(required, styleMoney))
+	return s
+}
+
+// sheetName makes a phase name a valid, unique sheet name: at most 31
+// characters, none of []:*?/\ and no leading or trailing apostrophe
+func sheetName(name string, used map[string]bool) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) || r < 0x20 {
+			return '_'
+		}
+		return r
+	}, name)
+	name = strings.Trim(strings.TrimSpace(name), "'")
+	if name == "" {
+		name = "阶段"
+	}
+	base := []rune(name)
+	for i := 1; ; i++ {
+		suffix := ""
+		if i > 1 {
+			suffix = fmt.Sprintf(" (%d)", i)
+		}
+		candidate := string(base[:min(len(base), 31-len(suffix))]) + suffix
+		if !used[strings.ToLower(candidate)] {
+			used[strings.ToLower(candidate)] = true
+			return candidate
+		}
+	}
+}
+
+// sheetRef refers to a cell of another sheet
+func sheetRef(sheet, cell string) string {
+	return "'" + strings.ReplaceAll(sheet, "'", "''") + "'!" + cell
+}
+
+// col returns the letters of a zero based column
+func col(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+func sumOf(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum
+}
+
+// writeXLSX packages the sheets. Formula cells are written without cached
+// values and the workbook asks for a full recalculation when opened.
+func writeXLSX(sheets []*xlsxSheet) ([]byte, error) {
+	var contentTypes, workbookSheets, workbookRels strings.Builder
+	parts := map[string]string{}
+	var order []string
+	for i, sheet := range sheets {
+		n := i + 1
+		part := fmt.Sprintf("xl/worksheets/sheet%d.xml", n)
+		parts[part] = sheetXML(sheet)
+		order = append(order, part)
+		fmt.Fprintf(&contentTypes, `<Override PartName="/%s" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, part)
+		fmt.Fprintf(&workbookSheets, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xmlEscape(sheet.name), n, n)
+		fmt.Fprintf(&workbookRels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, n, n)
+	}
+	fmt.Fprintf(&workbookRels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, len(sheets)+1)
+
+	parts["[Content_Types].xml"] = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+		contentTypes.String() + `</Types>`
+	parts["_rels/.rels"] = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+	parts["xl/workbook.xml"] = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets>` + workbookSheets.String() + `</sheets><calcPr calcId="191029" fullCalcOnLoad="1"/></workbook>`
+	parts["xl/_rels/workbook.xml.rels"] = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		workbookRels.String() + `</Relationships>`
+	parts["xl/styles.xml"] = xlsxStyles
+	order = append([]string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml"}, order...)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range order {
+		w, err := zw.Create(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write %s: %v", name, err)
+		}
+		if _, err := w.Write([]byte(parts[name])); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %v", name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write xlsx: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func sheetXML(s *xlsxSheet) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	if len(s.widths) > 0 {
+		b.WriteString("<cols>")
+		for i, w := range s.widths {
+			fmt.Fprintf(&b, `<col min="%d" max="%d" width="%s" customWidth="1"/>`, i+1, i+1, strconv.FormatFloat(w, 'f', -1, 64))
+		}
+		b.WriteString("</cols>")
+	}
+	b.WriteString("<sheetData>")
+	for i, row := range s.rows {
+		fmt.Fprintf(&b, `<row r="%d">`, i+1)
+		for c, cell := range row {
+			ref := fmt.Sprintf("%s%d", col(c), i+1)
+			switch {
+			case cell.formula != "":
+				fmt.Fprintf(&b, `<c r="%s" s="%d"><f>%s</f></c>`, ref, cell.style, xmlEscape(cell.formula))
+			case cell.isText && cell.text == "":
+				fmt.Fprintf(&b, `<c r="%s" s="%d"/>`, ref, cell.style)
+			case cell.isText:
+				fmt.Fprintf(&b, `<c r="%s" s="%d" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, cell.style, xmlEscape(cell.text))
+			default:
+				fmt.Fprintf(&b, `<c r="%s" s="%d"><v>%s</v></c>`, ref, cell.style, strconv.FormatFloat(cell.number, 'f', -1, 64))
+			}
+		}
+		b.WriteString("</row>")
+	}
+	b.WriteString("</sheetData></worksheet>")
+	return b.String()
+}
+
+// xlsxStyles defines the cell styles: plain, bold, money, header (bold on
+// grey) and total (bold money)
+const xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<numFmts count="1"><numFmt numFmtId="164" formatCode="#,##0.00"/></numFmts>
+<fonts count="2"><font><sz val="11"/><name val="Calibri"/><family val="2"/></font><font><b/><sz val="11"/><name val="Calibri"/><family val="2"/></font></fonts>
+<fills count="3"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill><fill><patternFill patternType="solid"><fgColor rgb="FFEDEDED"/><bgColor indexed="64"/></patternFill></fill></fills>
+<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>
+<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>
+<cellXfs count="5">
+<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>
+<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>
+<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>
+<xf numFmtId="0" fontId="1" fillId="2" borderId="0" xfId="0" applyFont="1" applyFill="1"/>
+<xf numFmtId="164" fontId="1" fillId="0" borderId="0" xfId="0" applyNumberFormat="1" applyFont="1"/>
+</cellXfs>
+<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>
+</styleSheet>`
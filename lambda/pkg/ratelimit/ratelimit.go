@@ -0,0 +1,198 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/x-zero/business-consultant/pkg/db"
+	"github.com/x-zero/business-consultant/pkg/usage"
+)
+
+// Defaults per endpoint, overridable with RATE_LIMIT_<ENDPOINT> (requests
+// per minute) and RATE_LIMIT_<ENDPOINT>_BURST, e.g. RATE_LIMIT_CHAT=20
+var defaults = map[string]Limit{
+	"chat":                     {PerMinute: 10, Burst: 5},
+	"identify-profession-tags": {PerMinute: 30, Burst: 10},
+}
+
+// defaultDailyTokenQuota is the number of LLM tokens a user may consume per
+// UTC day; override with DAILY_TOKEN_QUOTA (0 disables the quota)
+const defaultDailyTokenQuota = 200000
+
+// Limit is a token bucket refilled at PerMinute requests per minute and
+// holding at most Burst requests
+type Limit struct {
+	PerMinute float64
+	Burst     int
+}
+
+// LimitFor returns the configured limit of an endpoint
+func LimitFor(endpoint string) Limit {
+	limit, ok := defaults[endpoint]
+	if !ok {
+		limit = Limit{PerMinute: 30, Burst: 10}
+	}
+
+	key := "RATE_LIMIT_" + strings.ToUpper(strings.ReplaceAll(endpoint, "-", "_"))
+	if v, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil && v > 0 {
+		limit.PerMinute = v
+	}
+	if v, err := strconv.Atoi(os.Getenv(key + "_BURST")); err == nil && v > 0 {
+		limit.Burst = v
+	}
+	return limit
+}
+
+// DailyTokenQuota returns the configured daily token quota, 0 if disabled
+func DailyTokenQuota() int64 {
+	if v, err := strconv.ParseInt(os.Getenv("DAILY_TOKEN_QUOTA"), 10, 64); err == nil && v >= 0 {
+		return v
+	}
+	return defaultDailyTokenQuota
+}
+
+const exposedHeaders = "Retry-After,X-RateLimit-Limit,X-RateLimit-Remaining,X-Quota-Limit,X-Quota-Remaining,X-Quota-Reset"
+
+// Decision is the outcome of a limit check
+type Decision struct {
+	Allowed    bool
+	Reason     string // why the request was refused
+	RetryAfter time.Duration
+
+	Limit     int // bucket size
+	Remaining int // requests left in the bucket
+
+	QuotaLimit     int64 // daily token quota, 0 if disabled
+	QuotaRemaining int64
+	QuotaReset     time.Time
+}
+
+// Headers returns the rate limit headers of the decision. Retry-After is
+// only set for refused requests.
+func (d *Decision) Headers() map[string]string {
+	headers := map[string]string{
+		"X-RateLimit-Limit":     strconv.Itoa(d.Limit),
+		"X-RateLimit-Remaining": strconv.Itoa(d.Remaining),
+		// Let browsers read the headers on API Gateway responses
+		"Access-Control-Expose-Headers": exposedHeaders,
+	}
+	if d.QuotaLimit > 0 {
+		headers["X-Quota-Limit"] = strconv.FormatInt(d.QuotaLimit, 10)
+		headers["X-Quota-Remaining"] = strconv.FormatInt(d.QuotaRemaining, 10)
+		headers["X-Quota-Reset"] = strconv.FormatInt(d.QuotaReset.Unix(), 10)
+	}
+	if !d.Allowed {
+		headers["Retry-After"] = strconv.Itoa(int(math.Ceil(d.RetryAfter.Seconds())))
+	}
+	return headers
+}
+
+// Apply adds the rate limit headers of the decision to a response
+func (d *Decision) Apply(headers map[string]string) {
+	for k, v := range d.Headers() {
+		headers[k] = v
+	}
+}
+
+// Check takes one request of userDID on endpoint from its bucket, unless the
+// user's daily token quota is used up. The limiter fails open: errors are
+// logged and the request is allowed, so a database hiccup does not take the
+// AI endpoints down with it.
+func Check(ctx context.Context, userDID, endpoint string) *Decision {
+	limit := LimitFor(endpoint)
+	decision := &Decision{Allowed: true, Limit: limit.Burst, Remaining: limit.Burst}
+
+	if err := db.InitDB(); err != nil {
+		fmt.Printf("Rate limiter disabled: %v\n", err)
+		return decision
+	}
+
+	if err := checkQuota(ctx, db.GetPool(), userDID, decision); err != nil {
+		fmt.Printf("Quota check failed: %v\n", err)
+	}
+	if !decision.Allowed {
+		return decision
+	}
+
+	if err := take(ctx, db.GetPool(), userDID, endpoint, limit, decision); err != nil {
+		fmt.Printf("Rate limit check failed: %v\n", err)
+	}
+	return decision
+}
+
+// checkQuota refuses the request when the tokens recorded today reach the quota
+func checkQuota(ctx context.Context, q db.Querier, userDID string, d *Decision) error {
+	quota := DailyTokenQuota()
+	if quota == 0 {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	day := now.Truncate(24 * time.Hour)
+	d.QuotaLimit = quota
+	d.QuotaReset = day.Add(24 * time.Hour)
+
+	used, err := usage.TokensSince(ctx, q, userDID, day)
+	if err != nil {
+		d.QuotaRemaining = quota
+		return err
+	}
+
+	d.QuotaRemaining = max(quota-used, 0)
+	if d.QuotaRemaining == 0 {
+		d.Allowed = false
+		d.Reason = "Daily token quota exceeded"
+		d.RetryAfter = d.QuotaReset.Sub(now)
+	}
+	return nil
+}
+
+// take refills the bucket for the time elapsed since its last use and
+// removes one request from it. The refill is computed from the locked row in
+// the upsert itself, so concurrent Lambdas cannot both take the last request;
+// a refused request leaves the bucket untouched.
+func take(ctx context.Context, q db.Querier, userDID, endpoint string, limit Limit, d *Decision) error {
+	burst := float64(limit.Burst)
+	ratePerSecond := limit.PerMinute / 60
+
+	var tokens float64
+	err := q.QueryRow(ctx, `
+		INSERT INTO rate_limits (user_did, endpoint, tokens, updated_at)
+		VALUES ($1, $2, $3::float8 - 1, NOW())
+		ON CONFLICT (user_did, endpoint) DO UPDATE
+		SET tokens = LEAST($3::float8, rate_limits.tokens + EXTRACT(EPOCH FROM NOW() - rate_limits.updated_at) * $4::float8) - 1,
+		    updated_at = NOW()
+		WHERE LEAST($3::float8, rate_limits.tokens + EXTRACT(EPOCH FROM NOW() - rate_limits.updated_at) * $4::float8) >= 1
+		RETURNING tokens
+	`, userDID, endpoint, burst, ratePerSecond).Scan(&tokens)
+	if err == nil {
+		d.Remaining = int(math.Floor(tokens))
+		return nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("failed to update rate limit: %v", err)
+	}
+
+	// Refused: report when the next request becomes available
+	err = q.QueryRow(ctx, `
+		SELECT LEAST($3::float8, tokens + EXTRACT(EPOCH FROM NOW() - updated_at) * $4::float8)
+		FROM rate_limits
+		WHERE user_did = $1 AND endpoint = $2
+	`, userDID, endpoint, burst, ratePerSecond).Scan(&tokens)
+	if err != nil {
+		return fmt.Errorf("failed to read rate limit: %v", err)
+	}
+
+	d.Allowed = false
+	d.Reason = "Too many requests"
+	d.Remaining = 0
+	d.RetryAfter = time.Duration((1 - tokens) / ratePerSecond * float64(time.Second))
+	return nil
+}
@@ -0,0 +1,118 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/x-zero/business-consultant/pkg/db"
+)
+
+// MaxCommentLength is the maximum length of a comment in characters
+const MaxCommentLength = 4000
+
+// ErrCommentNotFound is returned when a report has no comment with the
+// requested ID
+var ErrCommentNotFound = errors.New("comment not found")
+
+// Comment is a comment of a project member on a report, or on one of its
+// items when ItemID is set
+type Comment struct {
+	CommentID string    `json:"comment_id"`
+	ReportID  string    `json:"report_id"`
+	ItemID    *string   `json:"item_id"`
+	UserDID   string    `json:"user_did"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AddComment adds a comment to a report. A non-nil itemID must name an item
+// of the report.
+func AddComment(ctx context.Context, q db.Querier, reportID string, itemID *string, userDID, body string) (*Comment, error) {
+	if itemID != nil {
+		table := itemTable(*itemID)
+		if table == "" {
+			return nil, ErrItemNotFound
+		}
+		var exists bool
+		err := q.QueryRow(ctx, `
+			SELECT EXISTS (SELECT 1 FROM `+table+` WHERE report_id = $1 AND item_id = $2)
+		`, reportID, *itemID).Scan(&exists)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read item: %v", err)
+		}
+		if !exists {
+			return nil, ErrItemNotFound
+		}
+	}
+
+	c := &Comment{ReportID: reportID, ItemID: itemID, UserDID: userDID, Body: body}
+	err := q.QueryRow(ctx, `
+		INSERT INTO report_comments (report_id, item_id, user_did, body)
+		VALUES ($1, $2, $3, $4)
+		RETURNING comment_id, created_at
+	`, reportID, itemID, userDID, body).Scan(&c.CommentID, &c.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add comment: %v", err)
+	}
+	return c, nil
+}
+
+// ListComments returns the comments of a report, oldest first. A non-empty
+// itemID only returns the comments of that item.
+func ListComments(ctx context.Context, q db.Querier, reportID, itemID string) ([]Comment, error) {
+	rows, err := q.Query(ctx, `
+		SELECT comment_id, report_id, item_id, user_did, body, created_at
+		FROM report_comments
+		WHERE report_id = $1 AND ($2 = '' OR item_id = $2)
+		ORDER BY created_at, comment_id
+	`, reportID, itemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query comments: %v", err)
+	}
+	defer rows.Close()
+
+	comments := []Comment{}
+	for rows.Next() {
+		var c Comment
+		if err := rows.Scan(&c.CommentID, &c.ReportID, &c.ItemID, &c.UserDID, &c.Body, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan comment: %v", err)
+		}
+		comments = append(comments, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query comments: %v", err)
+	}
+	return comments, nil
+}
+
+// CommentAuthor returns the DID of the author of a comment of a report
+func CommentAuthor(ctx context.Context, q db.Querier, reportID, commentID string) (string, error) {
+	var userDID string
+	err := q.QueryRow(ctx, `
+		SELECT user_did FROM report_comments WHERE report_id = $1 AND comment_id = $2
+	`, reportID, commentID).Scan(&userDID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrCommentNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read comment: %v", err)
+	}
+	return userDID, nil
+}
+
+// DeleteComment removes a comment of a report
+func DeleteComment(ctx context.Context, q db.Querier, reportID, commentID string) error {
+	tag, err := q.Exec(ctx, `
+		DELETE FROM report_comments WHERE report_id = $1 AND comment_id = $2
+	`, reportID, commentID)
+	if err != nil {
+		return fmt.Errorf("failed to delete comment: %v", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrCommentNotFound
+	}
+	return nil
+}
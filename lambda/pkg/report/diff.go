@@ -0,0 +1,160 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Change is a field that differs between two revisions. Nested objects such
+// as budget_breakdown are compared per key, e.g. "budget_breakdown.营销".
+type Change struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// ItemChange lists the changed fields of an item present in both revisions
+type ItemChange struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Changes []Change `json:"changes"`
+}
+
+// ListDiff compares one item list of two revisions
+type ListDiff struct {
+	Added   []map[string]interface{} `json:"added"`
+	Removed []map[string]interface{} `json:"removed"`
+	Changed []ItemChange             `json:"changed"`
+}
+
+// Diff is the structured difference between two revisions of a report
+type Diff struct {
+	FromVersion int      `json:"from_version"`
+	ToVersion   int      `json:"to_version"`
+	Changes     []Change `json:"changes"` // business_goal, summary
+	AIWorkflows ListDiff `json:"ai_workflows"`
+	HumanRoles  ListDiff `json:"human_roles"`
+	Phases      ListDiff `json:"phases"`
+}
+
+// item lists compared by DiffRevisions, with their ID prefix and name field
+var diffLists = []struct {
+	key, prefix, name string
+}{
+	{"ai_workflows", WorkflowPrefix, "name"},
+	{"human_roles", RolePrefix, "title"},
+	{"phases", PhasePrefix, "phase_name"},
+}
+
+// DiffRevisions compares two revisions. Items are matched by ID, so edits,
+// moves and removals are told apart; items of revisions saved without IDs
+// are matched by position.
+func DiffRevisions(from, to *Revision) (*Diff, error) {
+	var a, b map[string]interface{}
+	if err := json.Unmarshal(from.Recommendations, &a); err != nil {
+		return nil, fmt.Errorf("failed to parse revision %d: %v", from.Version, err)
+	}
+	if err := json.Unmarshal(to.Recommendations, &b); err != nil {
+		return nil, fmt.Errorf("failed to parse revision %d: %v", to.Version, err)
+	}
+
+	diff := &Diff{FromVersion: from.Version, ToVersion: to.Version, Changes: []Change{}}
+	if from.BusinessGoal != to.BusinessGoal {
+		diff.Changes = append(diff.Changes, Change{Field: "business_goal", From: from.BusinessGoal, To: to.BusinessGoal})
+	}
+	if !reflect.DeepEqual(a["summary"], b["summary"]) {
+		diff.Changes = append(diff.Changes, Change{Field: "summary", From: a["summary"], To: b["summary"]})
+	}
+
+	lists := []*ListDiff{&diff.AIWorkflows, &diff.HumanRoles, &diff.Phases}
+	for i, l := range diffLists {
+		*lists[i] = diffList(asObjects(a[l.key]), asObjects(b[l.key]), l.prefix, l.name)
+	}
+	return diff, nil
+}
+
+// diffList matches the items of two lists by ID and compares them
+func diffList(from, to []map[string]interface{}, prefix, nameField string) ListDiff {
+	diff := ListDiff{
+		Added:   []map[string]interface{}{},
+		Removed: []map[string]interface{}{},
+		Changed: []ItemChange{},
+	}
+
+	index := func(items []map[string]interface{}) (map[string]int, []string) {
+		positions := map[string]int{}
+		ids := make([]string, len(items))
+		for i, item := range items {
+			id, _ := item["id"].(string)
+			if id == "" {
+				id = ItemID(prefix, i)
+			}
+			positions[id] = i
+			ids[i] = id
+		}
+		return positions, ids
+	}
+	fromPos, fromIDs := index(from)
+	toPos, toIDs := index(to)
+
+	for i, id := range fromIDs {
+		if _, ok := toPos[id]; !ok {
+			diff.Removed = append(diff.Removed, from[i])
+		}
+	}
+
+	for j, id := range toIDs {
+		i, ok := fromPos[id]
+		if !ok {
+			diff.Added = append(diff.Added, to[j])
+			continue
+		}
+
+		changes := diffFields("", from[i], to[j])
+		if i != j {
+			changes = append(changes, Change{Field: "position", From: i, To: j})
+		}
+		if len(changes) > 0 {
+			name, _ := to[j][nameField].(string)
+			diff.Changed = append(diff.Changed, ItemChange{ID: id, Name: name, Changes: changes})
+		}
+	}
+
+	return diff
+}
+
+// diffFields compares two objects key by key, descending into nested objects
+func diffFields(prefix string, a, b map[string]interface{}) []Change {
+	keys := map[string]bool{}
+	for k := range a {
+		keys[k] = true
+	}
+	for k := range b {
+		keys[k] = true
+	}
+	delete(keys, "id")
+
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	var changes []Change
+	for _, k := range sorted {
+		av, bv := a[k], b[k]
+		if reflect.DeepEqual(av, bv) {
+			continue
+		}
+		am, aok := av.(map[string]interface{})
+		bm, bok := bv.(map[string]interface{})
+		if aok && bok {
+			changes = append(changes, diffFields(prefix+k+".", am, bm)...)
+			continue
+		}
+		changes = append(changes, Change{Field: prefix + k, From: av, To: bv})
+	}
+	return changes
+}
@@ -0,0 +1,261 @@
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/x-zero/business-consultant/pkg/db"
+)
+
+// Revision actions of the item editing endpoints
+const (
+	ActionItemAdd     = "item_add"
+	ActionItemEdit    = "item_edit"
+	ActionItemRemove  = "item_remove"
+	ActionItemReorder = "item_reorder"
+)
+
+// Errors of the item editing functions
+var (
+	ErrNotEditable  = errors.New("report was saved with validation errors and cannot be edited")
+	ErrItemNotFound = errors.New("item not found")
+	ErrLastItem     = errors.New("the last item of a list cannot be removed")
+	ErrInvalidOrder = errors.New("item_ids must list every item of the list exactly once")
+)
+
+// itemLists maps the list keys to their ID prefix and table
+var itemLists = map[string]struct{ prefix, table string }{
+	ListWorkflows: {WorkflowPrefix, "report_workflows"},
+	ListRoles:     {RolePrefix, "report_roles"},
+	ListPhases:    {PhasePrefix, "report_phases"},
+}
+
+// ListOf returns the list an item ID belongs to, or "" for unknown IDs
+func ListOf(itemID string) string {
+	for list, l := range itemLists {
+		if strings.HasPrefix(itemID, l.prefix+"-") {
+			return list
+		}
+	}
+	return ""
+}
+
+// newItemID returns the ID of an item added after the report was saved.
+// Positional IDs would be reused after removals, so added items get a
+// random suffix instead.
+func newItemID(list string) string {
+	return itemLists[list].prefix + "-" + strings.ReplaceAll(uuid.New().String(), "-", "")[:8]
+}
+
+// LockForEdit checks that the report can be edited and bumps its version,
+// which locks the report row until the transaction ends. expected is the
+// version from If-Match, if any. Check access.Edit before the transaction.
+func LockForEdit(ctx context.Context, q db.Querier, reportID string, expected *int) (int, error) {
+	var invalid bool
+	err := q.QueryRow(ctx, `
+		SELECT validation_errors IS NOT NULL
+		FROM business_reports
+		WHERE report_id = $1 AND deleted_at IS NULL
+	`, reportID).Scan(&invalid)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrReportNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read report: %v", err)
+	}
+	if invalid {
+		return 0, ErrNotEditable
+	}
+
+	return BumpVersion(ctx, q, reportID, expected)
+}
+
+// AddItem inserts a parsed item (see ParseItem) into a list at position,
+// shifting later items down. A negative or too large position appends.
+// Added items start without a publishing status.
+func AddItem(ctx context.Context, q db.Querier, reportID, list string, item interface{}, position int) (string, error) {
+	l, ok := itemLists[list]
+	if !ok {
+		return "", fmt.Errorf("unknown item list: %s", list)
+	}
+
+	var count int
+	err := q.QueryRow(ctx, `SELECT COUNT(*) FROM `+l.table+` WHERE report_id = $1`, reportID).Scan(&count)
+	if err != nil {
+		return "", fmt.Errorf("failed to count items: %v", err)
+	}
+	if position < 0 || position > count {
+		position = count
+	}
+
+	_, err = q.Exec(ctx, `
+		UPDATE `+l.table+`
+		SET position = position + 1
+		WHERE report_id = $1 AND position >= $2
+	`, reportID, position)
+	if err != nil {
+		return "", fmt.Errorf("failed to shift items: %v", err)
+	}
+
+	id := newItemID(list)
+	switch it := item.(type) {
+	case *AIWorkflow:
+		it.ID, it.Status, it.TaskID = id, nil, nil
+		err = insertWorkflow(ctx, q, reportID, position, it)
+	case *HumanRole:
+		it.ID, it.Status, it.TaskID = id, nil, nil
+		err = insertRole(ctx, q, reportID, position, it)
+	case *Phase:
+		it.ID = id
+		err = insertPhase(ctx, q, reportID, position, it)
+	default:
+		err = fmt.Errorf("unsupported item type %T", item)
+	}
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// EditItem replaces the content of an item with a parsed item of the same
+// list. The publishing status is kept.
+func EditItem(ctx context.Context, q db.Querier, reportID, itemID string, item interface{}) error {
+	var err error
+	var tag pgconn.CommandTag
+
+	switch it := item.(type) {
+	case *AIWorkflow:
+		tag, err = q.Exec(ctx, `
+			UPDATE report_workflows
+			SET name = $3, description = $4, input_requirements = $5, output_requirements = $6,
+			    estimated_cost = $7, priority = $8, updated_at = NOW()
+			WHERE report_id = $1 AND item_id = $2
+		`, reportID, itemID, it.Name, it.Description, it.InputRequirements, it.OutputRequirements,
+			it.EstimatedCost, it.Priority)
+	case *HumanRole:
+		responsibilities, _ := json.Marshal(nonNil(it.Responsibilities))
+		requirements, _ := json.Marshal(nonNil(it.Requirements))
+		tag, err = q.Exec(ctx, `
+			UPDATE report_roles
+			SET title = $3, responsibilities = $4::jsonb, requirements = $5::jsonb, work_hours = $6,
+			    monthly_budget = $7, priority = $8, updated_at = NOW()
+			WHERE report_id = $1 AND item_id = $2
+		`, reportID, itemID, it.Title, string(responsibilities), string(requirements), it.WorkHours,
+			it.MonthlyBudget, it.Priority)
+	case *Phase:
+		breakdown, _ := json.Marshal(it.BudgetBreakdown)
+		tag, err = q.Exec(ctx, `
+			UPDATE report_phases
+			SET phase_name = $3, duration = $4, monthly_budget = $5, budget_breakdown = $6::jsonb, updated_at = NOW()
+			WHERE report_id = $1 AND item_id = $2
+		`, reportID, itemID, it.PhaseName, it.Duration, it.MonthlyBudget, string(breakdown))
+	default:
+		return fmt.Errorf("unsupported item type %T", item)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update item %s: %v", itemID, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrItemNotFound
+	}
+	return nil
+}
+
+// RemoveItem deletes an item and closes the gap in the positions. Lists must
+// keep at least one item, as required by the schema.
+func RemoveItem(ctx context.Context, q db.Querier, reportID, itemID string) error {
+	l, ok := itemLists[ListOf(itemID)]
+	if !ok {
+		return ErrItemNotFound
+	}
+
+	var count int
+	err := q.QueryRow(ctx, `SELECT COUNT(*) FROM `+l.table+` WHERE report_id = $1`, reportID).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("failed to count items: %v", err)
+	}
+
+	var position int
+	err = q.QueryRow(ctx, `
+		SELECT position FROM `+l.table+` WHERE report_id = $1 AND item_id = $2
+	`, reportID, itemID).Scan(&position)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrItemNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read item %s: %v", itemID, err)
+	}
+	if count <= 1 {
+		return ErrLastItem
+	}
+
+	_, err = q.Exec(ctx, `DELETE FROM `+l.table+` WHERE report_id = $1 AND item_id = $2`, reportID, itemID)
+	if err != nil {
+		return fmt.Errorf("failed to remove item %s: %v", itemID, err)
+	}
+	_, err = q.Exec(ctx, `
+		UPDATE `+l.table+`
+		SET position = position - 1
+		WHERE report_id = $1 AND position > $2
+	`, reportID, position)
+	if err != nil {
+		return fmt.Errorf("failed to shift items: %v", err)
+	}
+	return nil
+}
+
+// ReorderItems puts the items of a list in the order of itemIDs, which must
+// name every item of the list exactly once
+func ReorderItems(ctx context.Context, q db.Querier, reportID, list string, itemIDs []string) error {
+	l, ok := itemLists[list]
+	if !ok {
+		return fmt.Errorf("unknown item list: %s", list)
+	}
+
+	rows, err := q.Query(ctx, `SELECT item_id FROM `+l.table+` WHERE report_id = $1`, reportID)
+	if err != nil {
+		return fmt.Errorf("failed to query items: %v", err)
+	}
+	existing := map[string]bool{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan item: %v", err)
+		}
+		existing[id] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to query items: %v", err)
+	}
+
+	if len(itemIDs) != len(existing) {
+		return ErrInvalidOrder
+	}
+	seen := map[string]bool{}
+	for _, id := range itemIDs {
+		if !existing[id] || seen[id] {
+			return ErrInvalidOrder
+		}
+		seen[id] = true
+	}
+
+	for position, id := range itemIDs {
+		_, err := q.Exec(ctx, `
+			UPDATE `+l.table+`
+			SET position = $3, updated_at = NOW()
+			WHERE report_id = $1 AND item_id = $2 AND position <> $3
+		`, reportID, id, position)
+		if err != nil {
+			return fmt.Errorf("failed to move item %s: %v", id, err)
+		}
+	}
+	return nil
+}
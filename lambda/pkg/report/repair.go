@@ -0,0 +1,250 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Local fixes reported by RepairResponse
+const (
+	FixExtracted      = "extracted_json_object"
+	FixSyntax         = "fixed_json_syntax"
+	FixCoercedNumbers = "coerced_numbers"
+)
+
+// RepairResponse decodes model output that should be a JSON object, applying
+// local fixes in order until it parses: extraction of the first JSON object
+// from surrounding text, syntax fixes (trailing commas, smart quotes, raw
+// newlines in strings) and coercion of "3000元"-style budgets into numbers.
+// The applied fixes are returned so callers can report them.
+func RepairResponse(text string) (map[string]interface{}, []string, error) {
+	var fixes []string
+
+	extracted := ExtractJSONObject(text)
+	if extracted != strings.TrimSpace(text) {
+		fixes = append(fixes, FixExtracted)
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(extracted), &doc); err != nil {
+		fixed := FixJSONSyntax(extracted)
+		if err := json.Unmarshal([]byte(fixed), &doc); err != nil {
+			return nil, fixes, fmt.Errorf("invalid JSON: %v", err)
+		}
+		fixes = append(fixes, FixSyntax)
+	}
+
+	if recs, ok := doc["recommendations"].(map[string]interface{}); ok {
+		if CoerceNumbers(recs) > 0 {
+			fixes = append(fixes, FixCoercedNumbers)
+		}
+	}
+
+	return doc, fixes, nil
+}
+
+// ExtractJSONObject returns the first balanced JSON object in text, ignoring
+// code fences and any prose around it. Unterminated objects are returned up
+// to the end of the text.
+func ExtractJSONObject(text string) string {
+	text = strings.TrimSpace(text)
+	start := strings.IndexByte(text, '{')
+	if start < 0 {
+		return text
+	}
+
+	depth := 0
+	inString := false
+	escaped := false
+	for i := start; i < len(text); i++ {
+		c := text[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			inString = true
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return text[start : i+1]
+			}
+		}
+	}
+
+	return strings.TrimSuffix(strings.TrimSpace(text[start:]), "```")
+}
+
+// FixJSONSyntax repairs common syntax slips of language models: trailing
+// commas before } or ], Chinese quotation marks used as string delimiters,
+// raw control characters inside strings and missing closing brackets.
+func FixJSONSyntax(text string) string {
+	var out strings.Builder
+	var closers []byte
+	inString := false
+	escaped := false
+	delim := rune(0)
+
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+				out.WriteRune(r)
+			case r == '\\':
+				escaped = true
+				out.WriteRune(r)
+			case r == delim || (delim == '”' && r == '“'):
+				inString = false
+				out.WriteByte('"')
+			case r == '"':
+				// A plain quote inside a string opened with a smart quote
+				out.WriteString(`\"`)
+			case r == '\n':
+				out.WriteString(`\n`)
+			case r == '\r':
+				out.WriteString(`\r`)
+			case r == '\t':
+				out.WriteString(`\t`)
+			default:
+				out.WriteRune(r)
+			}
+			continue
+		}
+
+		switch r {
+		case '"':
+			inString, delim = true, '"'
+			out.WriteByte('"')
+		case '“', '”':
+			inString, delim = true, '”'
+			out.WriteByte('"')
+		case '{':
+			closers = append(closers, '}')
+			out.WriteRune(r)
+		case '[':
+			closers = append(closers, ']')
+			out.WriteRune(r)
+		case '}', ']':
+			if len(closers) > 0 {
+				closers = closers[:len(closers)-1]
+			}
+			out.WriteRune(r)
+		case ',':
+			// Drop the comma when the next significant character closes a container
+			j := i + 1
+			for j < len(runes) && strings.ContainsRune(" \t\r\n", runes[j]) {
+				j++
+			}
+			if j < len(runes) && (runes[j] == '}' || runes[j] == ']') {
+				continue
+			}
+			out.WriteRune(r)
+		default:
+			out.WriteRune(r)
+		}
+	}
+
+	if inString {
+		out.WriteByte('"')
+	}
+	for i := len(closers) - 1; i >= 0; i-- {
+		out.WriteByte(closers[i])
+	}
+
+	return out.String()
+}
+
+var (
+	numberPattern = regexp.MustCompile(`\d+(?:\.\d+)?`)
+	rangePattern  = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*(?:-|~|～|—|到|至)\s*(\d+(?:\.\d+)?)`)
+	kiloPattern   = regexp.MustCompile(`\d\s*[kK]`)
+)
+
+// CoerceNumbers converts budget fields of a decoded recommendations object
+// that the model wrote as text ("3000元", "¥1,500/月", "0.5万", "2000-3000")
+// into numbers. Ranges resolve to their upper bound so budgets are never
+// under-estimated. It returns the number of values converted.
+func CoerceNumbers(recs map[string]interface{}) int {
+	count := 0
+	coerce := func(obj map[string]interface{}, key string) {
+		s, ok := obj[key].(string)
+		if !ok {
+			return
+		}
+		if n, ok := ParseAmount(s); ok {
+			obj[key] = n
+			count++
+		}
+	}
+
+	for _, item := range asObjects(recs["ai_workflows"]) {
+		coerce(item, "estimated_cost")
+	}
+	for _, item := range asObjects(recs["human_roles"]) {
+		coerce(item, "monthly_budget")
+	}
+	for _, phase := range asObjects(recs["phases"]) {
+		coerce(phase, "monthly_budget")
+		if breakdown, ok := phase["budget_breakdown"].(map[string]interface{}); ok {
+			for category := range breakdown {
+				coerce(breakdown, category)
+			}
+		}
+	}
+
+	return count
+}
+
+// ParseAmount extracts a monetary amount from free text such as "3000元/月"
+func ParseAmount(s string) (float64, bool) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), ",", "")
+	s = strings.ReplaceAll(s, "，", "")
+
+	var value float64
+	if m := rangePattern.FindStringSubmatch(s); m != nil {
+		value, _ = strconv.ParseFloat(m[2], 64)
+	} else if m := numberPattern.FindString(s); m != "" {
+		value, _ = strconv.ParseFloat(m, 64)
+	} else {
+		return 0, false
+	}
+
+	switch {
+	case strings.Contains(s, "万"):
+		value *= 10000
+	case strings.Contains(s, "千"), kiloPattern.MatchString(s):
+		value *= 1000
+	}
+
+	return value, true
+}
+
+// asObjects returns the object elements of a decoded JSON array
+func asObjects(v interface{}) []map[string]interface{} {
+	arr, _ := v.([]interface{})
+	objs := make([]map[string]interface{}, 0, len(arr))
+	for _, item := range arr {
+		if obj, ok := item.(map[string]interface{}); ok {
+			objs = append(objs, obj)
+		}
+	}
+	return objs
+}
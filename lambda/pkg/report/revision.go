@@ -0,0 +1,114 @@
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/x-zero/business-consultant/pkg/db"
+)
+
+// Revision actions
+const (
+	ActionCreate     = "create"
+	ActionItemStatus = "item_status"
+	ActionTrash      = "trash"
+	ActionRestore    = "restore"
+)
+
+// ErrRevisionNotFound is returned when a report has no revision with the
+// requested version
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// Revision is an immutable snapshot of a report taken after a mutation. Its
+// version is the report version the mutation produced.
+type Revision struct {
+	ReportID        string          `json:"report_id"`
+	Version         int             `json:"version"`
+	Action          string          `json:"action"`
+	UserDID         string          `json:"user_did"`
+	BusinessGoal    string          `json:"business_goal"`
+	Recommendations json.RawMessage `json:"recommendations,omitempty"`
+	CreatedAt       time.Time       `json:"created_at"`
+}
+
+// RecordRevision snapshots the current state of a report as the revision of
+// its current version. Call it in the transaction of the mutation, after the
+// version has been bumped.
+func RecordRevision(ctx context.Context, q db.Querier, reportID, userDID, action string) error {
+	var businessGoal string
+	var stored, validationErrors []byte
+	var version int
+	err := q.QueryRow(ctx, `
+		SELECT business_goal, recommendations, validation_errors, version
+		FROM business_reports
+		WHERE report_id = $1
+	`, reportID).Scan(&businessGoal, &stored, &validationErrors, &version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrReportNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read report: %v", err)
+	}
+
+	current, err := Current(ctx, q, reportID, stored, validationErrors == nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.Exec(ctx, `
+		INSERT INTO report_revisions (report_id, version, action, user_did, business_goal, recommendations)
+		VALUES ($1, $2, $3, $4, $5, $6::jsonb)
+	`, reportID, version, action, userDID, businessGoal, string(current))
+	if err != nil {
+		return fmt.Errorf("failed to record revision: %v", err)
+	}
+	return nil
+}
+
+// ListRevisions returns the revisions of a report, newest first, without
+// their snapshots
+func ListRevisions(ctx context.Context, q db.Querier, reportID string) ([]Revision, error) {
+	rows, err := q.Query(ctx, `
+		SELECT report_id, version, action, user_did, business_goal, created_at
+		FROM report_revisions
+		WHERE report_id = $1
+		ORDER BY version DESC
+	`, reportID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query revisions: %v", err)
+	}
+	defer rows.Close()
+
+	revisions := []Revision{}
+	for rows.Next() {
+		var r Revision
+		if err := rows.Scan(&r.ReportID, &r.Version, &r.Action, &r.UserDID, &r.BusinessGoal, &r.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan revision: %v", err)
+		}
+		revisions = append(revisions, r)
+	}
+	return revisions, rows.Err()
+}
+
+// GetRevision returns one revision of a report with its snapshot
+func GetRevision(ctx context.Context, q db.Querier, reportID string, version int) (*Revision, error) {
+	var r Revision
+	var recommendations []byte
+	err := q.QueryRow(ctx, `
+		SELECT report_id, version, action, user_did, business_goal, recommendations, created_at
+		FROM report_revisions
+		WHERE report_id = $1 AND version = $2
+	`, reportID, version).Scan(&r.ReportID, &r.Version, &r.Action, &r.UserDID, &r.BusinessGoal, &recommendations, &r.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrRevisionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get revision: %v", err)
+	}
+	r.Recommendations = recommendations
+	return &r, nil
+}
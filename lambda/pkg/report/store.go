@@ -0,0 +1,350 @@
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/x-zero/business-consultant/pkg/db"
+)
+
+// Item ID prefixes. Items are numbered from 0 in the order the model
+// returned them, e.g. wf-0, role-2, phase-1.
+const (
+	WorkflowPrefix = "wf"
+	RolePrefix     = "role"
+	PhasePrefix    = "phase"
+)
+
+// ItemID returns the ID of the item at index in its list
+func ItemID(prefix string, index int) string {
+	return fmt.Sprintf("%s-%d", prefix, index)
+}
+
+// AssignIDs gives every workflow, role and phase its ID by position and
+// moves item statuses onto the items. IDs sent by clients are ignored, so
+// the IDs of a saved report are always wf-0..n, role-0..n and phase-0..n.
+func AssignIDs(recs *Recommendations) {
+	for i := range recs.AIWorkflows {
+		wf := &recs.AIWorkflows[i]
+		wf.ID = ItemID(WorkflowPrefix, i)
+		if status, ok := recs.ItemStatuses[wf.ID]; ok && wf.Status == nil && wf.TaskID == nil {
+			wf.Status, wf.TaskID = status.Status, status.TaskID
+		}
+		if !ValidStatus(wf.Status) {
+			wf.Status = nil
+		}
+	}
+	for i := range recs.HumanRoles {
+		role := &recs.HumanRoles[i]
+		role.ID = ItemID(RolePrefix, i)
+		if status, ok := recs.ItemStatuses[role.ID]; ok && role.Status == nil && role.TaskID == nil {
+			role.Status, role.TaskID = status.Status, status.TaskID
+		}
+		if !ValidStatus(role.Status) {
+			role.Status = nil
+		}
+	}
+	for i := range recs.Phases {
+		recs.Phases[i].ID = ItemID(PhasePrefix, i)
+	}
+	recs.ItemStatuses = nil
+}
+
+// SaveItems writes the workflows, roles and phases of a report into the
+// report_workflows, report_roles and report_phases tables. Call AssignIDs
+// first.
+func SaveItems(ctx context.Context, q db.Querier, reportID string, recs *Recommendations) error {
+	for i := range recs.AIWorkflows {
+		if err := insertWorkflow(ctx, q, reportID, i, &recs.AIWorkflows[i]); err != nil {
+			return err
+		}
+	}
+	for i := range recs.HumanRoles {
+		if err := insertRole(ctx, q, reportID, i, &recs.HumanRoles[i]); err != nil {
+			return err
+		}
+	}
+	for i := range recs.Phases {
+		if err := insertPhase(ctx, q, reportID, i, &recs.Phases[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func insertWorkflow(ctx context.Context, q db.Querier, reportID string, position int, wf *AIWorkflow) error {
+	_, err := q.Exec(ctx, `
+		INSERT INTO report_workflows (report_id, item_id, position, name, description,
+		                              input_requirements, output_requirements, estimated_cost, priority, status, task_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`, reportID, wf.ID, position, wf.Name, wf.Description, wf.InputRequirements, wf.OutputRequirements,
+		wf.EstimatedCost, wf.Priority, wf.Status, wf.TaskID)
+	if err != nil {
+		return fmt.Errorf("failed to save workflow %s: %v", wf.ID, err)
+	}
+	return nil
+}
+
+func insertRole(ctx context.Context, q db.Querier, reportID string, position int, role *HumanRole) error {
+	responsibilities, _ := json.Marshal(nonNil(role.Responsibilities))
+	requirements, _ := json.Marshal(nonNil(role.Requirements))
+	_, err := q.Exec(ctx, `
+		INSERT INTO report_roles (report_id, item_id, position, title, responsibilities, requirements,
+		                          work_hours, monthly_budget, priority, status, task_id)
+		VALUES ($1, $2, $3, $4, $5::jsonb, $6::jsonb, $7, $8, $9, $10, $11)
+	`, reportID, role.ID, position, role.Title, string(responsibilities), string(requirements),
+		role.WorkHours, role.MonthlyBudget, role.Priority, role.Status, role.TaskID)
+	if err != nil {
+		return fmt.Errorf("failed to save role %s: %v", role.ID, err)
+	}
+	return nil
+}
+
+func insertPhase(ctx context.Context, q db.Querier, reportID string, position int, phase *Phase) error {
+	breakdown, _ := json.Marshal(phase.BudgetBreakdown)
+	_, err := q.Exec(ctx, `
+		INSERT INTO report_phases (report_id, item_id, position, phase_name, duration, monthly_budget, budget_breakdown)
+		VALUES ($1, $2, $3, $4, $5, $6, $7::jsonb)
+	`, reportID, phase.ID, position, phase.PhaseName, phase.Duration, phase.MonthlyBudget, string(breakdown))
+	if err != nil {
+		return fmt.Errorf("failed to save phase %s: %v", phase.ID, err)
+	}
+	return nil
+}
+
+// LoadItems replaces the workflows, roles, phases and item statuses of recs
+// with the rows stored for the report. It reports false when the report has
+// no item rows, e.g. reports saved with allow_invalid.
+func LoadItems(ctx context.Context, q db.Querier, reportID string, recs *Recommendations) (bool, error) {
+	statuses := map[string]ItemStatus{}
+
+	rows, err := q.Query(ctx, `
+		SELECT item_id, name, description, input_requirements, output_requirements,
+		       estimated_cost::float8, priority, status, task_id
+		FROM report_workflows
+		WHERE report_id = $1
+		ORDER BY position
+	`, reportID)
+	if err != nil {
+		return false, fmt.Errorf("failed to query workflows: %v", err)
+	}
+	workflows := []AIWorkflow{}
+	for rows.Next() {
+		var wf AIWorkflow
+		if err := rows.Scan(&wf.ID, &wf.Name, &wf.Description, &wf.InputRequirements, &wf.OutputRequirements,
+			&wf.EstimatedCost, &wf.Priority, &wf.Status, &wf.TaskID); err != nil {
+			rows.Close()
+			return false, fmt.Errorf("failed to scan workflow: %v", err)
+		}
+		workflows = append(workflows, wf)
+		addStatus(statuses, wf.ID, wf.Status, wf.TaskID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return false, fmt.Errorf("failed to query workflows: %v", err)
+	}
+
+	rows, err = q.Query(ctx, `
+		SELECT item_id, title, responsibilities, requirements, work_hours,
+		       monthly_budget::float8, priority, status, task_id
+		FROM report_roles
+		WHERE report_id = $1
+		ORDER BY position
+	`, reportID)
+	if err != nil {
+		return false, fmt.Errorf("failed to query roles: %v", err)
+	}
+	roles := []HumanRole{}
+	for rows.Next() {
+		var role HumanRole
+		var responsibilities, requirements []byte
+		if err := rows.Scan(&role.ID, &role.Title, &responsibilities, &requirements, &role.WorkHours,
+			&role.MonthlyBudget, &role.Priority, &role.Status, &role.TaskID); err != nil {
+			rows.Close()
+			return false, fmt.Errorf("failed to scan role: %v", err)
+		}
+		json.Unmarshal(responsibilities, &role.Responsibilities)
+		json.Unmarshal(requirements, &role.Requirements)
+		roles = append(roles, role)
+		addStatus(statuses, role.ID, role.Status, role.TaskID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return false, fmt.Errorf("failed to query roles: %v", err)
+	}
+
+	rows, err = q.Query(ctx, `
+		SELECT item_id, phase_name, duration, monthly_budget::float8, budget_breakdown
+		FROM report_phases
+		WHERE report_id = $1
+		ORDER BY position
+	`, reportID)
+	if err != nil {
+		return false, fmt.Errorf("failed to query phases: %v", err)
+	}
+	phases := []Phase{}
+	for rows.Next() {
+		var phase Phase
+		var breakdown []byte
+		if err := rows.Scan(&phase.ID, &phase.PhaseName, &phase.Duration, &phase.MonthlyBudget, &breakdown); err != nil {
+			rows.Close()
+			return false, fmt.Errorf("failed to scan phase: %v", err)
+		}
+		json.Unmarshal(breakdown, &phase.BudgetBreakdown)
+		phases = append(phases, phase)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return false, fmt.Errorf("failed to query phases: %v", err)
+	}
+
+	if len(workflows)+len(roles)+len(phases) == 0 {
+		return false, nil
+	}
+
+	recs.AIWorkflows = workflows
+	recs.HumanRoles = roles
+	recs.Phases = phases
+	recs.ItemStatuses = statuses
+	return true, nil
+}
+
+// HasStatus reports whether items with the given ID carry a publishing
+// status; only workflows and roles can be published as tasks
+func HasStatus(itemID string) bool {
+	table := itemTable(itemID)
+	return table == "report_workflows" || table == "report_roles"
+}
+
+// Current returns the current recommendations of a report: the stored
+// document with its items read from the item tables. Reports saved with
+// allow_invalid, or before the tables existed, have no item rows and are
+// returned as stored.
+func Current(ctx context.Context, q db.Querier, reportID string, stored []byte, valid bool) (json.RawMessage, error) {
+	if !valid {
+		return stored, nil
+	}
+
+	var recs Recommendations
+	json.Unmarshal(stored, &recs)
+	found, err := LoadItems(ctx, q, reportID, &recs)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return stored, nil
+	}
+	return json.Marshal(recs)
+}
+
+// UpdateItemStatus sets the publishing status of a workflow or role. It
+// reports false when the report has no such item.
+func UpdateItemStatus(ctx context.Context, q db.Querier, reportID, itemID string, status ItemStatus) (bool, error) {
+	if !HasStatus(itemID) {
+		return false, nil
+	}
+	table := itemTable(itemID)
+
+	result, err := q.Exec(ctx, `
+		UPDATE `+table+`
+		SET status = $3, task_id = $4, updated_at = NOW()
+		WHERE report_id = $1 AND item_id = $2
+	`, reportID, itemID, status.Status, status.TaskID)
+	if err != nil {
+		return false, fmt.Errorf("failed to update item %s: %v", itemID, err)
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// UpdateDocumentStatus sets the publishing status of a workflow or role of a
+// report that has no item rows: reports saved with allow_invalid and those
+// saved before the item tables. The item is matched by its id, or by its
+// position for items without one, and the status is also kept in
+// item_statuses for older clients. It reports false when there is no such
+// item or the report keeps its items in the tables.
+func UpdateDocumentStatus(ctx context.Context, q db.Querier, reportID, itemID string, status ItemStatus) (bool, error) {
+	if !HasStatus(itemID) {
+		return false, nil
+	}
+
+	var stored []byte
+	var hasRows bool
+	err := q.QueryRow(ctx, `
+		SELECT recommendations,
+		       EXISTS (SELECT 1 FROM report_workflows WHERE report_id = $1)
+		       OR EXISTS (SELECT 1 FROM report_roles WHERE report_id = $1)
+		       OR EXISTS (SELECT 1 FROM report_phases WHERE report_id = $1)
+		FROM business_reports
+		WHERE report_id = $1
+	`, reportID).Scan(&stored, &hasRows)
+	if err != nil {
+		return false, fmt.Errorf("failed to read report: %v", err)
+	}
+	if hasRows {
+		return false, nil
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(stored, &doc); err != nil || doc == nil {
+		return false, nil
+	}
+	list := ListOf(itemID)
+	items, _ := doc[list].([]interface{})
+	var item map[string]interface{}
+	for i, it := range items {
+		obj, ok := it.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		id, _ := obj["id"].(string)
+		if id == itemID || (id == "" && ItemID(itemLists[list].prefix, i) == itemID) {
+			item = obj
+			break
+		}
+	}
+	if item == nil {
+		return false, nil
+	}
+
+	item["status"] = status.Status
+	item["task_id"] = status.TaskID
+	statuses, _ := doc["item_statuses"].(map[string]interface{})
+	if statuses == nil {
+		statuses = map[string]interface{}{}
+		doc["item_statuses"] = statuses
+	}
+	statuses[itemID] = status
+
+	updated, err := json.Marshal(doc)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal recommendations: %v", err)
+	}
+	_, err = q.Exec(ctx, `
+		UPDATE business_reports SET recommendations = $2::jsonb WHERE report_id = $1
+	`, reportID, string(updated))
+	if err != nil {
+		return false, fmt.Errorf("failed to update item %s: %v", itemID, err)
+	}
+	return true, nil
+}
+
+// itemTable returns the table holding the item with the given ID
+func itemTable(itemID string) string {
+	return itemLists[ListOf(itemID)].table
+}
+
+// addStatus records the status of an item that has been acted on in the
+// item_statuses map kept for older clients
+func addStatus(statuses map[string]ItemStatus, id string, status, taskID *string) {
+	if status != nil || taskID != nil {
+		statuses[id] = ItemStatus{Status: status, TaskID: taskID}
+	}
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
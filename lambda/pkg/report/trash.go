@@ -0,0 +1,140 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/x-zero/business-consultant/pkg/access"
+	"github.com/x-zero/business-consultant/pkg/db"
+)
+
+// defaultRetention is how long trashed reports are kept before they are
+// purged, unless TRASH_RETENTION_DAYS is set
+const defaultRetention = 30 * 24 * time.Hour
+
+// Retention returns how long trashed reports are kept
+func Retention() time.Duration {
+	if days, err := strconv.Atoi(os.Getenv("TRASH_RETENTION_DAYS")); err == nil && days > 0 {
+		return time.Duration(days) * 24 * time.Hour
+	}
+	return defaultRetention
+}
+
+// TrashedReport is a report in the trash
+type TrashedReport struct {
+	ReportID     string    `json:"report_id"`
+	ProjectID    string    `json:"project_id"`
+	BusinessGoal string    `json:"business_goal"`
+	CreatedAt    time.Time `json:"created_at"`
+	DeletedAt    time.Time `json:"deleted_at"`
+	PurgeAt      time.Time `json:"purge_at"`
+}
+
+// Trash moves a report to the trash on behalf of userDID and returns when it
+// will be purged. Trashed reports are hidden from every read endpoint. Like
+// every mutation it bumps the version and records a revision, so run it in a
+// transaction.
+func Trash(ctx context.Context, q db.Querier, reportID, userDID string) (time.Time, error) {
+	var deletedAt time.Time
+	err := q.QueryRow(ctx, `
+		UPDATE business_reports
+		SET deleted_at = NOW()
+		WHERE report_id = $1 AND deleted_at IS NULL
+		RETURNING deleted_at
+	`, reportID).Scan(&deletedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return time.Time{}, ErrReportNotFound
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to trash report: %v", err)
+	}
+
+	if _, err := BumpVersion(ctx, q, reportID, nil); err != nil {
+		return time.Time{}, err
+	}
+	if err := RecordRevision(ctx, q, reportID, userDID, ActionTrash); err != nil {
+		return time.Time{}, err
+	}
+	return deletedAt.Add(Retention()), nil
+}
+
+// Restore takes a report out of the trash on behalf of userDID, recording a
+// revision like Trash. Run it in a transaction.
+func Restore(ctx context.Context, q db.Querier, reportID, userDID string) error {
+	result, err := q.Exec(ctx, `
+		UPDATE business_reports
+		SET deleted_at = NULL
+		WHERE report_id = $1 AND deleted_at IS NOT NULL
+	`, reportID)
+	if err != nil {
+		return fmt.Errorf("failed to restore report: %v", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrReportNotFound
+	}
+
+	if _, err := BumpVersion(ctx, q, reportID, nil); err != nil {
+		return err
+	}
+	return RecordRevision(ctx, q, reportID, userDID, ActionRestore)
+}
+
+// ListTrash returns the trashed reports userDID may restore, most recently
+// deleted first. projectID is optional.
+func ListTrash(ctx context.Context, q db.Querier, userDID, projectID string) ([]TrashedReport, error) {
+	rows, err := q.Query(ctx, `
+		SELECT report_id, project_id, business_goal, created_at, deleted_at
+		FROM business_reports
+		WHERE `+access.Where("", "$1", access.Delete)+` AND deleted_at IS NOT NULL
+		  AND ($2 = '' OR project_id = NULLIF($2, '')::uuid)
+		ORDER BY deleted_at DESC
+	`, userDID, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query trash: %v", err)
+	}
+	defer rows.Close()
+
+	retention := Retention()
+	reports := []TrashedReport{}
+	for rows.Next() {
+		var r TrashedReport
+		if err := rows.Scan(&r.ReportID, &r.ProjectID, &r.BusinessGoal, &r.CreatedAt, &r.DeletedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan report: %v", err)
+		}
+		r.PurgeAt = r.DeletedAt.Add(retention)
+		reports = append(reports, r)
+	}
+	return reports, rows.Err()
+}
+
+// Purge permanently deletes trashed reports userDID may delete: one report,
+// or the whole trash when reportID is empty. It returns the number deleted.
+func Purge(ctx context.Context, q db.Querier, userDID, reportID string) (int64, error) {
+	result, err := q.Exec(ctx, `
+		DELETE FROM business_reports
+		WHERE `+access.Where("", "$1", access.Delete)+` AND deleted_at IS NOT NULL
+		  AND ($2 = '' OR report_id = NULLIF($2, '')::uuid)
+	`, userDID, reportID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge trash: %v", err)
+	}
+	return result.RowsAffected(), nil
+}
+
+// PurgeExpired permanently deletes reports trashed longer than the
+// retention window
+func PurgeExpired(ctx context.Context, q db.Querier) (int64, error) {
+	result, err := q.Exec(ctx, `
+		DELETE FROM business_reports
+		WHERE deleted_at IS NOT NULL AND deleted_at < NOW() - $1::interval
+	`, fmt.Sprintf("%d seconds", int64(Retention().Seconds())))
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge expired reports: %v", err)
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,94 @@
+package report
+
+// Response stages returned by the consultant model
+const (
+	StageQuestioning  = "questioning"
+	StageRecommending = "recommending"
+)
+
+// Priority values accepted for workflows and roles
+const (
+	PriorityHigh   = "high"
+	PriorityMedium = "medium"
+	PriorityLow    = "low"
+)
+
+// ConsultantResponse is the JSON object the consultant model returns on every turn
+type ConsultantResponse struct {
+	Stage           string           `json:"stage"`
+	Message         string           `json:"message"`
+	Questions       []string         `json:"questions,omitempty"`
+	Progress        string           `json:"progress,omitempty"`
+	Recommendations *Recommendations `json:"recommendations,omitempty"`
+}
+
+// Recommendations is the plan produced in the recommending stage and stored
+// in business_reports.recommendations
+type Recommendations struct {
+	BusinessGoal string                `json:"business_goal"`
+	Summary      string                `json:"summary"`
+	AIWorkflows  []AIWorkflow          `json:"ai_workflows"`
+	HumanRoles   []HumanRole           `json:"human_roles"`
+	Phases       []Phase               `json:"phases"`
+	ItemStatuses map[string]ItemStatus `json:"item_statuses,omitempty"`
+}
+
+// Item lists of a recommendations document
+const (
+	ListWorkflows = "ai_workflows"
+	ListRoles     = "human_roles"
+	ListPhases    = "phases"
+)
+
+// Item statuses accepted by update-report-item; a null status clears it
+const (
+	StatusDraftCreated = "draft_created"
+	StatusPublished    = "published"
+)
+
+// AIWorkflow is a task the founder can automate with AI. ID, Status and
+// TaskID are assigned by the backend, not by the model.
+type AIWorkflow struct {
+	ID                 string  `json:"id,omitempty"`
+	Name               string  `json:"name"`
+	Description        string  `json:"description"`
+	InputRequirements  string  `json:"input_requirements"`
+	OutputRequirements string  `json:"output_requirements"`
+	EstimatedCost      float64 `json:"estimated_cost"`
+	Priority           string  `json:"priority"`
+	Status             *string `json:"status,omitempty"`
+	TaskID             *string `json:"task_id,omitempty"`
+}
+
+// HumanRole is a position that needs a real person
+type HumanRole struct {
+	ID               string   `json:"id,omitempty"`
+	Title            string   `json:"title"`
+	Responsibilities []string `json:"responsibilities"`
+	Requirements     []string `json:"requirements"`
+	WorkHours        string   `json:"work_hours"`
+	MonthlyBudget    float64  `json:"monthly_budget"`
+	Priority         string   `json:"priority"`
+	Status           *string  `json:"status,omitempty"`
+	TaskID           *string  `json:"task_id,omitempty"`
+}
+
+// Phase is a stage of the plan with its monthly budget
+type Phase struct {
+	ID              string             `json:"id,omitempty"`
+	PhaseName       string             `json:"phase_name"`
+	Duration        string             `json:"duration"`
+	MonthlyBudget   float64            `json:"monthly_budget"`
+	BudgetBreakdown map[string]float64 `json:"budget_breakdown"`
+}
+
+// ItemStatus is the task publishing state of a recommendation item
+type ItemStatus struct {
+	Status *string `json:"status"`
+	TaskID *string `json:"task_id"`
+}
+
+// ValidStatus reports whether status may be stored on an item
+func ValidStatus(status *string) bool {
+	return status == nil || *status == StatusDraftCreated || *status == StatusPublished
+}
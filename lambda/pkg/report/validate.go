@@ -0,0 +1,304 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ValidationError describes one violated schema rule
+type ValidationError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors collects every violated rule of a document
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, v := range e {
+		msgs[i] = v.Path + ": " + v.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+var allowedPriorities = map[string]bool{
+	PriorityHigh:   true,
+	PriorityMedium: true,
+	PriorityLow:    true,
+}
+
+// validator accumulates errors while walking a decoded JSON document
+type validator struct {
+	errs ValidationErrors
+}
+
+func (v *validator) add(path, format string, args ...interface{}) {
+	v.errs = append(v.errs, ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// ValidateResponse checks a decoded consultant response against the schema
+// described in the system prompt
+func ValidateResponse(doc map[string]interface{}) ValidationErrors {
+	v := &validator{}
+	stage := v.requireString(doc, "", "stage")
+	v.requireString(doc, "", "message")
+
+	switch stage {
+	case StageQuestioning:
+		v.requireStringArray(doc, "", "questions")
+	case StageRecommending:
+		recs, ok := doc["recommendations"].(map[string]interface{})
+		if !ok {
+			v.add("recommendations", "is required and must be an object")
+			break
+		}
+		v.recommendations(recs, "recommendations")
+	case "":
+	default:
+		v.add("stage", "unknown stage %q, expected %q or %q", stage, StageQuestioning, StageRecommending)
+	}
+
+	return v.errs
+}
+
+// ValidateRecommendations checks a decoded recommendations object
+func ValidateRecommendations(doc map[string]interface{}) ValidationErrors {
+	v := &validator{}
+	if doc == nil {
+		v.add("recommendations", "is required and must be an object")
+		return v.errs
+	}
+	v.recommendations(doc, "")
+	return v.errs
+}
+
+// ParseRecommendations decodes and validates raw recommendations JSON. The
+// typed value is only returned when the document is valid.
+func ParseRecommendations(raw []byte) (*Recommendations, ValidationErrors, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, nil, fmt.Errorf("invalid recommendations JSON: %v", err)
+	}
+
+	if errs := ValidateRecommendations(doc); len(errs) > 0 {
+		return nil, errs, nil
+	}
+
+	var recs Recommendations
+	if err := json.Unmarshal(raw, &recs); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode recommendations: %v", err)
+	}
+	return &recs, nil, nil
+}
+
+func (v *validator) recommendations(doc map[string]interface{}, path string) {
+	v.requireString(doc, path, "business_goal")
+	v.requireString(doc, path, "summary")
+
+	for i, item := range v.requireArray(doc, path, ListWorkflows) {
+		v.item(ListWorkflows, item, fmt.Sprintf("%s[%d]", join(path, ListWorkflows), i))
+	}
+	for i, item := range v.requireArray(doc, path, ListRoles) {
+		v.item(ListRoles, item, fmt.Sprintf("%s[%d]", join(path, ListRoles), i))
+	}
+	for i, item := range v.requireArray(doc, path, ListPhases) {
+		v.item(ListPhases, item, fmt.Sprintf("%s[%d]", join(path, ListPhases), i))
+	}
+}
+
+// item checks one element of an item list
+func (v *validator) item(list string, item interface{}, p string) {
+	obj, ok := item.(map[string]interface{})
+	if !ok {
+		v.add(p, "must be an object")
+		return
+	}
+
+	switch list {
+	case ListWorkflows:
+		v.requireString(obj, p, "name")
+		v.requireString(obj, p, "description")
+		v.requireString(obj, p, "input_requirements")
+		v.requireString(obj, p, "output_requirements")
+		v.requireNumber(obj, p, "estimated_cost")
+		v.requirePriority(obj, p)
+	case ListRoles:
+		v.requireString(obj, p, "title")
+		v.requireStringArray(obj, p, "responsibilities")
+		v.requireStringArray(obj, p, "requirements")
+		v.requireString(obj, p, "work_hours")
+		v.requireNumber(obj, p, "monthly_budget")
+		v.requirePriority(obj, p)
+	case ListPhases:
+		v.requireString(obj, p, "phase_name")
+		v.requireString(obj, p, "duration")
+		v.requireNumber(obj, p, "monthly_budget")
+		v.requireBreakdown(obj, p)
+	}
+}
+
+// ParseItem decodes and validates one workflow, role or phase sent by a
+// user, with the rules applied to model output. It returns *AIWorkflow,
+// *HumanRole or *Phase; the typed value is only returned when valid.
+func ParseItem(list string, raw []byte) (interface{}, ValidationErrors, error) {
+	var target interface{}
+	switch list {
+	case ListWorkflows:
+		target = &AIWorkflow{}
+	case ListRoles:
+		target = &HumanRole{}
+	case ListPhases:
+		target = &Phase{}
+	default:
+		return nil, nil, fmt.Errorf("unknown item list: %s", list)
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, nil, fmt.Errorf("invalid item JSON: %v", err)
+	}
+
+	v := &validator{}
+	v.item(list, doc, "")
+	if len(v.errs) > 0 {
+		return nil, v.errs, nil
+	}
+
+	if err := json.Unmarshal(raw, target); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode item: %v", err)
+	}
+	return target, nil, nil
+}
+
+func (v *validator) requireString(obj map[string]interface{}, path, key string) string {
+	p := join(path, key)
+	val, present := obj[key]
+	if !present || val == nil {
+		v.add(p, "is required")
+		return ""
+	}
+	s, ok := val.(string)
+	if !ok {
+		v.add(p, "must be a string")
+		return ""
+	}
+	if strings.TrimSpace(s) == "" {
+		v.add(p, "must not be empty")
+	}
+	return s
+}
+
+func (v *validator) requireNumber(obj map[string]interface{}, path, key string) {
+	p := join(path, key)
+	val, present := obj[key]
+	if !present || val == nil {
+		v.add(p, "is required")
+		return
+	}
+	n, ok := val.(float64)
+	if !ok {
+		v.add(p, "must be a number, got %s", describe(val))
+		return
+	}
+	if n < 0 {
+		v.add(p, "must not be negative")
+	}
+}
+
+func (v *validator) requireArray(obj map[string]interface{}, path, key string) []interface{} {
+	p := join(path, key)
+	val, present := obj[key]
+	if !present || val == nil {
+		v.add(p, "is required")
+		return nil
+	}
+	arr, ok := val.([]interface{})
+	if !ok {
+		v.add(p, "must be an array")
+		return nil
+	}
+	if len(arr) == 0 {
+		v.add(p, "must not be empty")
+	}
+	return arr
+}
+
+func (v *validator) requireStringArray(obj map[string]interface{}, path, key string) {
+	for i, item := range v.requireArray(obj, path, key) {
+		s, ok := item.(string)
+		if !ok || strings.TrimSpace(s) == "" {
+			v.add(fmt.Sprintf("%s[%d]", join(path, key), i), "must be a non-empty string")
+		}
+	}
+}
+
+func (v *validator) requirePriority(obj map[string]interface{}, path string) {
+	p := join(path, "priority")
+	val, present := obj["priority"]
+	if !present || val == nil {
+		v.add(p, "is required")
+		return
+	}
+	s, ok := val.(string)
+	if !ok || !allowedPriorities[s] {
+		v.add(p, "must be one of high, medium, low, got %s", describe(val))
+	}
+}
+
+func (v *validator) requireBreakdown(obj map[string]interface{}, path string) {
+	p := join(path, "budget_breakdown")
+	val, present := obj["budget_breakdown"]
+	if !present || val == nil {
+		v.add(p, "is required")
+		return
+	}
+	breakdown, ok := val.(map[string]interface{})
+	if !ok {
+		v.add(p, "must be an object, got %s", describe(val))
+		return
+	}
+	if len(breakdown) == 0 {
+		v.add(p, "must not be empty")
+	}
+	categories := make([]string, 0, len(breakdown))
+	for category := range breakdown {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	for _, category := range categories {
+		if _, ok := breakdown[category].(float64); !ok {
+			v.add(join(p, category), "must be a number, got %s", describe(breakdown[category]))
+		}
+	}
+}
+
+// join builds a dotted JSON path
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// describe renders a JSON value for error messages
+func describe(val interface{}) string {
+	switch x := val.(type) {
+	case string:
+		return fmt.Sprintf("string %q", x)
+	case float64:
+		return fmt.Sprintf("number %v", x)
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprintf("%T", val)
+	}
+}
@@ -0,0 +1,70 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/x-zero/business-consultant/pkg/db"
+)
+
+// ErrVersionConflict is returned when a report changed since the version the
+// client read
+var ErrVersionConflict = errors.New("report has been modified")
+
+// ErrReportNotFound is returned when a report does not exist
+var ErrReportNotFound = errors.New("report not found")
+
+// ETag returns the entity tag of a report version
+func ETag(version int) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// ParseIfMatch reads the version from an If-Match header. It returns nil
+// when the header is absent or "*", i.e. when any version matches.
+func ParseIfMatch(header string) (*int, error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return nil, nil
+	}
+	tag := strings.Trim(strings.TrimPrefix(header, "W/"), `"`)
+	version, err := strconv.Atoi(tag)
+	if err != nil {
+		return nil, fmt.Errorf("invalid If-Match header: %s", header)
+	}
+	return &version, nil
+}
+
+// BumpVersion increments the version of a report and touches updated_at.
+// When expected is set the update only happens if the report is still at
+// that version; otherwise ErrVersionConflict is returned together with the
+// current version. Run it in the transaction of the change it versions.
+func BumpVersion(ctx context.Context, q db.Querier, reportID string, expected *int) (int, error) {
+	var version int
+	err := q.QueryRow(ctx, `
+		UPDATE business_reports
+		SET version = version + 1, updated_at = NOW()
+		WHERE report_id = $1 AND ($2::int IS NULL OR version = $2::int)
+		RETURNING version
+	`, reportID, expected).Scan(&version)
+	if err == nil {
+		return version, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return 0, fmt.Errorf("failed to update report version: %v", err)
+	}
+
+	err = q.QueryRow(ctx, `
+		SELECT version FROM business_reports WHERE report_id = $1
+	`, reportID).Scan(&version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrReportNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read report version: %v", err)
+	}
+	return version, ErrVersionConflict
+}
@@ -1,7 +1,11 @@
 package response
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
 
 	"github.com/aws/aws-lambda-go/events"
 )
@@ -28,6 +32,16 @@ func Success(data interface{}) (events.APIGatewayProxyResponse, error) {
 	}, nil
 }
 
+// SuccessWithHeaders returns a successful API Gateway response with CORS
+// headers plus extra headers, e.g. ETag
+func SuccessWithHeaders(data interface{}, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	resp, err := Success(data)
+	for k, v := range headers {
+		resp.Headers[k] = v
+	}
+	return resp, err
+}
+
 // SuccessNoCORS returns a successful response without CORS headers (for Function URLs)
 func SuccessNoCORS(data interface{}) (events.APIGatewayProxyResponse, error) {
 	body, err := json.Marshal(map[string]interface{}{
@@ -81,3 +95,68 @@ func ErrorNoCORS(statusCode int, message string) (events.APIGatewayProxyResponse
 		Body: string(body),
 	}, nil
 }
+
+// ErrorWithDetails returns an error API Gateway response with CORS headers and
+// a details field, e.g. the list of validation errors
+func ErrorWithDetails(statusCode int, message string, details interface{}) (events.APIGatewayProxyResponse, error) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"success": false,
+		"error":   message,
+		"details": details,
+	})
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: statusCode,
+		Headers: map[string]string{
+			"Content-Type":                 "application/json",
+			"Access-Control-Allow-Origin":  "*",
+			"Access-Control-Allow-Headers": "Content-Type,Authorization",
+			"Access-Control-Allow-Methods": "GET,POST,PUT,DELETE,PATCH,OPTIONS",
+		},
+		Body: string(body),
+	}, nil
+}
+
+// ErrorWithHeaders returns an error API Gateway response with CORS headers
+// plus extra headers, e.g. Retry-After on 429
+func ErrorWithHeaders(statusCode int, message string, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	resp, err := Error(statusCode, message)
+	for k, v := range headers {
+		resp.Headers[k] = v
+	}
+	return resp, err
+}
+
+// File returns a file download with CORS headers. Binary content types are
+// base64 encoded for API Gateway, which must list them in BinaryMediaTypes.
+func File(data []byte, contentType, filename string) (events.APIGatewayProxyResponse, error) {
+	resp := events.APIGatewayProxyResponse{
+		StatusCode: 200,
+		Headers: map[string]string{
+			"Content-Type":                  contentType,
+			"Content-Disposition":           fmt.Sprintf(`attachment; filename="%s"; filename*=UTF-8''%s`, asciiName(filename), url.PathEscape(filename)),
+			"Access-Control-Allow-Origin":   "*",
+			"Access-Control-Allow-Headers":  "Content-Type,Authorization",
+			"Access-Control-Allow-Methods":  "GET,POST,PUT,DELETE,PATCH,OPTIONS",
+			"Access-Control-Expose-Headers": "Content-Disposition",
+		},
+	}
+	if strings.HasPrefix(contentType, "text/") {
+		resp.Body = string(data)
+	} else {
+		resp.Body = base64.StdEncoding.EncodeToString(data)
+		resp.IsBase64Encoded = true
+	}
+	return resp, nil
+}
+
+// asciiName replaces the characters of a filename that cannot appear in a
+// plain quoted header value, for clients ignoring filename*
+func asciiName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e || r == '"' || r == '\\' {
+			return '_'
+		}
+		return r
+	}, name)
+}
@@ -0,0 +1,70 @@
+package response
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// StreamSuccess returns a buffered JSON success response for Function URLs
+// configured with RESPONSE_STREAM invoke mode
+func StreamSuccess(data interface{}) (*events.LambdaFunctionURLStreamingResponse, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"success": true,
+		"data":    data,
+	})
+	if err != nil {
+		return StreamError(500, "Failed to marshal response")
+	}
+
+	return &events.LambdaFunctionURLStreamingResponse{
+		StatusCode: 200,
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+		Body: bytes.NewReader(body),
+	}, nil
+}
+
+// StreamError returns a buffered JSON error response for streaming Function URLs
+func StreamError(statusCode int, message string) (*events.LambdaFunctionURLStreamingResponse, error) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"success": false,
+		"error":   message,
+	})
+
+	return &events.LambdaFunctionURLStreamingResponse{
+		StatusCode: statusCode,
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+		Body: bytes.NewReader(body),
+	}, nil
+}
+
+// SSE returns a streaming Function URL response that forwards body to the
+// client as a text/event-stream
+func SSE(body io.Reader) *events.LambdaFunctionURLStreamingResponse {
+	return &events.LambdaFunctionURLStreamingResponse{
+		StatusCode: 200,
+		Headers: map[string]string{
+			"Content-Type":      "text/event-stream",
+			"Cache-Control":     "no-cache",
+			"Connection":        "keep-alive",
+			"X-Accel-Buffering": "no",
+		},
+		Body: body,
+	}
+}
+
+// StreamErrorWithHeaders returns a buffered JSON error response for streaming
+// Function URLs with extra headers, e.g. Retry-After on 429
+func StreamErrorWithHeaders(statusCode int, message string, headers map[string]string) (*events.LambdaFunctionURLStreamingResponse, error) {
+	resp, err := StreamError(statusCode, message)
+	for k, v := range headers {
+		resp.Headers[k] = v
+	}
+	return resp, err
+}
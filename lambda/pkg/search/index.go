@@ -0,0 +1,97 @@
+// Package search maintains the search columns of business_reports and
+// queries them.
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/x-zero/business-consultant/pkg/budget"
+	"github.com/x-zero/business-consultant/pkg/db"
+	"github.com/x-zero/business-consultant/pkg/report"
+)
+
+// Text returns the searchable text of a report: business goal, summary,
+// workflow names and descriptions and role titles, one per line
+func Text(businessGoal string, recs *report.Recommendations) string {
+	parts := []string{businessGoal, recs.Summary}
+	for _, wf := range recs.AIWorkflows {
+		parts = append(parts, wf.Name, wf.Description)
+	}
+	for _, role := range recs.HumanRoles {
+		parts = append(parts, role.Title)
+	}
+	return strings.Join(parts, "\n")
+}
+
+// Index recomputes the search text and budget columns of a report. Call it
+// in the transaction of any change to the report content; it does not
+// touch updated_at or the version.
+func Index(ctx context.Context, q db.Querier, reportID string) error {
+	var businessGoal string
+	var stored, validationErrors []byte
+	err := q.QueryRow(ctx, `
+		SELECT business_goal, recommendations, validation_errors
+		FROM business_reports
+		WHERE report_id = $1
+	`, reportID).Scan(&businessGoal, &stored, &validationErrors)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return report.ErrReportNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read report: %v", err)
+	}
+
+	valid := validationErrors == nil
+	current, err := report.Current(ctx, q, reportID, stored, valid)
+	if err != nil {
+		return err
+	}
+
+	// Invalid reports are searchable by whatever text decodes, but have no
+	// budget figures
+	var recs report.Recommendations
+	json.Unmarshal(current, &recs)
+	var total, monthly *float64
+	if valid {
+		summary := budget.Compute(&recs)
+		total, monthly = &summary.TotalBudget, &summary.PeakMonthlyBudget
+	}
+
+	_, err = q.Exec(ctx, `
+		UPDATE business_reports
+		SET search_text = $2, total_budget = $3, monthly_budget = $4
+		WHERE report_id = $1
+	`, reportID, Text(businessGoal, &recs), total, monthly)
+	if err != nil {
+		return fmt.Errorf("failed to index report: %v", err)
+	}
+	return nil
+}
+
+// IndexNext indexes one report saved before the search columns existed,
+// trashed reports included. It reports false when none is left. Must run in
+// a transaction.
+func IndexNext(ctx context.Context, q db.Querier) (bool, error) {
+	var reportID string
+	err := q.QueryRow(ctx, `
+		SELECT report_id FROM business_reports
+		WHERE search_text IS NULL
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED
+	`).Scan(&reportID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to query unindexed reports: %v", err)
+	}
+	if err := Index(ctx, q, reportID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,215 @@
+package search
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/x-zero/business-consultant/pkg/access"
+	"github.com/x-zero/business-consultant/pkg/db"
+	"github.com/x-zero/business-consultant/pkg/report"
+)
+
+// Sort keys of List
+const (
+	SortCreatedAt   = "created_at"
+	SortUpdatedAt   = "updated_at"
+	SortTotalBudget = "total_budget"
+)
+
+// ErrInvalidCursor is returned for cursors List did not issue for the same
+// sort key
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// sortColumns maps sort keys to their SQL expression and type. Reports
+// without a computed budget sort as 0.
+var sortColumns = map[string]struct{ expr, typ string }{
+	SortCreatedAt:   {"r.created_at", "timestamp"},
+	SortUpdatedAt:   {"r.updated_at", "timestamp"},
+	SortTotalBudget: {"COALESCE(r.total_budget, 0)", "numeric"},
+}
+
+// ListOptions selects a page of the reports of a project the user may view
+type ListOptions struct {
+	UserDID   string
+	ProjectID string
+	Sort      string
+	Ascending bool
+	Limit     int
+	Cursor    string // next_cursor of the previous page
+	Full      bool   // include the recommendations
+}
+
+// ReportSummary is a report without its recommendations body, unless
+// ListOptions.Full is set
+type ReportSummary struct {
+	ReportID        string          `json:"report_id"`
+	UserDID         string          `json:"user_did"` // creator, other project members may list it too
+	ProjectID       string          `json:"project_id"`
+	BusinessGoal    string          `json:"business_goal"`
+	Summary         string          `json:"summary"`
+	WorkflowCount   int             `json:"workflow_count"`
+	RoleCount       int             `json:"role_count"`
+	PhaseCount      int             `json:"phase_count"`
+	PublishedCount  int             `json:"published_count"`
+	TotalBudget     *float64        `json:"total_budget"`
+	MonthlyBudget   *float64        `json:"monthly_budget"`
+	Valid           bool            `json:"valid"`
+	Version         int             `json:"version"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+	Recommendations json.RawMessage `json:"recommendations,omitempty"`
+}
+
+// cursor is the position after the last report of a page
+type cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+// List returns one page of reports and the cursor of the next page, which
+// is empty on the last page. Pages are keyed on the sort value and the
+// report ID, so reports saved while paging neither repeat nor go missing.
+func List(ctx context.Context, q db.Querier, opts ListOptions) ([]ReportSummary, string, error) {
+	col, ok := sortColumns[opts.Sort]
+	if !ok {
+		return nil, "", fmt.Errorf("unknown sort key: %s", opts.Sort)
+	}
+	dir, cmp := "DESC", "<"
+	if opts.Ascending {
+		dir, cmp = "ASC", ">"
+	}
+
+	args := []interface{}{opts.UserDID, opts.ProjectID, opts.Limit + 1}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	after := ""
+	if opts.Cursor != "" {
+		c, err := decodeCursor(opts.Cursor)
+		if err != nil || c.Sort != opts.Sort {
+			return nil, "", ErrInvalidCursor
+		}
+		after = fmt.Sprintf("AND (%s, r.report_id) %s (%s::%s, %s::uuid)", col.expr, cmp, arg(c.Value), col.typ, arg(c.ID))
+	}
+	recommendations := "NULL::jsonb"
+	if opts.Full {
+		recommendations = "r.recommendations"
+	}
+
+	rows, err := q.Query(ctx, `
+		SELECT r.report_id, r.user_did, r.project_id, r.business_goal, COALESCE(r.recommendations->>'summary', ''),
+		       COALESCE((SELECT NULLIF(COUNT(*), 0) FROM report_workflows w WHERE w.report_id = r.report_id), `+jsonLength("ai_workflows")+`),
+		       COALESCE((SELECT NULLIF(COUNT(*), 0) FROM report_roles h WHERE h.report_id = r.report_id), `+jsonLength("human_roles")+`),
+		       COALESCE((SELECT NULLIF(COUNT(*), 0) FROM report_phases p WHERE p.report_id = r.report_id), `+jsonLength("phases")+`),
+		       (SELECT COUNT(*) FROM report_workflows w WHERE w.report_id = r.report_id AND w.status = 'published')
+		       + (SELECT COUNT(*) FROM report_roles h WHERE h.report_id = r.report_id AND h.status = 'published'),
+		       r.total_budget::float8, r.monthly_budget::float8, r.validation_errors IS NULL, r.version,
+		       r.created_at, r.updated_at, `+col.expr+`::text, `+recommendations+`
+		FROM business_reports r
+		WHERE `+access.Visible("r.", "$1")+` AND r.project_id = $2::uuid AND r.deleted_at IS NULL `+after+`
+		ORDER BY `+col.expr+` `+dir+`, r.report_id `+dir+`
+		LIMIT $3
+	`, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to query reports: %v", err)
+	}
+
+	reports := []ReportSummary{}
+	var sortValues []string
+	for rows.Next() {
+		var r ReportSummary
+		var sortValue string
+		var stored []byte
+		if err := rows.Scan(&r.ReportID, &r.UserDID, &r.ProjectID, &r.BusinessGoal, &r.Summary,
+			&r.WorkflowCount, &r.RoleCount, &r.PhaseCount, &r.PublishedCount,
+			&r.TotalBudget, &r.MonthlyBudget, &r.Valid, &r.Version,
+			&r.CreatedAt, &r.UpdatedAt, &sortValue, &stored); err != nil {
+			rows.Close()
+			return nil, "", fmt.Errorf("failed to scan report: %v", err)
+		}
+		r.Recommendations = stored
+		reports = append(reports, r)
+		sortValues = append(sortValues, sortValue)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("failed to query reports: %v", err)
+	}
+
+	next := ""
+	if len(reports) > opts.Limit {
+		reports = reports[:opts.Limit]
+		last := reports[len(reports)-1]
+		next = encodeCursor(cursor{Sort: opts.Sort, Value: sortValues[opts.Limit-1], ID: last.ReportID})
+	}
+
+	// Items and their statuses are read from the item tables
+	if opts.Full {
+		for i := range reports {
+			r := &reports[i]
+			if r.Recommendations, err = report.Current(ctx, q, r.ReportID, r.Recommendations, r.Valid); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+
+	return reports, next, nil
+}
+
+// jsonLength counts a list of the stored recommendations, for reports
+// without item rows
+func jsonLength(list string) string {
+	return fmt.Sprintf(`CASE WHEN jsonb_typeof(r.recommendations->'%[1]s') = 'array'
+		            THEN jsonb_array_length(r.recommendations->'%[1]s') ELSE 0 END`, list)
+}
+
+func encodeCursor(c cursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// numericText matches numeric values as Postgres prints them
+var numericText = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// decodeCursor reads a cursor and checks that its values can be cast to the
+// types of the query, so a tampered cursor is rejected instead of failing
+// the query
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, err
+	}
+
+	col, ok := sortColumns[c.Sort]
+	if !ok {
+		return c, fmt.Errorf("unknown sort key: %s", c.Sort)
+	}
+	switch col.typ {
+	case "timestamp":
+		_, err = time.Parse("2006-01-02 15:04:05.999999", c.Value)
+	case "numeric":
+		if !numericText.MatchString(c.Value) {
+			err = fmt.Errorf("not a number: %q", c.Value)
+		}
+	}
+	if err != nil {
+		return c, fmt.Errorf("invalid sort value: %v", err)
+	}
+	if _, err := uuid.Parse(c.ID); err != nil {
+		return c, fmt.Errorf("invalid report ID: %v", err)
+	}
+	return c, nil
+}
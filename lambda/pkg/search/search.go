@@ -0,0 +1,148 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/x-zero/business-consultant/pkg/access"
+	"github.com/x-zero/business-consultant/pkg/db"
+	"github.com/x-zero/business-consultant/pkg/report"
+)
+
+// StatusNone filters reports with items that have not been acted on
+const StatusNone = "none"
+
+// Filter selects the reports a user may view. Zero values do not filter; To is
+// exclusive. Status and Priority match reports with at least one workflow
+// or role that has both.
+type Filter struct {
+	UserDID   string
+	Query     string // every whitespace separated term must occur
+	ProjectID string
+	From      time.Time
+	To        time.Time
+	MinBudget *float64 // total_budget
+	MaxBudget *float64
+	Status    string // draft_created, published or none
+	Priority  string
+	Limit     int
+	Offset    int
+}
+
+// Result is a matching report. Score is the trigram word similarity of the
+// query to the report, 0 without a query.
+type Result struct {
+	ReportID      string    `json:"report_id"`
+	UserDID       string    `json:"user_did"`
+	ProjectID     string    `json:"project_id"`
+	BusinessGoal  string    `json:"business_goal"`
+	Summary       string    `json:"summary"`
+	TotalBudget   *float64  `json:"total_budget"`
+	MonthlyBudget *float64  `json:"monthly_budget"`
+	Version       int       `json:"version"`
+	Score         float64   `json:"score"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Search returns the reports matching f, best match first, newest first
+// without a query, and the total number of matches
+func Search(ctx context.Context, q db.Querier, f Filter) ([]Result, int, error) {
+	args := []interface{}{f.UserDID}
+	conds := []string{access.Visible("r.", "$1"), "r.deleted_at IS NULL"}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	for _, term := range strings.Fields(f.Query) {
+		conds = append(conds, "r.search_text ILIKE "+arg("%"+escapeLike(term)+"%"))
+	}
+	if f.ProjectID != "" {
+		conds = append(conds, "r.project_id = "+arg(f.ProjectID)+"::uuid")
+	}
+	if !f.From.IsZero() {
+		conds = append(conds, "r.created_at >= "+arg(f.From))
+	}
+	if !f.To.IsZero() {
+		conds = append(conds, "r.created_at < "+arg(f.To))
+	}
+	if f.MinBudget != nil {
+		conds = append(conds, "r.total_budget >= "+arg(*f.MinBudget))
+	}
+	if f.MaxBudget != nil {
+		conds = append(conds, "r.total_budget <= "+arg(*f.MaxBudget))
+	}
+
+	if f.Status != "" || f.Priority != "" {
+		var itemConds []string
+		switch f.Status {
+		case "":
+		case StatusNone:
+			itemConds = append(itemConds, "i.status IS NULL")
+		default:
+			itemConds = append(itemConds, "i.status = "+arg(f.Status))
+		}
+		if f.Priority != "" {
+			itemConds = append(itemConds, "i.priority = "+arg(f.Priority))
+		}
+		conds = append(conds, `EXISTS (
+			SELECT 1 FROM (
+				SELECT report_id, status, priority FROM report_workflows
+				UNION ALL
+				SELECT report_id, status, priority FROM report_roles
+			) i
+			WHERE i.report_id = r.report_id AND `+strings.Join(itemConds, " AND ")+`
+		)`)
+	}
+
+	where := strings.Join(conds, " AND ")
+
+	var total int
+	if err := q.QueryRow(ctx, `SELECT COUNT(*) FROM business_reports r WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count reports: %v", err)
+	}
+
+	score, order := "0::float8", "r.created_at DESC"
+	if query := strings.TrimSpace(f.Query); query != "" {
+		score = "word_similarity(" + arg(query) + ", r.search_text)::float8"
+		order = "score DESC, r.created_at DESC"
+	}
+	limit, offset := arg(f.Limit), arg(f.Offset)
+
+	rows, err := q.Query(ctx, `
+		SELECT r.report_id, r.user_did, r.project_id, r.business_goal, COALESCE(r.recommendations->>'summary', ''),
+		       r.total_budget::float8, r.monthly_budget::float8, r.version, `+score+` AS score,
+		       r.created_at, r.updated_at
+		FROM business_reports r
+		WHERE `+where+`
+		ORDER BY `+order+`
+		LIMIT `+limit+` OFFSET `+offset, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search reports: %v", err)
+	}
+	defer rows.Close()
+
+	results := []Result{}
+	for rows.Next() {
+		var r Result
+		if err := rows.Scan(&r.ReportID, &r.UserDID, &r.ProjectID, &r.BusinessGoal, &r.Summary, &r.TotalBudget, &r.MonthlyBudget,
+			&r.Version, &r.Score, &r.CreatedAt, &r.UpdatedAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan report: %v", err)
+		}
+		results = append(results, r)
+	}
+	return results, total, rows.Err()
+}
+
+// ValidStatus reports whether status can be used as a filter
+func ValidStatus(status string) bool {
+	return status == "" || status == StatusNone || report.ValidStatus(&status)
+}
+
+// escapeLike escapes the ILIKE wildcards of a search term
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,288 @@
+// Package share manages read-only share links of reports
+package share
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/x-zero/business-consultant/pkg/db"
+)
+
+// Outcomes of a visit to a share link, recorded in report_share_access
+const (
+	OutcomeViewed           = "viewed"
+	OutcomePasswordRequired = "password_required"
+	OutcomeWrongPassword    = "wrong_password"
+	OutcomeExpired          = "expired"
+	OutcomeRevoked          = "revoked"
+	OutcomeThrottled        = "throttled"
+)
+
+// Errors of Open and Revoke
+var (
+	ErrNotFound         = errors.New("share link not found")
+	ErrRevoked          = errors.New("share link has been revoked")
+	ErrExpired          = errors.New("share link has expired")
+	ErrPasswordRequired = errors.New("password required")
+	ErrWrongPassword    = errors.New("incorrect password")
+	ErrThrottled        = errors.New("too many incorrect passwords, try again later")
+)
+
+// Password attempts of a share link are refused once maxFailures wrong
+// passwords were given from the same source IP within FailureWindow, so one
+// visitor guessing cannot lock out the others
+const (
+	maxFailures   = 10
+	FailureWindow = 15 * time.Minute
+)
+
+// Share is a share link as listed to those who manage it. The token itself is
+// only returned by Create.
+type Share struct {
+	ShareID        string     `json:"share_id"`
+	ReportID       string     `json:"report_id"`
+	Label          string     `json:"label"`
+	HasPassword    bool       `json:"has_password"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+	RevokedAt      *time.Time `json:"revoked_at"`
+	Active         bool       `json:"active"` // neither revoked nor expired
+	AccessCount    int        `json:"access_count"`
+	LastAccessedAt *time.Time `json:"last_accessed_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// Options of a new share link. A zero ExpiresInDays never expires and an
+// empty Password lets anyone with the link view the report.
+type Options struct {
+	Label         string
+	Password      string
+	ExpiresInDays int
+}
+
+// Visitor identifies the client of a share link in the access log
+type Visitor struct {
+	SourceIP  string
+	UserAgent string
+}
+
+// Access is an entry of the access log of a share link
+type Access struct {
+	Outcome    string    `json:"outcome"`
+	SourceIP   string    `json:"source_ip"`
+	UserAgent  string    `json:"user_agent"`
+	AccessedAt time.Time `json:"accessed_at"`
+}
+
+// hashToken returns the stored form of a token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Create adds a share link to a report and returns it with its token
+func Create(ctx context.Context, q db.Querier, reportID, userDID string, opts Options) (*Share, string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, "", fmt.Errorf("failed to generate token: %v", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	var passwordHash *string
+	if opts.Password != "" {
+		hash, err := hashPassword(opts.Password)
+		if err != nil {
+			return nil, "", err
+		}
+		passwordHash = &hash
+	}
+
+	// Expiry is computed by the database, like the other timestamps
+	var validFor *string
+	if opts.ExpiresInDays > 0 {
+		interval := fmt.Sprintf("%d days", opts.ExpiresInDays)
+		validFor = &interval
+	}
+
+	s := &Share{ReportID: reportID, Label: opts.Label, HasPassword: passwordHash != nil, Active: true}
+	err := q.QueryRow(ctx, `
+		INSERT INTO report_shares (report_id, user_did, token_hash, password_hash, label, expires_at)
+		VALUES ($1, $2, $3, $4, $5, NOW() + $6::interval)
+		RETURNING share_id, expires_at, created_at
+	`, reportID, userDID, hashToken(token), passwordHash, opts.Label, validFor).Scan(&s.ShareID, &s.ExpiresAt, &s.CreatedAt)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create share link: %v", err)
+	}
+	return s, token, nil
+}
+
+// List returns the share links of a report, newest first
+func List(ctx context.Context, q db.Querier, reportID string) ([]Share, error) {
+	rows, err := q.Query(ctx, `
+		SELECT share_id, report_id, label, password_hash IS NOT NULL, expires_at, revoked_at,
+		       revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW()),
+		       access_count, last_accessed_at, created_at
+		FROM report_shares
+		WHERE report_id = $1
+		ORDER BY created_at DESC
+	`, reportID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query share links: %v", err)
+	}
+	defer rows.Close()
+
+	shares := []Share{}
+	for rows.Next() {
+		var s Share
+		if err := rows.Scan(&s.ShareID, &s.ReportID, &s.Label, &s.HasPassword, &s.ExpiresAt, &s.RevokedAt,
+			&s.Active, &s.AccessCount, &s.LastAccessedAt, &s.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan share link: %v", err)
+		}
+		shares = append(shares, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query share links: %v", err)
+	}
+	return shares, nil
+}
+
+// Revoke disables a share link of a report. Revoking a revoked link keeps
+// its original revocation time.
+func Revoke(ctx context.Context, q db.Querier, reportID, shareID string) error {
+	tag, err := q.Exec(ctx, `
+		UPDATE report_shares
+		SET revoked_at = COALESCE(revoked_at, NOW())
+		WHERE report_id = $1 AND share_id = $2
+	`, reportID, shareID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke share link: %v", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListAccess returns the most recent entries of the access log of a share
+// link of a report
+func ListAccess(ctx context.Context, q db.Querier, reportID, shareID string, limit int) ([]Access, error) {
+	rows, err := q.Query(ctx, `
+		SELECT a.outcome, a.source_ip, a.user_agent, a.accessed_at
+		FROM report_share_access a
+		JOIN report_shares s ON s.share_id = a.share_id
+		WHERE s.report_id = $1 AND a.share_id = $2
+		ORDER BY a.accessed_at DESC, a.access_id DESC
+		LIMIT $3
+	`, reportID, shareID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query access log: %v", err)
+	}
+	defer rows.Close()
+
+	log := []Access{}
+	for rows.Next() {
+		var a Access
+		if err := rows.Scan(&a.Outcome, &a.SourceIP, &a.UserAgent, &a.AccessedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan access: %v", err)
+		}
+		log = append(log, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query access log: %v", err)
+	}
+	return log, nil
+}
+
+// Open checks a token and password and returns the ID of the shared report.
+// Every visit to an existing link is logged, refused ones included. Links of
+// reports in the trash are not found.
+func Open(ctx context.Context, q db.Querier, token, password string, v Visitor) (string, error) {
+	var shareID, reportID string
+	var passwordHash *string
+	var revoked, expired bool
+	err := q.QueryRow(ctx, `
+		SELECT s.share_id, s.report_id, s.password_hash, s.revoked_at IS NOT NULL,
+		       s.expires_at IS NOT NULL AND s.expires_at <= NOW()
+		FROM report_shares s
+		JOIN business_reports r ON r.report_id = s.report_id
+		WHERE s.token_hash = $1 AND r.deleted_at IS NULL
+	`, hashToken(token)).Scan(&shareID, &reportID, &passwordHash, &revoked, &expired)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read share link: %v", err)
+	}
+
+	refuse := func(outcome string, reason error) (string, error) {
+		if err := record(ctx, q, shareID, outcome, v); err != nil {
+			return "", err
+		}
+		return "", reason
+	}
+
+	switch {
+	case revoked:
+		return refuse(OutcomeRevoked, ErrRevoked)
+	case expired:
+		return refuse(OutcomeExpired, ErrExpired)
+	}
+
+	if passwordHash != nil {
+		var failures int
+		err := q.QueryRow(ctx, `
+			SELECT COUNT(*) FROM report_share_access
+			WHERE share_id = $1 AND source_ip = $2 AND outcome = $3 AND accessed_at > NOW() - $4::interval
+		`, shareID, truncate(v.SourceIP, 64), OutcomeWrongPassword, fmt.Sprintf("%d seconds", int64(FailureWindow.Seconds()))).Scan(&failures)
+		if err != nil {
+			return "", fmt.Errorf("failed to count password attempts: %v", err)
+		}
+		switch {
+		case failures >= maxFailures:
+			return refuse(OutcomeThrottled, ErrThrottled)
+		case password == "":
+			return refuse(OutcomePasswordRequired, ErrPasswordRequired)
+		case !checkPassword(password, *passwordHash):
+			return refuse(OutcomeWrongPassword, ErrWrongPassword)
+		}
+	}
+
+	if err := record(ctx, q, shareID, OutcomeViewed, v); err != nil {
+		return "", err
+	}
+	_, err = q.Exec(ctx, `
+		UPDATE report_shares
+		SET access_count = access_count + 1, last_accessed_at = NOW()
+		WHERE share_id = $1
+	`, shareID)
+	if err != nil {
+		return "", fmt.Errorf("failed to update share link: %v", err)
+	}
+	return reportID, nil
+}
+
+// record adds a visit to the access log
+func record(ctx context.Context, q db.Querier, shareID, outcome string, v Visitor) error {
+	_, err := q.Exec(ctx, `
+		INSERT INTO report_share_access (share_id, outcome, source_ip, user_agent)
+		VALUES ($1, $2, $3, $4)
+	`, shareID, outcome, truncate(v.SourceIP, 64), truncate(v.UserAgent, 512))
+	if err != nil {
+		return fmt.Errorf("failed to log share access: %v", err)
+	}
+	return nil
+}
+
+// truncate shortens s to at most n runes
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
@@ -0,0 +1,62 @@
+package share
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/x-zero/business-consultant/pkg/budget"
+	"github.com/x-zero/business-consultant/pkg/db"
+	"github.com/x-zero/business-consultant/pkg/report"
+)
+
+// SharedReport is the read-only view of a report served through a share
+// link. It leaves out what only project members see: the report, user, project
+// and conversation IDs, validation errors and the IDs of published tasks.
+type SharedReport struct {
+	BusinessGoal    string                  `json:"business_goal"`
+	Recommendations *report.Recommendations `json:"recommendations"`
+	Budget          *budget.Summary         `json:"budget,omitempty"`
+	Version         int                     `json:"version"`
+	CreatedAt       time.Time               `json:"created_at"`
+	UpdatedAt       time.Time               `json:"updated_at"`
+}
+
+// View returns the shared view of a report opened with Open
+func View(ctx context.Context, q db.Querier, reportID string) (*SharedReport, error) {
+	v := &SharedReport{}
+	var recommendations, validationErrors []byte
+	err := q.QueryRow(ctx, `
+		SELECT business_goal, recommendations, validation_errors, version, created_at, updated_at
+		FROM business_reports
+		WHERE report_id = $1 AND deleted_at IS NULL
+	`, reportID).Scan(&v.BusinessGoal, &recommendations, &validationErrors, &v.Version, &v.CreatedAt, &v.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report: %v", err)
+	}
+
+	current, err := report.Current(ctx, q, reportID, recommendations, validationErrors == nil)
+	if err != nil {
+		return nil, err
+	}
+	var recs report.Recommendations
+	if err := json.Unmarshal(current, &recs); err != nil {
+		return nil, fmt.Errorf("failed to parse recommendations: %v", err)
+	}
+
+	for i := range recs.AIWorkflows {
+		recs.AIWorkflows[i].TaskID = nil
+	}
+	for i := range recs.HumanRoles {
+		recs.HumanRoles[i].TaskID = nil
+	}
+	recs.ItemStatuses = nil
+	v.Recommendations = &recs
+
+	// Budgets are only derived from valid reports
+	if validationErrors == nil {
+		v.Budget = budget.Compute(&recs)
+	}
+	return v, nil
+}
@@ -0,0 +1,111 @@
+package sse
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Event is a single dispatched server-sent event
+type Event struct {
+	ID    string
+	Event string
+	Data  string
+	Retry int
+}
+
+// Reader decodes a text/event-stream. Lines are buffered across reads, so
+// fields split over several network packets are reassembled before dispatch.
+type Reader struct {
+	r      *bufio.Reader
+	lastID string
+}
+
+// NewReader creates a new SSE reader
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// Next returns the next event with a non-empty data field. It returns io.EOF
+// once the stream is exhausted; an event still pending at EOF is dispatched
+// first so a truncated final frame is surfaced rather than dropped.
+func (r *Reader) Next() (*Event, error) {
+	var (
+		data    strings.Builder
+		hasData bool
+		event   string
+		retry   int
+	)
+
+	dispatch := func() *Event {
+		if !hasData {
+			event, retry = "", 0
+			return nil
+		}
+		ev := &Event{ID: r.lastID, Event: event, Data: data.String(), Retry: retry}
+		data.Reset()
+		hasData, event, retry = false, "", 0
+		return ev
+	}
+
+	for {
+		line, err := r.r.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		atEOF := err == io.EOF
+		if atEOF && line == "" {
+			if ev := dispatch(); ev != nil {
+				return ev, nil
+			}
+			return nil, io.EOF
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+
+		// A blank line terminates the current event
+		if line == "" {
+			if ev := dispatch(); ev != nil {
+				return ev, nil
+			}
+			continue
+		}
+
+		// Lines starting with a colon are comments (keep-alives)
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value := line, ""
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+
+		switch field {
+		case "data":
+			if hasData {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+			hasData = true
+		case "event":
+			event = value
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				r.lastID = value
+			}
+		case "retry":
+			if n, err := strconv.Atoi(value); err == nil {
+				retry = n
+			}
+		}
+
+		if atEOF {
+			if ev := dispatch(); ev != nil {
+				return ev, nil
+			}
+			return nil, io.EOF
+		}
+	}
+}
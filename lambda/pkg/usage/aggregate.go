@@ -0,0 +1,112 @@
+package usage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/x-zero/business-consultant/pkg/db"
+)
+
+// Aggregation granularities
+const (
+	Daily   = "daily"
+	Monthly = "monthly"
+)
+
+// Filter selects the usage rows to aggregate. ProjectID is optional; To is
+// exclusive.
+type Filter struct {
+	UserDID   string
+	ProjectID string
+	From      time.Time
+	To        time.Time
+}
+
+// Bucket is the usage of one day or month
+type Bucket struct {
+	Period           string  `json:"period"` // 2006-01-02 or 2006-01
+	Calls            int     `json:"calls"`
+	Errors           int     `json:"errors"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	TotalTokens      int64   `json:"total_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+	AvgLatencyMs     int     `json:"avg_latency_ms"`
+}
+
+// Aggregate sums usage per day or month (UTC), oldest first
+func Aggregate(ctx context.Context, q db.Querier, f Filter, granularity string) ([]Bucket, error) {
+	unit, format := "day", "YYYY-MM-DD"
+	switch granularity {
+	case Daily:
+	case Monthly:
+		unit, format = "month", "YYYY-MM"
+	default:
+		return nil, fmt.Errorf("unknown granularity: %s", granularity)
+	}
+
+	rows, err := q.Query(ctx, `
+		SELECT to_char(date_trunc('`+unit+`', created_at), '`+format+`') AS period,
+		       COUNT(*),
+		       COUNT(error_kind),
+		       COALESCE(SUM(prompt_tokens), 0),
+		       COALESCE(SUM(completion_tokens), 0),
+		       COALESCE(SUM(total_tokens), 0),
+		       COALESCE(SUM(cost_usd), 0)::float8,
+		       COALESCE(AVG(latency_ms), 0)::int
+		FROM llm_usage
+		WHERE user_did = $1
+		  AND ($2 = '' OR project_id = NULLIF($2, '')::uuid)
+		  AND created_at >= $3 AND created_at < $4
+		GROUP BY period
+		ORDER BY period
+	`, f.UserDID, f.ProjectID, f.From, f.To)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query usage: %v", err)
+	}
+	defer rows.Close()
+
+	buckets := []Bucket{}
+	for rows.Next() {
+		var b Bucket
+		if err := rows.Scan(&b.Period, &b.Calls, &b.Errors, &b.PromptTokens, &b.CompletionTokens, &b.TotalTokens, &b.CostUSD, &b.AvgLatencyMs); err != nil {
+			return nil, fmt.Errorf("failed to scan usage: %v", err)
+		}
+		buckets = append(buckets, b)
+	}
+	return buckets, rows.Err()
+}
+
+// Total sums buckets into a single bucket without period
+func Total(buckets []Bucket) Bucket {
+	var total Bucket
+	var latency int64
+	for _, b := range buckets {
+		total.Calls += b.Calls
+		total.Errors += b.Errors
+		total.PromptTokens += b.PromptTokens
+		total.CompletionTokens += b.CompletionTokens
+		total.TotalTokens += b.TotalTokens
+		total.CostUSD += b.CostUSD
+		latency += int64(b.AvgLatencyMs) * int64(b.Calls)
+	}
+	if total.Calls > 0 {
+		total.AvgLatencyMs = int(latency / int64(total.Calls))
+	}
+	return total
+}
+
+// TokensSince returns the tokens a user consumed since the given time
+func TokensSince(ctx context.Context, q db.Querier, userDID string, since time.Time) (int64, error) {
+	var tokens int64
+	err := q.QueryRow(ctx, `
+		SELECT COALESCE(SUM(total_tokens), 0)
+		FROM llm_usage
+		WHERE user_did = $1 AND created_at >= $2
+	`, userDID, since).Scan(&tokens)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum usage: %v", err)
+	}
+	return tokens, nil
+}
@@ -0,0 +1,59 @@
+package usage
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Price is the cost of a model in USD per million tokens
+type Price struct {
+	Input  float64
+	Output float64
+}
+
+// prices of the hosted models we use, by model name prefix. Local models
+// cost nothing per token.
+var prices = map[string]Price{
+	"deepseek-chat":     {Input: 0.28, Output: 0.42},
+	"deepseek-reasoner": {Input: 0.28, Output: 0.42},
+	"gpt-4o-mini":       {Input: 0.15, Output: 0.60},
+	"gpt-4o":            {Input: 2.50, Output: 10.00},
+	"gpt-4.1-mini":      {Input: 0.40, Output: 1.60},
+	"gpt-4.1":           {Input: 2.00, Output: 8.00},
+}
+
+// PriceOf returns the price of a model. LLM_PRICE_INPUT and LLM_PRICE_OUTPUT
+// (USD per million tokens) override the table, e.g. after a price change.
+func PriceOf(provider, model string) Price {
+	var price Price
+	if provider != "local" {
+		price = lookup(model)
+	}
+
+	if v, err := strconv.ParseFloat(os.Getenv("LLM_PRICE_INPUT"), 64); err == nil && v >= 0 {
+		price.Input = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("LLM_PRICE_OUTPUT"), 64); err == nil && v >= 0 {
+		price.Output = v
+	}
+	return price
+}
+
+// lookup finds the longest model name prefix in the price table, so dated
+// snapshots such as "gpt-4o-mini-2024-07-18" share the base price
+func lookup(model string) Price {
+	var best string
+	for name := range prices {
+		if strings.HasPrefix(model, name) && len(name) > len(best) {
+			best = name
+		}
+	}
+	return prices[best]
+}
+
+// EstimateCost returns the USD cost of a call
+func EstimateCost(provider, model string, promptTokens, completionTokens int) float64 {
+	price := PriceOf(provider, model)
+	return (float64(promptTokens)*price.Input + float64(completionTokens)*price.Output) / 1e6
+}
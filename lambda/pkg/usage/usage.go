@@ -0,0 +1,104 @@
+package usage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/x-zero/business-consultant/pkg/db"
+	"github.com/x-zero/business-consultant/pkg/deepseek"
+)
+
+// Scope attributes model calls to a user, project and endpoint
+type Scope struct {
+	UserDID   string
+	ProjectID string // optional, stored only when it is a UUID
+	Endpoint  string
+}
+
+type scopeKey struct{}
+
+// WithScope returns a context whose model calls are recorded against scope
+func WithScope(ctx context.Context, scope Scope) context.Context {
+	return context.WithValue(ctx, scopeKey{}, scope)
+}
+
+// ScopeFrom returns the scope set with WithScope
+func ScopeFrom(ctx context.Context) (Scope, bool) {
+	scope, ok := ctx.Value(scopeKey{}).(Scope)
+	return scope, ok
+}
+
+// Record describes one model call
+type Record struct {
+	Provider         string
+	Model            string
+	CallType         string // json / text / stream
+	PromptTokens     int
+	CompletionTokens int
+	Latency          time.Duration
+	ErrorKind        string // empty on success
+}
+
+// recordTimeout bounds the insert, which may run after the call's own
+// context expired
+const recordTimeout = 5 * time.Second
+
+// Track stores rec against the scope of ctx. Calls without a scope are only
+// logged, and storage failures never fail the call being recorded.
+func Track(ctx context.Context, rec Record) {
+	scope, ok := ScopeFrom(ctx)
+	if !ok {
+		fmt.Printf("LLM usage without scope: %s/%s %d+%d tokens\n", rec.Provider, rec.Model, rec.PromptTokens, rec.CompletionTokens)
+		return
+	}
+
+	if err := db.InitDB(); err != nil {
+		fmt.Printf("Failed to record LLM usage: %v\n", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), recordTimeout)
+	defer cancel()
+
+	if err := Save(ctx, db.GetPool(), scope, rec); err != nil {
+		fmt.Printf("Failed to record LLM usage: %v\n", err)
+	}
+}
+
+// Recorder is a deepseek.CallHook storing every model call with Track
+func Recorder(ctx context.Context, call deepseek.Call) {
+	Track(ctx, Record{
+		Provider:         call.Provider,
+		Model:            call.Model,
+		CallType:         call.Type,
+		PromptTokens:     call.PromptTokens,
+		CompletionTokens: call.CompletionTokens,
+		Latency:          call.Latency,
+		ErrorKind:        call.ErrorKind,
+	})
+}
+
+// Save inserts a usage row, pricing the call with EstimateCost
+func Save(ctx context.Context, q db.Querier, scope Scope, rec Record) error {
+	var projectID, errorKind *string
+	if _, err := uuid.Parse(scope.ProjectID); err == nil {
+		projectID = &scope.ProjectID
+	}
+	if rec.ErrorKind != "" {
+		errorKind = &rec.ErrorKind
+	}
+
+	_, err := q.Exec(ctx, `
+		INSERT INTO llm_usage (user_did, project_id, endpoint, provider, model, call_type,
+		                       prompt_tokens, completion_tokens, latency_ms, cost_usd, error_kind)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`, scope.UserDID, projectID, scope.Endpoint, rec.Provider, rec.Model, rec.CallType,
+		rec.PromptTokens, rec.CompletionTokens, rec.Latency.Milliseconds(),
+		EstimateCost(rec.Provider, rec.Model, rec.PromptTokens, rec.CompletionTokens), errorKind)
+	if err != nil {
+		return fmt.Errorf("failed to insert usage: %v", err)
+	}
+	return nil
+}
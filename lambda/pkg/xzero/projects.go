@@ -0,0 +1,76 @@
+// Package xzero reads the projects of a user from the X-Zero DID Login API,
+// which the frontend also uses to list them.
+package xzero
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// client bounds the calls to the API, which run inside API Gateway requests
+var client = &http.Client{Timeout: 10 * time.Second}
+
+// Project is an X-Zero project the user belongs to
+type Project struct {
+	ProjectID   string `json:"project_id"`
+	ProjectName string `json:"project_name"`
+}
+
+// Projects returns the projects of the user the Authorization header of a
+// request belongs to
+func Projects(ctx context.Context, authHeader string) ([]Project, error) {
+	baseURL := strings.TrimRight(os.Getenv("DID_LOGIN_API_URL"), "/")
+	if baseURL == "" {
+		return nil, errors.New("DID_LOGIN_API_URL not set")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL+"/api/projects", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", authHeader)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list projects: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read projects: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to list projects: status %d: %s", resp.StatusCode, body)
+	}
+
+	var result struct {
+		Data []Project `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse projects: %v", err)
+	}
+	return result.Data, nil
+}
+
+// Member reports whether the user the Authorization header belongs to is a
+// member of an X-Zero project
+func Member(ctx context.Context, authHeader, projectID string) (bool, error) {
+	projects, err := Projects(ctx, authHeader)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range projects {
+		if strings.EqualFold(p.ProjectID, projectID) {
+			return true, nil
+		}
+	}
+	return false, nil
+}